@@ -0,0 +1,161 @@
+package scalekit
+
+import (
+	"errors"
+	"net/http"
+)
+
+// AuthHandlersOptions configures NewAuthHandlers.
+type AuthHandlersOptions struct {
+	// Sessions keeps the session established by the callback. Required.
+	Sessions SessionStore
+
+	// DefaultReturnTo is where users are sent after a login that did not ask
+	// for a return_to path. Defaults to "/".
+	DefaultReturnTo string
+
+	// PostLogoutRedirectUri is where Scalekit sends users after logout. It must
+	// be registered for the application. When empty, Scalekit's default
+	// applies.
+	PostLogoutRedirectUri string
+
+	// OnSuccess writes the response to a completed login, after the session
+	// is saved. Defaults to redirecting to the login's return_to path.
+	OnSuccess func(w http.ResponseWriter, r *http.Request, result *LoginResult)
+
+	// OnError writes the response when a login, callback or logout fails.
+	// Defaults to WriteLoginError.
+	OnError func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// AuthHandlers serves the login, callback and logout routes of a web
+// application that signs users in with Scalekit.
+//
+//	flow, _ := scalekit.NewLoginFlow(client, scalekit.LoginFlowOptions{
+//		RedirectUri: "https://app.example.com/auth/callback",
+//		Secret:      loginSecret,
+//	})
+//	sessions, _ := scalekit.NewCookieSessionStore(sessionSecret, scalekit.CookieOptions{})
+//	scalekit.NewAuthHandlers(flow, scalekit.AuthHandlersOptions{Sessions: sessions}).Register(mux, "/auth")
+type AuthHandlers struct {
+	flow    *LoginFlow
+	options AuthHandlersOptions
+}
+
+// NewAuthHandlers returns the handlers for logins run by flow.
+func NewAuthHandlers(flow *LoginFlow, options AuthHandlersOptions) *AuthHandlers {
+	if options.DefaultReturnTo == "" {
+		options.DefaultReturnTo = "/"
+	}
+	if options.OnSuccess == nil {
+		options.OnSuccess = func(w http.ResponseWriter, r *http.Request, result *LoginResult) {
+			http.Redirect(w, r, result.ReturnTo, http.StatusSeeOther)
+		}
+	}
+	if options.OnError == nil {
+		options.OnError = WriteLoginError
+	}
+	return &AuthHandlers{flow: flow, options: options}
+}
+
+// Register registers Login, Callback and Logout on mux at prefix+"/login",
+// prefix+"/callback" and prefix+"/logout". The callback path must be the
+// LoginFlow's redirect URI. Logout only accepts POST, so that a link or image
+// on another site cannot sign users out.
+func (h *AuthHandlers) Register(mux *http.ServeMux, prefix string) {
+	mux.HandleFunc("GET "+prefix+"/login", h.Login)
+	mux.HandleFunc("GET "+prefix+"/callback", h.Callback)
+	mux.HandleFunc("POST "+prefix+"/logout", h.Logout)
+}
+
+// Login starts a login and redirects to Scalekit. The organization_id,
+// connection_id, domain_hint and login_hint query parameters select how the
+// user signs in, and return_to is the local path to send them to afterwards.
+func (h *AuthHandlers) Login(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	returnTo := query.Get("return_to")
+	if !IsLocalRedirect(returnTo) {
+		returnTo = h.options.DefaultReturnTo
+	}
+	authUrl, err := h.flow.AuthorizationUrl(w, r, LoginOptions{
+		AuthorizationUrlOptions: AuthorizationUrlOptions{
+			OrganizationId: query.Get("organization_id"),
+			ConnectionId:   query.Get("connection_id"),
+			DomainHint:     query.Get("domain_hint"),
+			LoginHint:      query.Get("login_hint"),
+		},
+		ReturnTo: returnTo,
+	})
+	if err != nil {
+		h.options.OnError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, authUrl.String(), http.StatusFound)
+}
+
+// Callback completes the login, saves the session and calls OnSuccess.
+func (h *AuthHandlers) Callback(w http.ResponseWriter, r *http.Request) {
+	result, err := h.flow.Callback(w, r)
+	if err != nil {
+		h.options.OnError(w, r, err)
+		return
+	}
+	if result.ReturnTo == "" {
+		result.ReturnTo = h.options.DefaultReturnTo
+	}
+	sessionId, _ := result.User.Claims["sid"].(string)
+	if err := h.options.Sessions.Save(w, r, newSession(result.AuthenticationResponse, sessionId)); err != nil {
+		h.options.OnError(w, r, err)
+		return
+	}
+	h.options.OnSuccess(w, r, result)
+}
+
+// Logout clears the local session, revokes the Scalekit session and redirects
+// to Scalekit's logout URL, which ends the user's Scalekit login. A session
+// that cannot be loaded is still cleared.
+func (h *AuthHandlers) Logout(w http.ResponseWriter, r *http.Request) {
+	session, _ := h.options.Sessions.Load(r)
+	if err := h.options.Sessions.Clear(w, r); err != nil {
+		h.options.OnError(w, r, err)
+		return
+	}
+
+	logoutOptions := LogoutUrlOptions{PostLogoutRedirectUri: h.options.PostLogoutRedirectUri}
+	if session != nil {
+		if session.SessionId != "" {
+			_, err := h.flow.client.Session().RevokeSession(r.Context(), session.SessionId)
+			if err != nil && !IsNotFound(err) {
+				h.options.OnError(w, r, err)
+				return
+			}
+		}
+		logoutOptions.IdTokenHint = session.IdToken
+	}
+	logoutUrl, err := h.flow.client.GetLogoutUrl(logoutOptions)
+	if err != nil {
+		h.options.OnError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, logoutUrl.String(), http.StatusFound)
+}
+
+// WriteLoginError writes a plain-text response for a failed login or logout:
+// 400 for an OAuth error or a missing, used or expired login state, 502 when
+// Scalekit could not be reached or rejected the request, and 401 for anything
+// else, such as an ID token that failed validation.
+func WriteLoginError(w http.ResponseWriter, _ *http.Request, err error) {
+	var authErr *AuthorizationError
+	var sdkErr *Error
+	switch {
+	case errors.As(err, &authErr),
+		errors.Is(err, ErrInvalidLoginState),
+		errors.Is(err, ErrLoginStateExpired),
+		errors.Is(err, ErrCodeRequired):
+		http.Error(w, "login failed: "+err.Error(), http.StatusBadRequest)
+	case errors.As(err, &sdkErr):
+		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+	default:
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	}
+}
@@ -20,6 +20,49 @@ type connectExecuter[TRequest interface{}, TResponse interface{}] struct {
 	retries    int // retries for unauthenticated errors; compared against maxRetries.
 	maxRetries int
 	fn         fn[TRequest, TResponse]
+	spec       connect.Spec // spec of the last attempt, used to decide whether it is safe to retry.
+}
+
+type rpcResponseKey struct{}
+
+// rpcResponse records the HTTP status and headers of an RPC response. Connect
+// does not expose them on errors for non-200 responses, which is where proxies
+// and load balancers report throttling (429 with Retry-After).
+type rpcResponse struct {
+	statusCode int
+	header     http.Header
+}
+
+// toError converts the error of a failed RPC into an *Error, first copying the
+// Retry-After and X-Request-Id headers of the response onto its metadata.
+func (r *rpcResponse) toError(err error) error {
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) {
+		return err
+	}
+	for _, key := range []string{"Retry-After", "X-Request-Id"} {
+		if value := r.header.Get(key); value != "" && connectErr.Meta().Get(key) == "" {
+			connectErr.Meta().Set(key, value)
+		}
+	}
+	return errorFromConnect(connectErr, r.statusCode)
+}
+
+// rpcResponseTransport fills in the rpcResponse found in the request context.
+type rpcResponseTransport struct {
+	t http.RoundTripper
+}
+
+func (t *rpcResponseTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := t.t.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	if record, ok := r.Context().Value(rpcResponseKey{}).(*rpcResponse); ok {
+		record.statusCode = resp.StatusCode
+		record.header = resp.Header
+	}
+	return resp, nil
 }
 
 func newConnectClient[T interface{}](
@@ -31,7 +74,7 @@ func newConnectClient[T interface{}](
 ) T,
 ) T {
 	return fn(
-		http.DefaultClient,
+		c.rpcClient,
 		c.envUrl,
 		connect.WithGRPC(),
 		connect.WithInterceptors(newHeaderInterceptor(c)),
@@ -44,14 +87,14 @@ func newHeaderInterceptor(c *coreClient) connect.UnaryInterceptorFunc {
 			ctx context.Context,
 			req connect.AnyRequest,
 		) (connect.AnyResponse, error) {
-			ctx, cancel := withDefaultTimeout(ctx)
+			ctx, cancel := c.withDefaultTimeout(ctx)
 			defer cancel()
 			if req.Spec().IsClient {
 				req.Header().Set("user-agent", c.userAgent)
 				req.Header().Set("x-sdk-version", c.sdkVersion)
 				req.Header().Set("x-api-version", c.apiVersion)
 				if token := c.accessToken.Load(); token != nil {
-					req.Header().Set("Authorization", fmt.Sprintf("Bearer %s", *token))
+					req.Header().Set("Authorization", fmt.Sprintf("Bearer %s", token.value))
 				}
 			}
 			return next(ctx, req)
@@ -72,35 +115,37 @@ func newConnectExecuter[TRequest interface{}, TResponse interface{}](
 	}
 }
 
-// isUnauthenticated reports whether err indicates an authentication failure
-// (HTTP 401 or Connect CodeUnauthenticated).
-func isUnauthenticated(err error) bool {
-	var httpErr *Error
-	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusUnauthorized {
-		return true
-	}
-	var connectErr *connect.Error
-	return errors.As(err, &connectErr) && connectErr.Code() == connect.CodeUnauthenticated
-}
-
-// exec runs the Connect RPC. Errors (including validation/CodeInvalidArgument) are returned
-// as-is; use errors.As(err, &connectErr) with *connect.Error to inspect Code() and Details().
+// exec runs the Connect RPC, retrying transient failures according to the
+// client's (or the context's) RetryPolicy. Failed RPCs (including validation/CodeInvalidArgument)
+// are returned as *Error with the decoded error details; the original *connect.Error is wrapped
+// and can still be extracted with errors.As to inspect Code() and Details().
 func (r *connectExecuter[TRequest, TResponse]) exec(ctx context.Context) (*TResponse, error) {
 	if r.coreClient.clientSecret == "" {
 		return nil, ErrClientSecretRequired
 	}
-	if !r.coreClient.hasAccessToken() {
-		// explicitly making a call before the actual call to ensure that the access token is available. Not consuming the error, so that the call can go into retries
-		_ = r.coreClient.authenticateClient(ctx)
-	}
-	data, err := r.fn(ctx, connect.NewRequest(r.data))
+	return withRetry(ctx, r.coreClient,
+		func() bool { return isIdempotentProcedure(r.spec) },
+		func() (*TResponse, error) { return r.call(ctx) },
+	)
+}
+
+// call makes a single attempt, re-authenticating and repeating the call when it
+// fails with an authentication error and unauthenticated retries remain.
+func (r *connectExecuter[TRequest, TResponse]) call(ctx context.Context) (*TResponse, error) {
+	// explicitly making a call before the actual call to ensure that a fresh access token is available. Not consuming the error, so that the call can go into retries
+	_ = r.coreClient.ensureAccessToken(ctx)
+	request := connect.NewRequest(r.data)
+	response := &rpcResponse{}
+	data, err := r.fn(context.WithValue(ctx, rpcResponseKey{}, response), request)
+	r.spec = request.Spec()
 	if err != nil {
-		if r.maxRetries-r.retries > 0 && isUnauthenticated(err) {
+		err = response.toError(err)
+		if r.maxRetries-r.retries > 0 && IsUnauthenticated(err) {
 			if authErr := r.coreClient.authenticateClient(ctx); authErr != nil {
 				return nil, authErr
 			}
 			r.retries++
-			return r.exec(ctx)
+			return r.call(ctx)
 		}
 		return nil, err
 	}
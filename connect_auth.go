@@ -0,0 +1,152 @@
+package scalekit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"connectrpc.com/connect"
+)
+
+// ProcedureRequirements lists what an access token must grant to call one
+// procedure, on top of the requirements shared by every procedure.
+type ProcedureRequirements struct {
+	// Scopes must all be present in the token's space-delimited scope claim.
+	Scopes []string
+	// Permissions must all be present in the token's permissions claim.
+	Permissions []string
+}
+
+// AuthInterceptorOptions configures NewAuthInterceptor.
+type AuthInterceptorOptions struct {
+	// ValidateTokenOptions holds the checks every procedure's token must
+	// pass, such as audiences and scopes.
+	ValidateTokenOptions
+
+	// Procedures maps full procedure names, such as
+	// "/acme.invoices.v1.InvoiceService/ListInvoices", to their additional
+	// requirements.
+	Procedures map[string]ProcedureRequirements
+
+	// PublicProcedures lists procedures that can be called without a token.
+	PublicProcedures []string
+}
+
+// NewAuthInterceptor returns a Connect server interceptor that requires a valid
+// access token in the Authorization metadata of unary and streaming calls. It
+// applies to handlers serving the Connect, gRPC and gRPC-Web protocols.
+//
+// Tokens are validated like in NewAuthMiddleware and the claims of accepted
+// tokens are available through AccessTokenClaimsFromContext. Calls
+// without a valid token fail with connect.CodeUnauthenticated, and tokens
+// lacking a required scope or permission with connect.CodePermissionDenied.
+//
+//	interceptor := scalekit.NewAuthInterceptor(client, scalekit.AuthInterceptorOptions{
+//		Procedures: map[string]scalekit.ProcedureRequirements{
+//			invoicesv1connect.InvoiceServiceDeleteInvoiceProcedure: {Permissions: []string{"invoices:delete"}},
+//		},
+//	})
+//	mux.Handle(invoicesv1connect.NewInvoiceServiceHandler(svc, connect.WithInterceptors(interceptor)))
+func NewAuthInterceptor(client Scalekit, options AuthInterceptorOptions) connect.Interceptor {
+	return &authInterceptor{client: client, options: options}
+}
+
+type authInterceptor struct {
+	client  Scalekit
+	options AuthInterceptorOptions
+}
+
+func (a *authInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		ctx, err := a.authenticate(ctx, req.Spec().Procedure, req.Header())
+		if err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+// WrapStreamingClient leaves client streams untouched; the interceptor only
+// authenticates incoming calls.
+func (a *authInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (a *authInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx, err := a.authenticate(ctx, conn.Spec().Procedure, conn.RequestHeader())
+		if err != nil {
+			return err
+		}
+		return next(ctx, conn)
+	}
+}
+
+// authenticate validates the token in header against the requirements of
+// procedure and returns a context carrying its claims.
+func (a *authInterceptor) authenticate(ctx context.Context, procedure string, header http.Header) (context.Context, error) {
+	if slices.Contains(a.options.PublicProcedures, procedure) {
+		return ctx, nil
+	}
+	token, err := bearerToken(header)
+	if err == nil && token == "" {
+		err = ErrTokenRequired
+	}
+	if err != nil {
+		return ctx, connect.NewError(connect.CodeUnauthenticated, err)
+	}
+
+	requirements := a.options.Procedures[procedure]
+	validateOptions := a.options.ValidateTokenOptions
+	validateOptions.Scopes = append(slices.Clip(validateOptions.Scopes), requirements.Scopes...)
+	claims, err := accessTokenClaims(ctx, a.client, token, &validateOptions)
+	if err == nil {
+		err = checkPermissions(claims.Claims, requirements.Permissions)
+	}
+	if err != nil {
+		return ctx, connect.NewError(authErrorCode(err), err)
+	}
+	return ContextWithAccessTokenClaims(ctx, claims), nil
+}
+
+// authErrorCode maps a token validation error to a Connect code.
+func authErrorCode(err error) connect.Code {
+	var sdkErr *Error
+	switch {
+	case errors.Is(err, ErrInsufficientScope), errors.Is(err, ErrMissingPermission):
+		return connect.CodePermissionDenied
+	case errors.As(err, &sdkErr):
+		return connect.CodeUnavailable
+	default:
+		return connect.CodeUnauthenticated
+	}
+}
+
+// checkPermissions succeeds when the token's permissions claim contains every
+// required permission.
+func checkPermissions(claims Claims, required []string) error {
+	if len(required) == 0 {
+		return nil
+	}
+	values, ok := claims["permissions"].([]interface{})
+	if !ok {
+		return &tokenCheckError{sentinel: ErrMissingPermission, message: "token missing permissions claim"}
+	}
+	granted := map[string]struct{}{}
+	for _, value := range values {
+		if permission, ok := value.(string); ok {
+			granted[permission] = struct{}{}
+		}
+	}
+	for _, permission := range required {
+		if _, ok := granted[permission]; !ok {
+			return &tokenCheckError{sentinel: ErrMissingPermission, message: fmt.Sprintf("missing expected permission %q in token permissions claim", permission)}
+		}
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/url"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -24,17 +25,61 @@ const (
 	sdkVersion         = "Scalekit-Go/" + sdkVersionNumber
 	defaultHTTPTimeout = 10 * time.Second
 	maxErrorBodyBytes  = 8 * 1024
+
+	// defaultJwksCacheTTL bounds how long a fetched key set is trusted before it
+	// is refetched, so rotated signing keys are picked up without a restart.
+	defaultJwksCacheTTL = time.Hour
+	// defaultJwksMinRefreshInterval rate-limits refetches triggered by unknown
+	// key ids and is the lower bound for Cache-Control derived lifetimes.
+	defaultJwksMinRefreshInterval = 30 * time.Second
+
+	// defaultTokenRefreshSkew is how long before expiry the client-credentials
+	// token is considered stale and refreshed before a management call.
+	defaultTokenRefreshSkew = time.Minute
 )
 
-// withDefaultTimeout attaches a defaultHTTPTimeout deadline to ctx if it has
-// no deadline yet, returning the wrapped context and its cancel function.
-// If ctx already has a deadline it is returned unchanged alongside a no-op
-// cancel, so callers can always safely defer cancel() in both cases.
-func withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
-	if _, ok := ctx.Deadline(); ok {
+// withDefaultTimeout attaches the client's timeout (defaultHTTPTimeout unless
+// configured with WithTimeout) to ctx if it has no deadline yet, returning the
+// wrapped context and its cancel function. If ctx already has a deadline, or
+// the timeout is disabled, ctx is returned unchanged alongside a no-op cancel,
+// so callers can always safely defer cancel() in both cases.
+func (c *coreClient) withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || c.timeout <= 0 {
 		return ctx, func() {}
 	}
-	return context.WithTimeout(ctx, defaultHTTPTimeout)
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// validateTokenOptions returns a copy of options with the client's issuer,
+// leeway and signing algorithms filled in where options leaves them unset.
+// With discovery enabled, the discovered issuer and ID token signing
+// algorithms are used unless configured with WithIssuer and
+// WithSigningAlgorithms. The discovered issuer matches the environment URL up
+// to a trailing slash, which tokens then carry as spelled in the metadata.
+func (c *coreClient) validateTokenOptions(ctx context.Context, options *ValidateTokenOptions) *ValidateTokenOptions {
+	resolved := ValidateTokenOptions{}
+	if options != nil {
+		resolved = *options
+	}
+	issuer, algorithms := c.issuer, c.signingAlgorithms
+	if metadata := c.discoveredMetadata(ctx); metadata != nil {
+		if !c.issuerConfigured {
+			issuer = metadata.Issuer
+		}
+		if len(algorithms) == 0 {
+			algorithms = discoveredSigningAlgorithms(metadata)
+		}
+	}
+	if resolved.Issuer == "" {
+		resolved.Issuer = issuer
+	}
+	if resolved.Leeway == 0 {
+		resolved.Leeway = c.tokenLeeway
+	}
+	if len(resolved.SigningAlgorithms) == 0 {
+		resolved.SigningAlgorithms = algorithms
+	}
+	return &resolved
 }
 
 type coreClient struct {
@@ -45,20 +90,68 @@ type coreClient struct {
 	apiVersion   string
 	userAgent    string
 
-	accessToken atomic.Pointer[string]
-	authGroup   singleflight.Group
+	accessToken      atomic.Pointer[clientToken]
+	authGroup        singleflight.Group
+	tokenRefreshSkew time.Duration
+	refreshing       atomic.Bool
 
-	jwksGroup     singleflight.Group
-	jsonWebKeySet atomic.Pointer[jose.JSONWebKeySet]
+	jwksGroup              singleflight.Group
+	jwksCache              atomic.Pointer[jwksCacheEntry]
+	jwksCacheTTL           time.Duration
+	jwksMinRefreshInterval time.Duration
 
+	// discovery enables locating endpoints through the provider metadata,
+	// which is cached in discoveryCache for discoveryCacheTTL.
+	discovery         bool
+	discoveryGroup    singleflight.Group
+	discoveryCache    atomic.Pointer[discoveryCacheEntry]
+	discoveryCacheTTL time.Duration
+
+	// issuer is the iss claim expected in validated tokens; empty disables the
+	// check. tokenLeeway and signingAlgorithms are the defaults for
+	// ValidateTokenOptions.Leeway and SigningAlgorithms.
+	issuer            string
+	issuerConfigured  bool
+	tokenLeeway       time.Duration
+	signingAlgorithms []jose.SignatureAlgorithm
+
+	timeout         time.Duration
+	retry           RetryPolicy
+	userAgentSuffix string
+	baseHTTPClient  *http.Client
+	transport       http.RoundTripper
+
+	// httpClient is used for the OAuth and JWKS endpoints and adds the SDK
+	// headers itself; rpcClient is handed to the Connect clients, whose
+	// interceptor adds them. Both share the same underlying transport.
 	httpClient *http.Client
+	rpcClient  *http.Client
+	options    []Option
+}
+
+// clientToken is the client-credentials access token used for management calls.
+// expiresAt is zero when the token endpoint did not report expires_in.
+type clientToken struct {
+	value     string
+	expiresAt time.Time
+}
+
+// jwksCacheEntry is an immutable snapshot of the cached key set. fetchedAt is
+// the time of the last successful fetch; expiresAt is when the set goes stale.
+type jwksCacheEntry struct {
+	keySet    *jose.JSONWebKeySet
+	fetchedAt time.Time
+	expiresAt time.Time
 }
 
 type authenticationResponse struct {
-	IdToken      string `json:"id_token"`
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	ExpiresIn    int    `json:"expires_in"`
+	IdToken         string `json:"id_token"`
+	AccessToken     string `json:"access_token"`
+	RefreshToken    string `json:"refresh_token"`
+	ExpiresIn       int    `json:"expires_in"`
+	TokenType       string `json:"token_type"`
+	Scope           string `json:"scope"`
+	IssuedTokenType string `json:"issued_token_type"`
 }
 
 type headerInterceptor struct {
@@ -84,11 +177,12 @@ func (h *headerInterceptor) RoundTrip(r *http.Request) (*http.Response, error) {
 	r.Header.Add("user-agent", h.client.userAgent)
 	r.Header.Add("x-sdk-version", h.client.sdkVersion)
 	r.Header.Add("x-api-version", h.client.apiVersion)
-	if token := h.client.accessToken.Load(); token != nil {
-		r.Header.Add("Authorization", fmt.Sprintf("Bearer %s", *token))
+	// The management token is only sent to the environment itself.
+	if token := h.client.accessToken.Load(); token != nil && sameOrigin(r.URL.String(), h.client.envUrl) {
+		r.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token.value))
 	}
 
-	ctx, cancel := withDefaultTimeout(r.Context())
+	ctx, cancel := h.client.withDefaultTimeout(r.Context())
 	resp, err := h.t.RoundTrip(r.WithContext(ctx))
 	if err != nil {
 		cancel()
@@ -98,24 +192,55 @@ func (h *headerInterceptor) RoundTrip(r *http.Request) (*http.Response, error) {
 	return resp, nil
 }
 
-func newCoreClient(envUrl, clientId, clientSecret string) *coreClient {
+func newCoreClient(envUrl, clientId, clientSecret string, opts ...Option) *coreClient {
 	sdkVersion := sdkVersion
 	apiVersion := "20260312"
 	client := &coreClient{
-		sdkVersion:   sdkVersion,
-		apiVersion:   apiVersion,
-		userAgent:    fmt.Sprintf("%s Go/%s (%s; %s)", sdkVersion, runtime.Version(), runtime.GOOS, runtime.GOARCH),
-		envUrl:       envUrl,
-		clientId:     clientId,
-		clientSecret: clientSecret,
+		sdkVersion:             sdkVersion,
+		apiVersion:             apiVersion,
+		userAgent:              fmt.Sprintf("%s Go/%s (%s; %s)", sdkVersion, runtime.Version(), runtime.GOOS, runtime.GOARCH),
+		envUrl:                 envUrl,
+		clientId:               clientId,
+		clientSecret:           clientSecret,
+		jwksCacheTTL:           defaultJwksCacheTTL,
+		jwksMinRefreshInterval: defaultJwksMinRefreshInterval,
+		discoveryCacheTTL:      defaultDiscoveryCacheTTL,
+		tokenRefreshSkew:       defaultTokenRefreshSkew,
+		issuer:                 envUrl,
+		timeout:                defaultHTTPTimeout,
+		retry:                  DefaultRetryPolicy(),
+		options:                opts,
 	}
-	client.httpClient = &http.Client{
-		Transport: &headerInterceptor{
-			t:      http.DefaultTransport,
-			client: client,
-		},
+	for _, opt := range opts {
+		if opt != nil {
+			opt(client)
+		}
+	}
+	if client.userAgentSuffix != "" {
+		client.userAgent = fmt.Sprintf("%s %s", client.userAgent, client.userAgentSuffix)
 	}
 
+	base := &http.Client{}
+	if client.baseHTTPClient != nil {
+		*base = *client.baseHTTPClient
+	}
+	transport := base.Transport
+	if client.transport != nil {
+		transport = client.transport
+	}
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	rpcClient := *base
+	rpcClient.Transport = &rpcResponseTransport{t: transport}
+	client.rpcClient = &rpcClient
+	httpClient := *base
+	httpClient.Transport = &headerInterceptor{
+		t:      transport,
+		client: client,
+	}
+	client.httpClient = &httpClient
+
 	return client
 }
 
@@ -133,16 +258,57 @@ func (c *coreClient) authenticateClient(ctx context.Context) error {
 		if err != nil {
 			return nil, err
 		}
-		c.accessToken.Store(&res.AccessToken)
+		token := &clientToken{value: res.AccessToken}
+		if res.ExpiresIn > 0 {
+			token.expiresAt = time.Now().Add(time.Duration(res.ExpiresIn) * time.Second)
+		}
+		c.accessToken.Store(token)
 		return nil, nil
 	})
 	return err
 }
 
+// ensureAccessToken makes sure a client-credentials token that is not about to
+// expire is available. A missing token, or one within tokenRefreshSkew of its
+// expiry, is fetched synchronously. A token within twice the skew is still used
+// but refreshed in the background so steady traffic never waits for the token
+// endpoint or hits an expired token.
+func (c *coreClient) ensureAccessToken(ctx context.Context) error {
+	token := c.accessToken.Load()
+	if token == nil || token.value == "" {
+		return c.authenticateClient(ctx)
+	}
+	if token.expiresAt.IsZero() {
+		return nil
+	}
+	remaining := time.Until(token.expiresAt)
+	if remaining <= c.tokenRefreshSkew {
+		return c.authenticateClient(ctx)
+	}
+	if remaining <= 2*c.tokenRefreshSkew && c.refreshing.CompareAndSwap(false, true) {
+		go func() {
+			defer c.refreshing.Store(false)
+			// Failures are ignored; the token is still valid and the next call retries.
+			_ = c.authenticateClient(context.WithoutCancel(ctx))
+		}()
+	}
+	return nil
+}
+
+// authenticate posts requestData to the token endpoint. Only client_credentials
+// grants are treated as idempotent by the retry policy; authorization codes and
+// rotating refresh tokens are single-use.
 func (c *coreClient) authenticate(ctx context.Context, requestData url.Values) (*authenticationResponse, error) {
+	return withRetry(ctx, c,
+		func() bool { return requestData.Get("grant_type") == GrantTypeClientCredentials },
+		func() (*authenticationResponse, error) { return c.postToken(ctx, requestData) },
+	)
+}
+
+func (c *coreClient) postToken(ctx context.Context, requestData url.Values) (*authenticationResponse, error) {
 	request, err := http.NewRequestWithContext(ctx,
 		http.MethodPost,
-		fmt.Sprintf("%s/%s", c.envUrl, tokenEndpoint),
+		c.endpoints(ctx).TokenEndpoint,
 		strings.NewReader(requestData.Encode()),
 	)
 	if err != nil {
@@ -173,42 +339,66 @@ func (c *coreClient) authenticate(ctx context.Context, requestData url.Values) (
 	return &responseData, nil
 }
 
+// GetJwks returns the environment's JSON Web Key Set. The set is cached for
+// jwksCacheTTL (shortened by a Cache-Control max-age from the server) and
+// concurrent refetches are coalesced through jwksGroup.
 func (c *coreClient) GetJwks(ctx context.Context) (*jose.JSONWebKeySet, error) {
-	if cached := c.jsonWebKeySet.Load(); cached != nil {
-		return copyJSONWebKeySet(cached), nil
+	if cached := c.jwksCache.Load(); cached != nil && time.Now().Before(cached.expiresAt) {
+		return copyJSONWebKeySet(cached.keySet), nil
+	}
+	return c.refreshJwks(ctx, false)
+}
+
+// getJwksForKeyId returns the cached key set, refetching it once when keyId is
+// not part of it so tokens signed with a freshly rotated key still verify.
+// Refetches for unknown key ids are rate-limited by jwksMinRefreshInterval.
+func (c *coreClient) getJwksForKeyId(ctx context.Context, keyId string) (*jose.JSONWebKeySet, error) {
+	keySet, err := c.GetJwks(ctx)
+	if err != nil || keyId == "" || len(keySet.Key(keyId)) > 0 {
+		return keySet, err
+	}
+	if cached := c.jwksCache.Load(); cached != nil && time.Since(cached.fetchedAt) < c.jwksMinRefreshInterval {
+		return keySet, nil
 	}
+	return c.refreshJwks(ctx, true)
+}
+
+// refreshJwks fetches the key set unless another caller refreshed it while this
+// one waited. When force is set a fresh (non-expired) cache entry is refetched
+// too, as long as it is older than jwksMinRefreshInterval. If the fetch fails
+// and a previous key set is cached, the stale set is served and the next attempt
+// is deferred by jwksMinRefreshInterval.
+func (c *coreClient) refreshJwks(ctx context.Context, force bool) (*jose.JSONWebKeySet, error) {
 	v, err, _ := c.jwksGroup.Do("jwks", func() (any, error) {
-		if cached := c.jsonWebKeySet.Load(); cached != nil {
-			return copyJSONWebKeySet(cached), nil
+		cached := c.jwksCache.Load()
+		if cached != nil {
+			if !force && time.Now().Before(cached.expiresAt) {
+				return copyJSONWebKeySet(cached.keySet), nil
+			}
+			if force && time.Since(cached.fetchedAt) < c.jwksMinRefreshInterval {
+				return copyJSONWebKeySet(cached.keySet), nil
+			}
 		}
 		// Use WithoutCancel so one caller's context cancellation does not fail all waiters.
-		request, err := http.NewRequestWithContext(context.WithoutCancel(ctx),
-			http.MethodGet,
-			fmt.Sprintf("%s/%s", c.envUrl, jwksEndpoint),
-			nil,
+		fetchCtx := context.WithoutCancel(ctx)
+		result, err := withRetry(fetchCtx, c,
+			func() bool { return true },
+			func() (*jwksFetchResult, error) { return c.fetchJwks(fetchCtx) },
 		)
 		if err != nil {
-			return nil, err
-		}
-		response, err := c.httpClient.Do(request)
-		if err != nil {
-			return nil, err
-		}
-		// Close errors are intentionally ignored; the response body is fully consumed or discarded below.
-		defer func() { _ = response.Body.Close() }()
-		if response.StatusCode < 200 || response.StatusCode >= 300 {
-			return nil, httpErrorFromResponse(response, "failed to fetch JWKS")
-		}
-		var responseData jose.JSONWebKeySet
-		err = json.NewDecoder(response.Body).Decode(&responseData)
-		if err != nil {
-			return nil, err
+			if cached == nil {
+				return nil, err
+			}
+			c.jwksCache.Store(&jwksCacheEntry{
+				keySet:    cached.keySet,
+				fetchedAt: cached.fetchedAt,
+				expiresAt: time.Now().Add(c.jwksMinRefreshInterval),
+			})
+			return copyJSONWebKeySet(cached.keySet), nil
 		}
-		if len(responseData.Keys) == 0 {
-			return nil, ErrJwksEmptyKeySet
-		}
-		c.jsonWebKeySet.Store(&responseData)
-		return copyJSONWebKeySet(&responseData), nil
+		now := time.Now()
+		c.jwksCache.Store(&jwksCacheEntry{keySet: result.keySet, fetchedAt: now, expiresAt: now.Add(result.ttl)})
+		return copyJSONWebKeySet(result.keySet), nil
 	})
 	if err != nil {
 		return nil, err
@@ -220,6 +410,69 @@ func (c *coreClient) GetJwks(ctx context.Context) (*jose.JSONWebKeySet, error) {
 	return jwks, nil
 }
 
+// jwksFetchResult is a downloaded key set and the lifetime it may be cached for.
+type jwksFetchResult struct {
+	keySet *jose.JSONWebKeySet
+	ttl    time.Duration
+}
+
+func (c *coreClient) fetchJwks(ctx context.Context) (*jwksFetchResult, error) {
+	request, err := http.NewRequestWithContext(ctx,
+		http.MethodGet,
+		c.endpoints(ctx).JwksUri,
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	// Close errors are intentionally ignored; the response body is fully consumed or discarded below.
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, httpErrorFromResponse(response, "failed to fetch JWKS")
+	}
+	var responseData jose.JSONWebKeySet
+	err = json.NewDecoder(response.Body).Decode(&responseData)
+	if err != nil {
+		return nil, err
+	}
+	if len(responseData.Keys) == 0 {
+		return nil, ErrJwksEmptyKeySet
+	}
+	return &jwksFetchResult{
+		keySet: &responseData,
+		ttl:    jwksLifetime(response.Header, c.jwksCacheTTL, c.jwksMinRefreshInterval),
+	}, nil
+}
+
+// jwksLifetime returns ttl, shortened when the response's Cache-Control header
+// asks for a shorter lifetime. Server-provided lifetimes are never shorter than
+// floor so a misconfigured max-age=0 cannot turn every validation into a fetch.
+func jwksLifetime(header http.Header, ttl, floor time.Duration) time.Duration {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		var lifetime time.Duration
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache":
+			lifetime = 0
+		case "max-age":
+			seconds, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+			if err != nil || seconds < 0 || time.Duration(seconds) >= ttl/time.Second {
+				continue
+			}
+			lifetime = time.Duration(seconds) * time.Second
+		default:
+			continue
+		}
+		lifetime = max(lifetime, floor)
+		ttl = min(ttl, lifetime)
+	}
+	return ttl
+}
+
 // copyJSONWebKeySet returns a shallow copy of the key set so callers cannot mutate the internal cache (e.g. the Keys slice).
 func copyJSONWebKeySet(src *jose.JSONWebKeySet) *jose.JSONWebKeySet {
 	if src == nil {
@@ -229,8 +482,3 @@ func copyJSONWebKeySet(src *jose.JSONWebKeySet) *jose.JSONWebKeySet {
 	copy(keys, src.Keys)
 	return &jose.JSONWebKeySet{Keys: keys}
 }
-
-func (c *coreClient) hasAccessToken() bool {
-	token := c.accessToken.Load()
-	return token != nil && *token != ""
-}
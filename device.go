@@ -0,0 +1,204 @@
+package scalekit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const deviceAuthorizationEndpoint = "oauth/device/code"
+
+// defaultDevicePollInterval is the polling interval used when the device
+// authorization response has none (RFC 8628 section 3.2).
+const defaultDevicePollInterval = 5 * time.Second
+
+// deviceSlowDownIncrement is added to the polling interval each time the token
+// endpoint answers slow_down (RFC 8628 section 3.5).
+const deviceSlowDownIncrement = 5 * time.Second
+
+// DeviceAuthorizationOptions configures RequestDeviceAuthorization.
+type DeviceAuthorizationOptions struct {
+	// Scopes requested for the tokens. Defaults to openid, profile, email and
+	// offline_access.
+	Scopes []string
+}
+
+// DeviceAuthorization is a pending device authorization. Show UserCode and
+// VerificationUri, or VerificationUriComplete, to the user, then call
+// PollDeviceToken.
+type DeviceAuthorization struct {
+	DeviceCode              string
+	UserCode                string
+	VerificationUri         string
+	VerificationUriComplete string
+
+	// ExpiresAt is when DeviceCode expires and polling stops. It is zero when
+	// Scalekit did not say, and polling then continues until ctx is done.
+	ExpiresAt time.Time
+
+	// Interval is the minimum time between token requests.
+	Interval time.Duration
+}
+
+// DeviceTokenResponse holds the tokens issued once the user approves a device
+// authorization.
+type DeviceTokenResponse struct {
+	TokenResponse
+
+	// IdTokenClaims are the validated claims of IdToken, or nil when no ID
+	// token was issued.
+	IdTokenClaims *IdTokenClaims
+}
+
+type deviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationUri         string `json:"verification_uri"`
+	VerificationUriComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// DeviceAuthorizer runs the device authorization grant for command-line tools
+// and other devices without a browser. The client returned by
+// NewScalekitClient implements it.
+type DeviceAuthorizer interface {
+	RequestDeviceAuthorization(ctx context.Context, options DeviceAuthorizationOptions) (*DeviceAuthorization, error)
+	PollDeviceToken(ctx context.Context, authorization *DeviceAuthorization) (*DeviceTokenResponse, error)
+}
+
+// RequestDeviceAuthorization starts the OAuth 2.0 Device Authorization Grant
+// (RFC 8628) for input-constrained clients such as command-line tools.
+func (s *scalekitClient) RequestDeviceAuthorization(ctx context.Context, options DeviceAuthorizationOptions) (*DeviceAuthorization, error) {
+	scopes := []string{"openid", "profile", "email", "offline_access"}
+	if options.Scopes != nil {
+		scopes = options.Scopes
+	}
+	qs := url.Values{}
+	qs.Set("client_id", s.coreClient.clientId)
+	qs.Set("scope", strings.Join(scopes, " "))
+	if s.coreClient.clientSecret != "" {
+		qs.Set("client_secret", s.coreClient.clientSecret)
+	}
+
+	request, err := http.NewRequestWithContext(ctx,
+		http.MethodPost,
+		s.coreClient.endpoints(ctx).DeviceAuthorizationEndpoint,
+		strings.NewReader(qs.Encode()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	response, err := s.coreClient.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	// Close errors are intentionally ignored; the response body is fully consumed or discarded below.
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, httpErrorFromResponse(response, "device authorization failed")
+	}
+	var responseData deviceAuthorizationResponse
+	if err := json.NewDecoder(response.Body).Decode(&responseData); err != nil {
+		return nil, err
+	}
+	if responseData.DeviceCode == "" {
+		return nil, ErrDeviceCodeRequired
+	}
+
+	interval := time.Duration(responseData.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultDevicePollInterval
+	}
+	authorization := &DeviceAuthorization{
+		DeviceCode:              responseData.DeviceCode,
+		UserCode:                responseData.UserCode,
+		VerificationUri:         responseData.VerificationUri,
+		VerificationUriComplete: responseData.VerificationUriComplete,
+		Interval:                interval,
+	}
+	if responseData.ExpiresIn > 0 {
+		authorization.ExpiresAt = time.Now().Add(time.Duration(responseData.ExpiresIn) * time.Second)
+	}
+	return authorization, nil
+}
+
+// PollDeviceToken polls the token endpoint until the user approves or denies
+// authorization, the device code expires, or ctx is done. It waits
+// authorization.Interval between requests, and 5 seconds longer after each
+// slow_down answer. The ID token, when issued, is validated like in
+// AuthenticateWithCode.
+//
+// It returns ErrDeviceAccessDenied when the user denies the request and
+// ErrDeviceCodeExpired when the code expires first.
+func (s *scalekitClient) PollDeviceToken(ctx context.Context, authorization *DeviceAuthorization) (*DeviceTokenResponse, error) {
+	if authorization == nil || authorization.DeviceCode == "" {
+		return nil, ErrDeviceCodeRequired
+	}
+	qs := url.Values{}
+	qs.Set("grant_type", GrantTypeDeviceCode)
+	qs.Set("device_code", authorization.DeviceCode)
+	qs.Set("client_id", s.coreClient.clientId)
+	if s.coreClient.clientSecret != "" {
+		qs.Set("client_secret", s.coreClient.clientSecret)
+	}
+
+	interval := authorization.Interval
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		if !authorization.ExpiresAt.IsZero() && time.Now().Add(interval).After(authorization.ExpiresAt) {
+			return nil, ErrDeviceCodeExpired
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		authResp, err := s.coreClient.authenticate(ctx, qs)
+		var sdkErr *Error
+		switch {
+		case err == nil:
+			return s.deviceTokenResponse(ctx, authResp)
+		case !errors.As(err, &sdkErr):
+			return nil, err
+		case sdkErr.Reason == "authorization_pending":
+		case sdkErr.Reason == "slow_down":
+			interval += deviceSlowDownIncrement
+		case sdkErr.Reason == "access_denied":
+			return nil, errors.Join(ErrDeviceAccessDenied, err)
+		case sdkErr.Reason == "expired_token":
+			return nil, errors.Join(ErrDeviceCodeExpired, err)
+		default:
+			return nil, err
+		}
+		timer.Reset(interval)
+	}
+}
+
+func (s *scalekitClient) deviceTokenResponse(ctx context.Context, authResp *authenticationResponse) (*DeviceTokenResponse, error) {
+	resp := &DeviceTokenResponse{TokenResponse: TokenResponse{
+		AccessToken:  authResp.AccessToken,
+		IdToken:      authResp.IdToken,
+		RefreshToken: authResp.RefreshToken,
+		ExpiresIn:    authResp.ExpiresIn,
+	}}
+	if authResp.IdToken == "" {
+		return resp, nil
+	}
+	claims, err := validateToken[IdTokenClaims](ctx, authResp.IdToken, s.coreClient.getJwksForKeyId, s.coreClient.validateTokenOptions(ctx, nil))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkIdTokenBinding(authResp.IdToken, claims.Claims, s.coreClient.clientId, "", authResp.AccessToken, ""); err != nil {
+		return nil, err
+	}
+	resp.IdTokenClaims = claims
+	return resp, nil
+}
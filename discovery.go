@@ -0,0 +1,253 @@
+package scalekit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+const discoveryEndpoint = ".well-known/openid-configuration"
+
+const (
+	// defaultDiscoveryCacheTTL bounds how long discovered provider metadata is
+	// used before it is fetched again.
+	defaultDiscoveryCacheTTL = time.Hour
+	// discoveryRetryInterval is how long a failed discovery fetch is not
+	// retried, while the previous metadata or the default endpoints are used.
+	discoveryRetryInterval = 30 * time.Second
+)
+
+// ProviderMetadata is the OpenID Provider configuration of an environment,
+// served at /.well-known/openid-configuration.
+type ProviderMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	JwksUri                           string   `json:"jwks_uri"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint,omitempty"`
+	RevocationEndpoint                string   `json:"revocation_endpoint,omitempty"`
+	EndSessionEndpoint                string   `json:"end_session_endpoint,omitempty"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint,omitempty"`
+	ScopesSupported                   []string `json:"scopes_supported,omitempty"`
+	ResponseTypesSupported            []string `json:"response_types_supported,omitempty"`
+	GrantTypesSupported               []string `json:"grant_types_supported,omitempty"`
+	IdTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported,omitempty"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported,omitempty"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported,omitempty"`
+}
+
+// discoveryCacheEntry is an immutable snapshot of the last discovery fetch.
+// metadata is nil and err set when no fetch has succeeded yet.
+type discoveryCacheEntry struct {
+	metadata  *ProviderMetadata
+	err       error
+	expiresAt time.Time
+}
+
+// ProviderMetadataFetcher returns the environment's OpenID Provider metadata.
+// The client returned by NewScalekitClient implements it.
+type ProviderMetadataFetcher interface {
+	GetProviderMetadata(ctx context.Context) (*ProviderMetadata, error)
+}
+
+// GetProviderMetadata returns the environment's OpenID Provider metadata. It
+// is cached for an hour, or the TTL set with WithDiscoveryCacheTTL, whether or
+// not the client uses it to locate endpoints (see WithDiscovery).
+func (s *scalekitClient) GetProviderMetadata(ctx context.Context) (*ProviderMetadata, error) {
+	metadata, err := s.coreClient.providerMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return copyProviderMetadata(metadata), nil
+}
+
+// providerMetadata returns the cached metadata, fetching it when it expired.
+// When a fetch fails, the previous metadata is served, or the error returned,
+// until discoveryRetryInterval has passed.
+func (c *coreClient) providerMetadata(ctx context.Context) (*ProviderMetadata, error) {
+	if cached := c.discoveryCache.Load(); cached != nil && time.Now().Before(cached.expiresAt) {
+		return cached.metadata, cached.err
+	}
+	v, err, _ := c.discoveryGroup.Do("discovery", func() (any, error) {
+		cached := c.discoveryCache.Load()
+		if cached != nil && time.Now().Before(cached.expiresAt) {
+			return cached.metadata, cached.err
+		}
+		// Use WithoutCancel so one caller's context cancellation does not fail all waiters.
+		fetchCtx := context.WithoutCancel(ctx)
+		metadata, err := withRetry(fetchCtx, c,
+			func() bool { return true },
+			func() (*ProviderMetadata, error) { return c.fetchProviderMetadata(fetchCtx) },
+		)
+		if err != nil {
+			entry := &discoveryCacheEntry{err: err, expiresAt: time.Now().Add(discoveryRetryInterval)}
+			if cached != nil && cached.metadata != nil {
+				entry.metadata, entry.err = cached.metadata, nil
+			}
+			c.discoveryCache.Store(entry)
+			return entry.metadata, entry.err
+		}
+		c.discoveryCache.Store(&discoveryCacheEntry{metadata: metadata, expiresAt: time.Now().Add(c.discoveryCacheTTL)})
+		return metadata, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*ProviderMetadata), nil
+}
+
+func (c *coreClient) fetchProviderMetadata(ctx context.Context) (*ProviderMetadata, error) {
+	request, err := http.NewRequestWithContext(ctx,
+		http.MethodGet,
+		fmt.Sprintf("%s/%s", c.envUrl, discoveryEndpoint),
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	// Close errors are intentionally ignored; the response body is fully consumed or discarded below.
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, httpErrorFromResponse(response, "failed to fetch OpenID configuration")
+	}
+	var metadata ProviderMetadata
+	if err := json.NewDecoder(response.Body).Decode(&metadata); err != nil {
+		return nil, err
+	}
+	if metadata.Issuer == "" {
+		return nil, errors.Join(ErrInvalidProviderMetadata, errors.New("issuer is missing"))
+	}
+	// OpenID Connect Discovery 1.0 section 4.3: the issuer must be the URL
+	// the configuration was retrieved from, or the custom domain configured
+	// with WithIssuer.
+	expected := c.envUrl
+	if c.issuerConfigured && c.issuer != "" {
+		expected = c.issuer
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != strings.TrimSuffix(expected, "/") {
+		return nil, errors.Join(ErrInvalidProviderMetadata,
+			fmt.Errorf("issuer %q does not match %q", metadata.Issuer, expected))
+	}
+	return &metadata, nil
+}
+
+// endpoints returns the endpoints the client calls: the discovered ones when
+// discovery is enabled and they are advertised, the environment's default
+// paths otherwise.
+func (c *coreClient) endpoints(ctx context.Context) *ProviderMetadata {
+	return c.endpointsFrom(c.discoveredMetadata(ctx))
+}
+
+// cachedEndpoints is like endpoints, but never fetches the metadata: it uses
+// the metadata from an earlier fetch, if any, for URLs built without a
+// context.
+func (c *coreClient) cachedEndpoints() *ProviderMetadata {
+	var metadata *ProviderMetadata
+	if cached := c.discoveryCache.Load(); c.discovery && cached != nil {
+		metadata = cached.metadata
+	}
+	return c.endpointsFrom(metadata)
+}
+
+// endpointsFrom returns the endpoints advertised by metadata, falling back to
+// the default paths for those it lacks or when metadata is nil. Endpoints on
+// other hosts than the environment's or the configured issuer's also fall
+// back, so that the client's credentials are not sent elsewhere.
+func (c *coreClient) endpointsFrom(metadata *ProviderMetadata) *ProviderMetadata {
+	endpoints := &ProviderMetadata{
+		Issuer:                      c.envUrl,
+		AuthorizationEndpoint:       fmt.Sprintf("%s/%s", c.envUrl, authorizeEndpoint),
+		TokenEndpoint:               fmt.Sprintf("%s/%s", c.envUrl, tokenEndpoint),
+		JwksUri:                     fmt.Sprintf("%s/%s", c.envUrl, jwksEndpoint),
+		EndSessionEndpoint:          fmt.Sprintf("%s/%s", c.envUrl, logoutEndpoint),
+		DeviceAuthorizationEndpoint: fmt.Sprintf("%s/%s", c.envUrl, deviceAuthorizationEndpoint),
+	}
+	if metadata == nil {
+		return endpoints
+	}
+	discovered := copyProviderMetadata(metadata)
+	for field, fallback := range map[*string]string{
+		&discovered.AuthorizationEndpoint:       endpoints.AuthorizationEndpoint,
+		&discovered.TokenEndpoint:               endpoints.TokenEndpoint,
+		&discovered.JwksUri:                     endpoints.JwksUri,
+		&discovered.EndSessionEndpoint:          endpoints.EndSessionEndpoint,
+		&discovered.DeviceAuthorizationEndpoint: endpoints.DeviceAuthorizationEndpoint,
+	} {
+		if *field == "" || !c.trustedHost(*field) {
+			*field = fallback
+		}
+	}
+	return discovered
+}
+
+// trustedHost reports whether rawUrl is on the environment's host or on the
+// host of the issuer configured with WithIssuer.
+func (c *coreClient) trustedHost(rawUrl string) bool {
+	if sameOrigin(rawUrl, c.envUrl) {
+		return true
+	}
+	return c.issuerConfigured && c.issuer != "" && sameOrigin(rawUrl, c.issuer)
+}
+
+// sameOrigin reports whether both URLs parse and share scheme and host.
+func sameOrigin(a, b string) bool {
+	first, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	second, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return first.Scheme == second.Scheme && first.Host == second.Host
+}
+
+// discoveredMetadata returns the provider metadata when discovery is enabled
+// and it could be fetched, nil otherwise.
+func (c *coreClient) discoveredMetadata(ctx context.Context) *ProviderMetadata {
+	if !c.discovery {
+		return nil
+	}
+	metadata, err := c.providerMetadata(ctx)
+	if err != nil {
+		return nil
+	}
+	return metadata
+}
+
+// discoveredSigningAlgorithms returns the supported algorithms among those the
+// discovered metadata advertises for ID tokens, or nil.
+func discoveredSigningAlgorithms(metadata *ProviderMetadata) []jose.SignatureAlgorithm {
+	var algorithms []jose.SignatureAlgorithm
+	for _, name := range metadata.IdTokenSigningAlgValuesSupported {
+		if algorithm := jose.SignatureAlgorithm(name); slices.Contains(supportedSigningAlgorithms, algorithm) {
+			algorithms = append(algorithms, algorithm)
+		}
+	}
+	return algorithms
+}
+
+// copyProviderMetadata returns a deep copy of metadata so callers cannot
+// mutate the cached one.
+func copyProviderMetadata(metadata *ProviderMetadata) *ProviderMetadata {
+	clone := *metadata
+	clone.ScopesSupported = slices.Clone(metadata.ScopesSupported)
+	clone.ResponseTypesSupported = slices.Clone(metadata.ResponseTypesSupported)
+	clone.GrantTypesSupported = slices.Clone(metadata.GrantTypesSupported)
+	clone.IdTokenSigningAlgValuesSupported = slices.Clone(metadata.IdTokenSigningAlgValuesSupported)
+	clone.TokenEndpointAuthMethodsSupported = slices.Clone(metadata.TokenEndpointAuthMethodsSupported)
+	clone.CodeChallengeMethodsSupported = slices.Clone(metadata.CodeChallengeMethodsSupported)
+	return &clone
+}
@@ -1,11 +1,15 @@
 package scalekit
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/errdetails"
 )
 
 // Sentinel errors returned by SDK methods. Use errors.Is to check for specific conditions.
@@ -99,8 +103,125 @@ var (
 
 	// ErrJwksEmptyKeySet is returned when the JWKS endpoint returns a key set with no keys.
 	ErrJwksEmptyKeySet = errors.New("JWKS endpoint returned empty key set")
+
+	// ErrInvalidProviderMetadata is returned when the OpenID configuration of
+	// the environment lacks required fields or names another issuer.
+	ErrInvalidProviderMetadata = errors.New("invalid OpenID provider metadata")
+
+	// ErrTokenNotYetValid is returned when a JWT's nbf claim is in the future.
+	ErrTokenNotYetValid = errors.New("token is not valid yet")
+
+	// ErrTokenIssuedInFuture is returned when a JWT's iat claim is in the future.
+	ErrTokenIssuedInFuture = errors.New("token issued in the future")
+
+	// ErrMissingIatClaim is returned when ValidateTokenOptions.MaxAge is set and
+	// a JWT has no iat claim.
+	ErrMissingIatClaim = errors.New("token missing required iat claim")
+
+	// ErrTokenTooOld is returned when a JWT was issued longer ago than
+	// ValidateTokenOptions.MaxAge.
+	ErrTokenTooOld = errors.New("token exceeds maximum age")
+
+	// ErrInvalidIssuer is matched by the errors returned when a JWT's iss claim
+	// is missing or differs from the expected issuer.
+	ErrInvalidIssuer = errors.New("token issuer is invalid")
+
+	// ErrUnsupportedSigningAlgorithm is matched by the errors returned when a
+	// JWT is signed with an algorithm that is not accepted.
+	ErrUnsupportedSigningAlgorithm = errors.New("token signing algorithm is not accepted")
+
+	// ErrSigningKeyNotFound is matched by the errors returned when the key set
+	// has no key with the JWT's kid that supports its signing algorithm.
+	ErrSigningKeyNotFound = errors.New("token signing key not found in key set")
+
+	// ErrAudienceMismatch is returned when a token's aud claim contains none of
+	// the audiences in ValidateTokenOptions.
+	ErrAudienceMismatch = errors.New("none of the expected audiences found in token aud claim")
+
+	// ErrInsufficientScope is matched by the errors returned when a token lacks
+	// a scope required by ValidateTokenOptions. The error message names the
+	// failed check.
+	ErrInsufficientScope = errors.New("token is missing a required scope")
+
+	// ErrMissingPermission is matched by the errors returned when a token lacks
+	// a permission required by NewAuthInterceptor.
+	ErrMissingPermission = errors.New("token is missing a required permission")
+
+	// ErrNonceMismatch is returned when the ID token's nonce claim differs from
+	// AuthenticationOptions.Nonce.
+	ErrNonceMismatch = errors.New("id token nonce does not match the expected nonce")
+
+	// ErrInvalidAuthorizedParty is returned when the ID token's azp claim is
+	// not the client id, or is missing from a token with several audiences.
+	ErrInvalidAuthorizedParty = errors.New("id token azp claim does not match the client id")
+
+	// ErrAtHashMismatch is returned when the ID token's at_hash claim does not
+	// match the access token returned with it.
+	ErrAtHashMismatch = errors.New("id token at_hash does not match the access token")
+
+	// ErrCHashMismatch is returned when the ID token's c_hash claim does not
+	// match the authorization code.
+	ErrCHashMismatch = errors.New("id token c_hash does not match the authorization code")
+
+	// ErrCookieSecretTooShort is returned when a secret used to seal cookies is
+	// shorter than 32 bytes.
+	ErrCookieSecretTooShort = errors.New("cookie secret must be at least 32 bytes")
+
+	// ErrInvalidLoginState is returned by LoginFlow.Callback when the state
+	// parameter is missing or does not match a login started by this browser.
+	ErrInvalidLoginState = errors.New("login state is missing, invalid or already used")
+
+	// ErrLoginStateExpired is returned by LoginFlow.Callback when the login took
+	// longer than LoginFlowOptions.MaxAge.
+	ErrLoginStateExpired = errors.New("login state has expired")
+
+	// ErrInvalidReturnTo is returned when LoginOptions.ReturnTo is not a local path.
+	ErrInvalidReturnTo = errors.New("return-to URL must be a local path")
+
+	// ErrSessionTooLarge is returned when a session does not fit in the cookies
+	// of NewCookieSessionStore.
+	ErrSessionTooLarge = errors.New("session is too large for cookies")
+
+	// ErrDeviceCodeRequired is returned when PollDeviceToken is called without a
+	// device code, or the device authorization response has none.
+	ErrDeviceCodeRequired = errors.New("device code is required")
+
+	// ErrDeviceAccessDenied is matched by the error PollDeviceToken returns when
+	// the user denies the device authorization.
+	ErrDeviceAccessDenied = errors.New("device authorization denied")
+
+	// ErrDeviceCodeExpired is matched by the error PollDeviceToken returns when
+	// the device code expires before the user approves it.
+	ErrDeviceCodeExpired = errors.New("device code expired")
+
+	// ErrLoginTimeout is returned by LoginWithLoopback when the browser does not
+	// return before the timeout.
+	ErrLoginTimeout = errors.New("timed out waiting for the login to complete")
+
+	// ErrBrowserUnavailable is matched by the error LoginWithLoopback returns
+	// when it cannot open the system browser.
+	ErrBrowserUnavailable = errors.New("cannot open a browser")
+
+	// ErrSubjectTokenRequired is returned when ExchangeToken is called without a
+	// subject token.
+	ErrSubjectTokenRequired = errors.New("subject token is required")
+
+	// ErrInvalidAuthorizationHeader is returned by token extractors when the
+	// Authorization header is present but is not a Bearer credential.
+	ErrInvalidAuthorizationHeader = errors.New("authorization header must use the Bearer scheme")
 )
 
+// tokenCheckError reports a failed token check with its own message while
+// matching a sentinel error with errors.Is.
+type tokenCheckError struct {
+	sentinel error
+	message  string
+}
+
+func (e *tokenCheckError) Error() string { return e.message }
+
+func (e *tokenCheckError) Unwrap() error { return e.sentinel }
+
 // errorCore holds the common fields for SDK errors. Unexported so HTTPError can embed it without
 // the embedded field name shadowing the Error() method (embedding "Error" would shadow).
 type errorCore struct {
@@ -120,10 +241,116 @@ func (e *errorCore) Unwrap() error {
 	return e.Err
 }
 
-// Error represents an error returned by the SDK. Currently it only covers non-2xx HTTP responses.
-// Use errors.As(err, &e) to extract: var e *scalekit.Error; if errors.As(err, &e) { code := e.StatusCode }.
+// Error represents an error returned by the SDK for a failed request: a non-2xx HTTP response from
+// the OAuth or JWKS endpoints, or a failed management RPC. RPC errors wrap the original
+// *connect.Error, so errors.As(err, &connectErr) keeps working.
+// Use errors.As(err, &e) to extract: var e *scalekit.Error; if errors.As(err, &e) { code := e.Code }.
+// The IsNotFound, IsAlreadyExists, IsPermissionDenied, IsUnauthenticated and IsRateLimited helpers
+// work for both kinds.
 type Error struct {
 	errorCore
+
+	// Code is the Connect/gRPC status code. For HTTP errors it is derived from StatusCode.
+	Code connect.Code
+
+	// RequestID identifies the failed request when contacting Scalekit support.
+	RequestID string
+
+	// Reason is the machine-readable error code, e.g. the OAuth "error" value
+	// ("invalid_grant") or the ErrorInfo error_code of an RPC error.
+	Reason string
+
+	// LocalizedMessage is a user-facing message provided by the server, if any.
+	LocalizedMessage string
+
+	// Violations lists per-field validation failures of an invalid request.
+	Violations []FieldViolation
+
+	// HelpLinks points to documentation about the error.
+	HelpLinks []HelpLink
+
+	// Resource describes the resource the error relates to, if the server reported one.
+	Resource *ErrorResource
+
+	// header holds the response headers, e.g. Retry-After for throttled requests.
+	header http.Header
+}
+
+// FieldViolation describes a single invalid field of a request.
+type FieldViolation struct {
+	Field       string
+	Description string
+	Constraint  string
+}
+
+// HelpLink points to documentation about an error.
+type HelpLink struct {
+	Description string
+	URL         string
+}
+
+// ErrorResource describes the resource an error relates to.
+type ErrorResource struct {
+	Name                string
+	Owner               string
+	Description         string
+	User                string
+	RequiredPermissions []string
+}
+
+// IsNotFound reports whether err indicates that the requested resource does not exist.
+func IsNotFound(err error) bool {
+	return errorCode(err) == connect.CodeNotFound
+}
+
+// IsAlreadyExists reports whether err indicates that the resource being created already exists.
+func IsAlreadyExists(err error) bool {
+	return errorCode(err) == connect.CodeAlreadyExists
+}
+
+// IsPermissionDenied reports whether err indicates that the caller may not perform the operation.
+func IsPermissionDenied(err error) bool {
+	return errorCode(err) == connect.CodePermissionDenied
+}
+
+// IsUnauthenticated reports whether err indicates missing or invalid credentials.
+func IsUnauthenticated(err error) bool {
+	return errorCode(err) == connect.CodeUnauthenticated
+}
+
+// IsInvalidArgument reports whether err indicates an invalid request; see Error.Violations for details.
+func IsInvalidArgument(err error) bool {
+	return errorCode(err) == connect.CodeInvalidArgument
+}
+
+// IsRateLimited reports whether err indicates the request was throttled (HTTP 429 or CodeResourceExhausted).
+func IsRateLimited(err error) bool {
+	var sdkErr *Error
+	if errors.As(err, &sdkErr) && sdkErr.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return errorCode(err) == connect.CodeResourceExhausted
+}
+
+// errorCode returns the status code carried by err, or 0 if it carries none.
+func errorCode(err error) connect.Code {
+	var sdkErr *Error
+	if errors.As(err, &sdkErr) && sdkErr.Code != 0 {
+		return sdkErr.Code
+	}
+	var connectErr *connect.Error
+	if errors.As(err, &connectErr) {
+		return connectErr.Code()
+	}
+	return 0
+}
+
+// oauthErrorBody is the error payload of the OAuth endpoints (RFC 6749 section 5.2),
+// with the error_code field used by other Scalekit HTTP endpoints.
+type oauthErrorBody struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+	ErrorCode        string `json:"error_code"`
 }
 
 // httpErrorFromResponse reads the response body (capped at maxErrorBodyBytes to avoid
@@ -131,14 +358,167 @@ type Error struct {
 // non-success responses. The prefix is used in the error message (e.g. "authentication failed").
 // The caller is responsible for closing resp.Body; this function may consume part or all of it.
 func httpErrorFromResponse(resp *http.Response, prefix string) *Error {
+	sdkErr := &Error{
+		errorCore: errorCore{StatusCode: resp.StatusCode},
+		Code:      codeFromHTTPStatus(resp.StatusCode),
+		RequestID: resp.Header.Get("X-Request-Id"),
+		header:    resp.Header,
+	}
 	body, readErr := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes+1))
 	if readErr != nil {
-		return &Error{errorCore: errorCore{StatusCode: resp.StatusCode, Message: readErr.Error(), Err: readErr}}
+		sdkErr.Message, sdkErr.Err = readErr.Error(), readErr
+		return sdkErr
 	}
 	msg := strings.TrimSpace(string(body))
 	if len(body) > maxErrorBodyBytes {
 		msg = strings.TrimSpace(string(body[:maxErrorBodyBytes])) + " …(truncated)"
 	}
+	var errorBody oauthErrorBody
+	if json.Unmarshal(body, &errorBody) == nil {
+		sdkErr.Reason = errorBody.Error
+		if sdkErr.Reason == "" {
+			sdkErr.Reason = errorBody.ErrorCode
+		}
+	}
 	err := fmt.Errorf("%s: HTTP %d: %s", prefix, resp.StatusCode, msg)
-	return &Error{errorCore: errorCore{StatusCode: resp.StatusCode, Message: err.Error(), Err: err}}
+	sdkErr.Message, sdkErr.Err = err.Error(), err
+	return sdkErr
+}
+
+// errorFromConnect converts a failed RPC into an *Error, decoding the Scalekit error details
+// attached to it. statusCode is the HTTP status of the response, or 0 if none was received
+// or it was 200 (gRPC reports errors in trailers); the equivalent HTTP status of the code is
+// used then.
+func errorFromConnect(connectErr *connect.Error, statusCode int) *Error {
+	if statusCode == 0 || statusCode == http.StatusOK {
+		statusCode = httpStatusFromCode(connectErr.Code())
+	}
+	sdkErr := &Error{
+		errorCore: errorCore{StatusCode: statusCode, Message: connectErr.Message(), Err: connectErr},
+		Code:      connectErr.Code(),
+		RequestID: connectErr.Meta().Get("X-Request-Id"),
+		header:    connectErr.Meta(),
+	}
+	for _, detail := range connectErr.Details() {
+		value, err := detail.Value()
+		if err != nil {
+			continue
+		}
+		switch info := value.(type) {
+		case *errdetails.ErrorInfo:
+			sdkErr.Reason = info.GetErrorCode()
+			sdkErr.addHelpInfo(info.GetHelpInfo())
+			sdkErr.addLocalizedMessageInfo(info.GetLocalizedMessageInfo())
+			sdkErr.addResourceInfo(info.GetResourceInfo())
+			sdkErr.addRequestInfo(info.GetRequestInfo())
+			sdkErr.addValidationErrorInfo(info.GetValidationErrorInfo())
+		case *errdetails.HelpInfo:
+			sdkErr.addHelpInfo(info)
+		case *errdetails.LocalizedMessageInfo:
+			sdkErr.addLocalizedMessageInfo(info)
+		case *errdetails.ResourceInfo:
+			sdkErr.addResourceInfo(info)
+		case *errdetails.RequestInfo:
+			sdkErr.addRequestInfo(info)
+		case *errdetails.ValidationErrorInfo:
+			sdkErr.addValidationErrorInfo(info)
+		}
+	}
+	return sdkErr
+}
+
+func (e *Error) addHelpInfo(info *errdetails.HelpInfo) {
+	for _, link := range info.GetLinks() {
+		e.HelpLinks = append(e.HelpLinks, HelpLink{Description: link.GetDescription(), URL: link.GetUrl()})
+	}
+}
+
+func (e *Error) addLocalizedMessageInfo(info *errdetails.LocalizedMessageInfo) {
+	if info.GetMessage() != "" {
+		e.LocalizedMessage = info.GetMessage()
+	}
+}
+
+func (e *Error) addResourceInfo(info *errdetails.ResourceInfo) {
+	if info == nil {
+		return
+	}
+	e.Resource = &ErrorResource{
+		Name:                info.GetResourceName(),
+		Owner:               info.GetOwner(),
+		Description:         info.GetDescription(),
+		User:                info.GetUser(),
+		RequiredPermissions: info.GetRequiredPermissions(),
+	}
+}
+
+func (e *Error) addRequestInfo(info *errdetails.RequestInfo) {
+	if info.GetRequestId() != "" {
+		e.RequestID = info.GetRequestId()
+	}
+}
+
+func (e *Error) addValidationErrorInfo(info *errdetails.ValidationErrorInfo) {
+	for _, violation := range info.GetFieldViolations() {
+		e.Violations = append(e.Violations, FieldViolation{
+			Field:       violation.GetField(),
+			Description: violation.GetDescription(),
+			Constraint:  violation.GetConstraint(),
+		})
+	}
+}
+
+// codeFromHTTPStatus maps an HTTP status to the closest Connect code.
+func codeFromHTTPStatus(status int) connect.Code {
+	switch status {
+	case http.StatusBadRequest:
+		return connect.CodeInvalidArgument
+	case http.StatusUnauthorized:
+		return connect.CodeUnauthenticated
+	case http.StatusForbidden:
+		return connect.CodePermissionDenied
+	case http.StatusNotFound:
+		return connect.CodeNotFound
+	case http.StatusConflict:
+		return connect.CodeAlreadyExists
+	case http.StatusTooManyRequests:
+		return connect.CodeResourceExhausted
+	case http.StatusNotImplemented:
+		return connect.CodeUnimplemented
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return connect.CodeUnavailable
+	case http.StatusGatewayTimeout:
+		return connect.CodeDeadlineExceeded
+	}
+	if status >= 500 {
+		return connect.CodeInternal
+	}
+	return connect.CodeUnknown
+}
+
+// httpStatusFromCode maps a Connect code to the equivalent HTTP status.
+func httpStatusFromCode(code connect.Code) int {
+	switch code {
+	case connect.CodeCanceled:
+		return 499
+	case connect.CodeInvalidArgument, connect.CodeFailedPrecondition, connect.CodeOutOfRange:
+		return http.StatusBadRequest
+	case connect.CodeDeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case connect.CodeNotFound:
+		return http.StatusNotFound
+	case connect.CodeAlreadyExists, connect.CodeAborted:
+		return http.StatusConflict
+	case connect.CodePermissionDenied:
+		return http.StatusForbidden
+	case connect.CodeResourceExhausted:
+		return http.StatusTooManyRequests
+	case connect.CodeUnimplemented:
+		return http.StatusNotImplemented
+	case connect.CodeUnavailable:
+		return http.StatusServiceUnavailable
+	case connect.CodeUnauthenticated:
+		return http.StatusUnauthorized
+	}
+	return http.StatusInternalServerError
 }
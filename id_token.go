@@ -0,0 +1,79 @@
+package scalekit
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"hash"
+	"slices"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// checkIdTokenBinding performs the ID token checks of OpenID Connect Core
+// section 3.1.3.7 that tie the token to this client and this authentication
+// response: the audience and authorized party must be clientId, the nonce must
+// be the one sent with the authorization request, and the at_hash and c_hash
+// claims, when present, must match the access token and code.
+func checkIdTokenBinding(idToken string, claims Claims, clientId, nonce, accessToken, code string) error {
+	var aud Audience
+	switch v := claims["aud"].(type) {
+	case string:
+		aud = Audience{v}
+	case []interface{}:
+		for _, value := range v {
+			if s, ok := value.(string); ok {
+				aud = append(aud, s)
+			}
+		}
+	}
+	if !slices.Contains(aud, clientId) {
+		return ErrAudienceMismatch
+	}
+	azp, hasAzp := claims["azp"].(string)
+	if (hasAzp || len(aud) > 1) && azp != clientId {
+		return ErrInvalidAuthorizedParty
+	}
+
+	if nonce != "" {
+		if claimed, _ := claims["nonce"].(string); claimed != nonce {
+			return ErrNonceMismatch
+		}
+	}
+
+	atHash, hasAtHash := claims["at_hash"].(string)
+	cHash, hasCHash := claims["c_hash"].(string)
+	if !hasAtHash && !hasCHash {
+		return nil
+	}
+	jws, err := jose.ParseSigned(idToken, supportedSigningAlgorithms)
+	if err != nil {
+		return err
+	}
+	algorithm := jose.SignatureAlgorithm(jws.Signatures[0].Header.Algorithm)
+	if hasAtHash && tokenHash(algorithm, accessToken) != atHash {
+		return ErrAtHashMismatch
+	}
+	if hasCHash && tokenHash(algorithm, code) != cHash {
+		return ErrCHashMismatch
+	}
+	return nil
+}
+
+// tokenHash computes an at_hash or c_hash value: the base64url encoded left
+// half of the hash of value, using the hash function of the ID token's
+// signing algorithm.
+func tokenHash(algorithm jose.SignatureAlgorithm, value string) string {
+	var h hash.Hash
+	switch algorithm {
+	case jose.RS384, jose.PS384, jose.ES384:
+		h = sha512.New384()
+	case jose.RS512, jose.PS512, jose.ES512, jose.EdDSA:
+		h = sha512.New()
+	default:
+		h = sha256.New()
+	}
+	h.Write([]byte(value))
+	sum := h.Sum(nil)
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
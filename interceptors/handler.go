@@ -0,0 +1,150 @@
+package interceptors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/internal/signedhttp"
+)
+
+// DefaultMaxBodyBytes is the largest payload NewHandler reads when
+// HandlerOptions.MaxBodyBytes is zero.
+const DefaultMaxBodyBytes = signedhttp.DefaultMaxBodyBytes
+
+// DefaultTimeout bounds the decision function of NewHandler when
+// HandlerOptions.Timeout is zero. It leaves room within the time Scalekit
+// waits for an interceptor before applying its fallback.
+const DefaultTimeout = 3 * time.Second
+
+// Verifier authenticates interceptor calls. Verify returns nil when the
+// signature headers match the payload. *scalekit.WebhookVerifier implements
+// it, and accepts several secrets while one is rotated.
+type Verifier = signedhttp.Verifier
+
+// SecretVerifier returns a Verifier that checks calls with
+// client.VerifyInterceptorPayload and the interceptor's signing secret.
+func SecretVerifier(client scalekit.Scalekit, secret string) Verifier {
+	return signedhttp.VerifierFunc(func(headers map[string]string, payload []byte) error {
+		_, err := client.VerifyInterceptorPayload(secret, headers, payload)
+		return err
+	})
+}
+
+// DecisionFunc decides the outcome of an interceptor call. ctx is done when
+// the handler's Timeout elapses.
+type DecisionFunc func(ctx context.Context, req *Request) (*Response, error)
+
+// HandlerOptions configures NewHandler.
+type HandlerOptions struct {
+	// Verifier authenticates calls. Required; NewHandler panics without it.
+	Verifier Verifier
+
+	// MaxBodyBytes caps the payload size. Larger calls are rejected with 413.
+	// Defaults to DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+
+	// Timeout bounds the decision function. Defaults to DefaultTimeout.
+	Timeout time.Duration
+
+	// Fallback answers calls whose decision function fails or times out.
+	// When nil, they are answered with 500 or 504, and Scalekit applies the
+	// fallback configured for the interceptor.
+	Fallback *Response
+
+	// OnError is called with calls that are rejected or whose decision
+	// function fails, for logging. req is nil when the call was not decoded.
+	OnError func(r *http.Request, req *Request, err error)
+}
+
+type handler struct {
+	decide  DecisionFunc
+	options HandlerOptions
+}
+
+// NewHandler returns an http.Handler serving interceptor calls. It reads the
+// body up to MaxBodyBytes, verifies it with the signature headers of the
+// request, decodes it and calls decide with a deadline of Timeout. It
+// responds with:
+//
+//   - 200 and the JSON encoded Response of decide, or Fallback;
+//   - 400 when headers are missing or the payload is not a request;
+//   - 401 when the signature or timestamp does not verify;
+//   - 405 for methods other than POST, 413 for payloads over MaxBodyBytes;
+//   - 500 when decide fails, or 504 when it times out, without Fallback.
+//
+// NewHandler panics when options.Verifier is nil.
+func NewHandler(decide DecisionFunc, options HandlerOptions) http.Handler {
+	if options.Verifier == nil {
+		panic("interceptors: HandlerOptions.Verifier is required")
+	}
+	if options.MaxBodyBytes <= 0 {
+		options.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+	if options.OnError == nil {
+		options.OnError = func(*http.Request, *Request, error) {}
+	}
+	return &handler{decide: decide, options: options}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	payload, _, ok := signedhttp.ReadVerified(w, r, h.options.Verifier, h.options.MaxBodyBytes,
+		func(err error) { h.options.OnError(r, nil, err) })
+	if !ok {
+		return
+	}
+	req, err := ParseRequest(payload)
+	if err != nil {
+		h.options.OnError(r, nil, err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.decideWithin(r.Context(), req)
+	if err != nil {
+		h.options.OnError(r, req, err)
+		if h.options.Fallback != nil {
+			resp = h.options.Fallback
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			http.Error(w, http.StatusText(http.StatusGatewayTimeout), http.StatusGatewayTimeout)
+			return
+		} else {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+type decision struct {
+	resp *Response
+	err  error
+}
+
+// decideWithin calls decide and returns its result, or the context's error
+// when the deadline passes first, even if decide does not return.
+func (h *handler) decideWithin(ctx context.Context, req *Request) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.options.Timeout)
+	defer cancel()
+	decisions := make(chan decision, 1)
+	go func() {
+		resp, err := h.decide(ctx, req)
+		if err == nil && resp == nil {
+			err = errors.New("interceptors: decision function returned no response")
+		}
+		decisions <- decision{resp: resp, err: err}
+	}()
+	select {
+	case d := <-decisions:
+		return d.resp, d.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
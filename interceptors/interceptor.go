@@ -0,0 +1,212 @@
+// Package interceptors decodes the calls Scalekit makes to interceptors at
+// trigger points of its authentication flows, such as before a signup or
+// before a session is created, and encodes the decisions they answer with.
+//
+// NewHandler verifies each call, decodes it and answers with the Response of
+// a decision function:
+//
+//	mux.Handle("POST /interceptors/scalekit", interceptors.NewHandler(func(ctx context.Context, req *interceptors.Request) (*interceptors.Response, error) {
+//		var signup interceptors.PreSignupContext
+//		if err := req.Decode(&signup); err != nil {
+//			return nil, err
+//		}
+//		if !strings.HasSuffix(signup.UserEmail, "@acme.com") {
+//			return interceptors.Deny("Sign up with your Acme email address."), nil
+//		}
+//		return interceptors.Allow(), nil
+//	}, interceptors.HandlerOptions{
+//		Verifier: interceptors.SecretVerifier(client, secret),
+//	}))
+//
+// Experimental: the trigger points, contexts and response schema of this
+// package have not been checked against a published schema or recorded calls
+// from Scalekit yet. Field names and values may change in a minor release
+// once they are.
+package interceptors
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Trigger points of the authentication flows at which Scalekit calls
+// interceptors, as named in the trigger_point field of its calls.
+//
+// Experimental: see the package documentation.
+const (
+	PreSignup             = "PRE_SIGNUP"
+	PreSessionCreation    = "PRE_SESSION_CREATION"
+	PreUserInvitation     = "PRE_USER_INVITATION"
+	PreM2MTokenCreation   = "PRE_M2M_TOKEN_CREATION"
+	PreOrganizationSwitch = "PRE_ORGANIZATION_SWITCH"
+)
+
+// Decisions of a Response, the values of its decision field.
+//
+// Experimental: see the package documentation.
+const (
+	DecisionAllow = "ALLOW"
+	DecisionDeny  = "DENY"
+)
+
+// ErrInvalidRequest is returned when a verified payload is not an interceptor
+// request.
+var ErrInvalidRequest = errors.New("invalid interceptor request")
+
+// Request is a call to an interceptor, decoded from its JSON payload. Context
+// holds the details of the trigger point, which Decode unmarshals into the
+// matching context struct, such as PreSignupContext.
+//
+// Experimental: see the package documentation.
+type Request struct {
+	Id             string    `json:"id"`
+	TriggerPoint   string    `json:"trigger_point"`
+	EnvironmentId  string    `json:"environment_id"`
+	OrganizationId string    `json:"organization_id,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+
+	Context json.RawMessage `json:"interceptor_context"`
+}
+
+// ParseRequest decodes an interceptor payload into a Request. It does not
+// verify the payload.
+func ParseRequest(payload []byte) (*Request, error) {
+	var request Request
+	if err := json.Unmarshal(payload, &request); err != nil {
+		return nil, errors.Join(ErrInvalidRequest, err)
+	}
+	if request.TriggerPoint == "" {
+		return nil, errors.Join(ErrInvalidRequest, errors.New("trigger point is required"))
+	}
+	return &request, nil
+}
+
+// Decode unmarshals the request's Context into v.
+func (r *Request) Decode(v any) error {
+	if len(r.Context) == 0 {
+		return errors.Join(ErrInvalidRequest, errors.New("request has no interceptor context"))
+	}
+	if err := json.Unmarshal(r.Context, v); err != nil {
+		return errors.Join(ErrInvalidRequest, err)
+	}
+	return nil
+}
+
+// ClientInfo describes the browser or device that started the flow.
+type ClientInfo struct {
+	UserAgent string `json:"user_agent,omitempty"`
+	IpAddress string `json:"ip_address,omitempty"`
+	Region    string `json:"region,omitempty"`
+}
+
+// PreSignupContext is the context of PreSignup calls, made before a user
+// account is created.
+type PreSignupContext struct {
+	ClientInfo
+	UserEmail      string `json:"user_email"`
+	ConnectionType string `json:"connection_type,omitempty"`
+	Provider       string `json:"provider,omitempty"`
+	OrganizationId string `json:"organization_id,omitempty"`
+}
+
+// PreSessionCreationContext is the context of PreSessionCreation calls, made
+// before a signed-in user's session and tokens are issued.
+type PreSessionCreationContext struct {
+	ClientInfo
+	UserId         string            `json:"user_id"`
+	UserEmail      string            `json:"user_email"`
+	OrganizationId string            `json:"organization_id,omitempty"`
+	ConnectionType string            `json:"connection_type,omitempty"`
+	Roles          []string          `json:"roles,omitempty"`
+	UserMetadata   map[string]string `json:"user_metadata,omitempty"`
+}
+
+// PreUserInvitationContext is the context of PreUserInvitation calls, made
+// before a user is invited to an organization.
+type PreUserInvitationContext struct {
+	InviterId      string   `json:"inviter_id,omitempty"`
+	UserEmail      string   `json:"user_email"`
+	OrganizationId string   `json:"organization_id"`
+	Roles          []string `json:"roles,omitempty"`
+}
+
+// PreM2MTokenCreationContext is the context of PreM2MTokenCreation calls, made
+// before an access token is issued to an M2M client.
+type PreM2MTokenCreationContext struct {
+	ClientId       string   `json:"client_id"`
+	OrganizationId string   `json:"organization_id,omitempty"`
+	Scopes         []string `json:"scopes,omitempty"`
+}
+
+// PreOrganizationSwitchContext is the context of PreOrganizationSwitch calls,
+// made before a signed-in user switches to another organization.
+type PreOrganizationSwitchContext struct {
+	ClientInfo
+	UserId             string `json:"user_id"`
+	UserEmail          string `json:"user_email"`
+	FromOrganizationId string `json:"from_organization_id,omitempty"`
+	ToOrganizationId   string `json:"to_organization_id"`
+}
+
+// Response is an interceptor's decision, encoded as JSON: a decision, an
+// error message for denials, and the claims and user metadata to apply under
+// response. Build it with Allow or Deny and chain WithClaim and WithMetadata
+// to change what Scalekit issues.
+//
+// Experimental: see the package documentation.
+type Response struct {
+	Decision string         `json:"decision"`
+	Error    *ResponseError `json:"error,omitempty"`
+	Changes  *Changes       `json:"response,omitempty"`
+}
+
+// ResponseError is the message shown to the user when a flow is denied.
+type ResponseError struct {
+	Message string `json:"message"`
+}
+
+// Changes are the mutations an allowing Response asks Scalekit to apply.
+type Changes struct {
+	// Claims are added to the tokens issued by the flow.
+	Claims map[string]any `json:"claims,omitempty"`
+	// UserMetadata is merged into the metadata of the user.
+	UserMetadata map[string]string `json:"user_metadata,omitempty"`
+}
+
+// Allow returns a Response letting the flow continue.
+func Allow() *Response {
+	return &Response{Decision: DecisionAllow}
+}
+
+// Deny returns a Response stopping the flow, with message shown to the user.
+func Deny(message string) *Response {
+	return &Response{Decision: DecisionDeny, Error: &ResponseError{Message: message}}
+}
+
+// WithClaim adds a claim to the tokens issued by an allowed flow.
+func (r *Response) WithClaim(name string, value any) *Response {
+	changes := r.changes()
+	if changes.Claims == nil {
+		changes.Claims = make(map[string]any)
+	}
+	changes.Claims[name] = value
+	return r
+}
+
+// WithMetadata sets a metadata key of the user of an allowed flow.
+func (r *Response) WithMetadata(key, value string) *Response {
+	changes := r.changes()
+	if changes.UserMetadata == nil {
+		changes.UserMetadata = make(map[string]string)
+	}
+	changes.UserMetadata[key] = value
+	return r
+}
+
+func (r *Response) changes() *Changes {
+	if r.Changes == nil {
+		r.Changes = &Changes{}
+	}
+	return r.Changes
+}
@@ -0,0 +1,80 @@
+// Package signedhttp reads the signed calls Scalekit makes to webhook
+// endpoints and interceptors, which are verified alike with the webhook-id,
+// webhook-timestamp and webhook-signature headers.
+package signedhttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+)
+
+// DefaultMaxBodyBytes is the largest payload ReadVerified reads when
+// maxBytes is zero.
+const DefaultMaxBodyBytes = 1 << 20
+
+// Headers are the request headers a call is verified with.
+var Headers = []string{"webhook-id", "webhook-timestamp", "webhook-signature"}
+
+// Verifier authenticates calls. Verify returns nil when the signature headers
+// match the payload.
+type Verifier interface {
+	Verify(headers map[string]string, payload []byte) error
+}
+
+// VerifierFunc adapts a function to a Verifier.
+type VerifierFunc func(headers map[string]string, payload []byte) error
+
+func (f VerifierFunc) Verify(headers map[string]string, payload []byte) error {
+	return f(headers, payload)
+}
+
+// ReadVerified reads the body of r up to maxBytes and verifies it with the
+// signature headers of r. When the call is rejected it responds with:
+//
+//   - 400 when headers are missing or the body cannot be read;
+//   - 401 when the signature or timestamp does not verify;
+//   - 405 for methods other than POST, 413 for bodies over maxBytes;
+//
+// reports the error to onError, except for the wrong method, and returns
+// false.
+func ReadVerified(w http.ResponseWriter, r *http.Request, verifier Verifier, maxBytes int64, onError func(error)) (payload []byte, headers map[string]string, ok bool) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return nil, nil, false
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBodyBytes
+	}
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+	if err != nil {
+		onError(err)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return nil, nil, false
+		}
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	headers = make(map[string]string, len(Headers))
+	for _, name := range Headers {
+		if value := r.Header.Get(name); value != "" {
+			headers[name] = value
+		}
+	}
+	if err := verifier.Verify(headers, payload); err != nil {
+		onError(err)
+		if errors.Is(err, scalekit.ErrMissingRequiredHeaders) {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return nil, nil, false
+		}
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return nil, nil, false
+	}
+	return payload, headers, true
+}
@@ -0,0 +1,101 @@
+package scalekit
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+	"slices"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// supportedSigningAlgorithms are the JWS algorithms tokens can be signed with.
+// Only asymmetric algorithms are listed: a token signed with an HMAC key or
+// with "none" is never accepted.
+var supportedSigningAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+// selectSigningKey returns the key in keySet that verifies a token with the
+// given JWS header. The key must have the token's kid and support its alg, and
+// alg must be in allowed, or in the algorithms advertised by keySet when
+// allowed is empty.
+func selectSigningKey(keySet *jose.JSONWebKeySet, header jose.Header, allowed []jose.SignatureAlgorithm) (jose.JSONWebKey, error) {
+	alg := jose.SignatureAlgorithm(header.Algorithm)
+	if len(allowed) == 0 {
+		allowed = keySetAlgorithms(keySet)
+	}
+	if !slices.Contains(allowed, alg) {
+		return jose.JSONWebKey{}, &tokenCheckError{sentinel: ErrUnsupportedSigningAlgorithm, message: fmt.Sprintf("token signing algorithm %q is not accepted", alg)}
+	}
+	if header.KeyID == "" {
+		return jose.JSONWebKey{}, &tokenCheckError{sentinel: ErrSigningKeyNotFound, message: "token header missing kid"}
+	}
+	for _, key := range keySet.Key(header.KeyID) {
+		if isSigningKey(key) && slices.Contains(keyAlgorithms(key), alg) {
+			return key, nil
+		}
+	}
+	return jose.JSONWebKey{}, &tokenCheckError{sentinel: ErrSigningKeyNotFound, message: fmt.Sprintf("no %s key with kid %q in key set", alg, header.KeyID)}
+}
+
+// keySetAlgorithms returns the algorithms the signing keys of keySet are
+// advertised for: their alg member, or the conventional algorithm for their key
+// type when alg is absent.
+func keySetAlgorithms(keySet *jose.JSONWebKeySet) []jose.SignatureAlgorithm {
+	var algorithms []jose.SignatureAlgorithm
+	for _, key := range keySet.Keys {
+		if !isSigningKey(key) {
+			continue
+		}
+		algorithm := jose.SignatureAlgorithm(key.Algorithm)
+		if algorithm == "" {
+			candidates := keyAlgorithms(key)
+			if len(candidates) == 0 {
+				continue
+			}
+			algorithm = candidates[0]
+		}
+		if !slices.Contains(algorithms, algorithm) {
+			algorithms = append(algorithms, algorithm)
+		}
+	}
+	return algorithms
+}
+
+// keyAlgorithms returns the algorithms key can verify, most conventional
+// first. A key with an alg member verifies only that algorithm.
+func keyAlgorithms(key jose.JSONWebKey) []jose.SignatureAlgorithm {
+	if key.Algorithm != "" {
+		if slices.Contains(supportedSigningAlgorithms, jose.SignatureAlgorithm(key.Algorithm)) {
+			return []jose.SignatureAlgorithm{jose.SignatureAlgorithm(key.Algorithm)}
+		}
+		return nil
+	}
+	switch k := key.Key.(type) {
+	case *rsa.PublicKey:
+		return []jose.SignatureAlgorithm{jose.RS256, jose.RS384, jose.RS512, jose.PS256, jose.PS384, jose.PS512}
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return []jose.SignatureAlgorithm{jose.ES256}
+		case elliptic.P384():
+			return []jose.SignatureAlgorithm{jose.ES384}
+		case elliptic.P521():
+			return []jose.SignatureAlgorithm{jose.ES512}
+		}
+	case ed25519.PublicKey:
+		return []jose.SignatureAlgorithm{jose.EdDSA}
+	}
+	return nil
+}
+
+// isSigningKey reports whether key is a public key meant for signatures.
+func isSigningKey(key jose.JSONWebKey) bool {
+	return key.IsPublic() && (key.Use == "" || key.Use == "sig")
+}
@@ -0,0 +1,352 @@
+package scalekit
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultLoginStateMaxAge is how long a user has to complete a login started
+// with LoginFlow before the callback is rejected.
+const DefaultLoginStateMaxAge = 10 * time.Minute
+
+// DefaultLoginCookieName is the prefix of the cookies NewCookieLoginStateStore
+// sets when CookieOptions.Name is empty.
+const DefaultLoginCookieName = "scalekit_login"
+
+// LoginState is what LoginFlow remembers between redirecting the browser to
+// the authorization endpoint and handling the callback.
+type LoginState struct {
+	State        string    `json:"s"`
+	Nonce        string    `json:"n"`
+	CodeVerifier string    `json:"v"`
+	ReturnTo     string    `json:"r,omitempty"`
+	CreatedAt    time.Time `json:"t"`
+}
+
+// LoginStateStore keeps login state until the callback. Take must forget the
+// state it returns, and return it only to the browser it was saved for, so
+// that a state issued to one browser cannot complete a login in another. Take
+// returns an error matching ErrInvalidLoginState when no state was saved under
+// the key.
+type LoginStateStore interface {
+	Save(w http.ResponseWriter, r *http.Request, state *LoginState) error
+	Take(w http.ResponseWriter, r *http.Request, key string) (*LoginState, error)
+}
+
+// CookieOptions sets the attributes of the cookies the SDK writes.
+type CookieOptions struct {
+	// Name is the cookie name, or the prefix of the cookie names.
+	Name string
+
+	// Path defaults to "/".
+	Path string
+
+	Domain string
+
+	// MaxAge is the cookie lifetime. Zero uses the store's default.
+	MaxAge time.Duration
+
+	// SameSite defaults to http.SameSiteLaxMode, which lets the cookie through
+	// on the top-level redirect back from Scalekit.
+	SameSite http.SameSite
+
+	// Insecure omits the Secure attribute, for local development over plain
+	// HTTP. Leave it false in production.
+	Insecure bool
+}
+
+// cookie returns a cookie named name with the configured attributes, value
+// and lifetime.
+func (o CookieOptions) cookie(name, value string, maxAge time.Duration) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     o.Path,
+		Domain:   o.Domain,
+		MaxAge:   int(maxAge.Seconds()),
+		Secure:   !o.Insecure,
+		HttpOnly: true,
+		SameSite: o.SameSite,
+	}
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	if cookie.SameSite == 0 {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+	if maxAge < 0 {
+		cookie.MaxAge = -1
+	}
+	return cookie
+}
+
+type cookieLoginStateStore struct {
+	sealer  *sealer
+	options CookieOptions
+}
+
+// NewCookieLoginStateStore returns a LoginStateStore that keeps each login
+// state in its own cookie, named after the state, so that logins started in
+// several tabs do not interfere. The state is encrypted and authenticated
+// with AES-256-GCM under a key derived from secret with HMAC-SHA256, and
+// bound to the cookie name, so a state cannot be read, modified or replayed
+// under another state's cookie. secret must
+// be at least 32 bytes; keep it out of source control and share it between
+// the instances of a service. The cookie lifetime defaults to
+// DefaultLoginStateMaxAge.
+func NewCookieLoginStateStore(secret []byte, options CookieOptions) (LoginStateStore, error) {
+	sealer, err := newSealer(secret, "login state")
+	if err != nil {
+		return nil, err
+	}
+	if options.Name == "" {
+		options.Name = DefaultLoginCookieName
+	}
+	if options.MaxAge == 0 {
+		options.MaxAge = DefaultLoginStateMaxAge
+	}
+	return &cookieLoginStateStore{sealer: sealer, options: options}, nil
+}
+
+func (s *cookieLoginStateStore) cookieName(key string) string {
+	return s.options.Name + "_" + key
+}
+
+func (s *cookieLoginStateStore) Save(w http.ResponseWriter, _ *http.Request, state *LoginState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	name := s.cookieName(state.State)
+	value, err := s.sealer.seal(data, name)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, s.options.cookie(name, value, s.options.MaxAge))
+	return nil
+}
+
+func (s *cookieLoginStateStore) Take(w http.ResponseWriter, r *http.Request, key string) (*LoginState, error) {
+	name := s.cookieName(key)
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return nil, ErrInvalidLoginState
+	}
+	http.SetCookie(w, s.options.cookie(name, "", -1))
+	data, err := s.sealer.open(cookie.Value, name)
+	if err != nil {
+		return nil, ErrInvalidLoginState
+	}
+	var state LoginState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, ErrInvalidLoginState
+	}
+	return &state, nil
+}
+
+// LoginFlowOptions configures NewLoginFlow.
+type LoginFlowOptions struct {
+	// RedirectUri is the callback URL registered for the application. Required.
+	RedirectUri string
+
+	// Store keeps login state until the callback. Defaults to a
+	// NewCookieLoginStateStore using Secret and Cookie.
+	Store LoginStateStore
+
+	// Secret seals the default cookie store's cookies. Required when Store is
+	// nil.
+	Secret []byte
+
+	// Cookie sets the attributes of the default store's cookies.
+	Cookie CookieOptions
+
+	// MaxAge is how long a login may take. Defaults to DefaultLoginStateMaxAge.
+	MaxAge time.Duration
+}
+
+// LoginOptions customizes a single login started with LoginFlow.
+type LoginOptions struct {
+	// AuthorizationUrlOptions are passed to GetAuthorizationUrl. Their State,
+	// Nonce, CodeChallenge and CodeChallengeMethod are generated by the flow
+	// and must be left empty.
+	AuthorizationUrlOptions
+
+	// ReturnTo is where the application sends the user after the login, and
+	// is returned by Callback. It must be a path on the application, such as
+	// "/settings?tab=sso".
+	ReturnTo string
+}
+
+// LoginResult is the outcome of a completed login.
+type LoginResult struct {
+	*AuthenticationResponse
+
+	// ReturnTo is the LoginOptions.ReturnTo of the login.
+	ReturnTo string
+}
+
+// AuthorizationError is returned by LoginFlow.Callback when Scalekit
+// redirects back with an OAuth error instead of a code, for example when the
+// user denies consent or the identity provider rejects the login.
+type AuthorizationError struct {
+	// Code is the OAuth error code, such as "access_denied".
+	Code        string
+	Description string
+}
+
+func (e *AuthorizationError) Error() string {
+	if e.Description == "" {
+		return fmt.Sprintf("authorization failed: %s", e.Code)
+	}
+	return fmt.Sprintf("authorization failed: %s: %s", e.Code, e.Description)
+}
+
+// LoginFlow runs the authorization code flow for a web application. It
+// generates the state, nonce and PKCE verifier of each login, keeps them in a
+// LoginStateStore, and checks them when the browser returns, so the
+// application does not have to.
+//
+//	flow, err := scalekit.NewLoginFlow(client, scalekit.LoginFlowOptions{
+//		RedirectUri: "https://app.example.com/auth/callback",
+//		Secret:      cookieSecret,
+//	})
+//
+//	// GET /login
+//	authUrl, err := flow.AuthorizationUrl(w, r, scalekit.LoginOptions{ReturnTo: "/dashboard"})
+//	http.Redirect(w, r, authUrl.String(), http.StatusFound)
+//
+//	// GET /auth/callback
+//	result, err := flow.Callback(w, r)
+type LoginFlow struct {
+	client      Scalekit
+	store       LoginStateStore
+	redirectUri string
+	maxAge      time.Duration
+}
+
+// NewLoginFlow returns a LoginFlow that authenticates users with client.
+func NewLoginFlow(client Scalekit, options LoginFlowOptions) (*LoginFlow, error) {
+	if options.RedirectUri == "" {
+		return nil, ErrRedirectUriRequired
+	}
+	store := options.Store
+	if store == nil {
+		var err error
+		cookie := options.Cookie
+		if cookie.MaxAge == 0 && options.MaxAge > 0 {
+			cookie.MaxAge = options.MaxAge
+		}
+		store, err = NewCookieLoginStateStore(options.Secret, cookie)
+		if err != nil {
+			return nil, err
+		}
+	}
+	maxAge := options.MaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultLoginStateMaxAge
+	}
+	return &LoginFlow{client: client, store: store, redirectUri: options.RedirectUri, maxAge: maxAge}, nil
+}
+
+// AuthorizationUrl starts a login: it saves a new login state for the browser
+// making r and returns the authorization URL to redirect it to. It returns
+// ErrInvalidReturnTo when options.ReturnTo is not a local path.
+func (f *LoginFlow) AuthorizationUrl(w http.ResponseWriter, r *http.Request, options LoginOptions) (*url.URL, error) {
+	if options.ReturnTo != "" && !IsLocalRedirect(options.ReturnTo) {
+		return nil, ErrInvalidReturnTo
+	}
+	pkce, err := f.client.GeneratePKCEConfiguration(PKCEOptions{})
+	if err != nil {
+		return nil, err
+	}
+	state := &LoginState{
+		State:        randomValue(),
+		Nonce:        randomValue(),
+		CodeVerifier: pkce.CodeVerifier,
+		ReturnTo:     options.ReturnTo,
+		CreatedAt:    time.Now().Truncate(time.Second),
+	}
+
+	urlOptions := options.AuthorizationUrlOptions
+	urlOptions.State = state.State
+	urlOptions.Nonce = state.Nonce
+	urlOptions.CodeChallenge = pkce.CodeChallenge
+	urlOptions.CodeChallengeMethod = pkce.CodeChallengeMethod
+	authUrl, err := f.client.GetAuthorizationUrl(f.redirectUri, urlOptions)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.store.Save(w, r, state); err != nil {
+		return nil, err
+	}
+	return authUrl, nil
+}
+
+// Callback completes the login whose callback request is r. It takes the
+// saved login state matching the request's state parameter, checks that it
+// has not expired, and exchanges the code with AuthenticateWithCode using the
+// saved PKCE verifier and nonce.
+//
+// It returns an error matching ErrInvalidLoginState when the state is missing,
+// unknown, already used or was issued to another browser, and
+// ErrLoginStateExpired when the login took longer than the flow's MaxAge. Only
+// then is an OAuth error in the request reported, as an *AuthorizationError,
+// so that forged callbacks cannot show arbitrary error messages.
+func (f *LoginFlow) Callback(w http.ResponseWriter, r *http.Request) (*LoginResult, error) {
+	query := r.URL.Query()
+	key := query.Get("state")
+	var state *LoginState
+	var stateErr error
+	if key == "" {
+		stateErr = ErrInvalidLoginState
+	} else {
+		state, stateErr = f.store.Take(w, r, key)
+	}
+
+	if stateErr != nil {
+		return nil, stateErr
+	}
+	if subtle.ConstantTimeCompare([]byte(state.State), []byte(key)) != 1 {
+		return nil, ErrInvalidLoginState
+	}
+	if time.Since(state.CreatedAt) > f.maxAge {
+		return nil, ErrLoginStateExpired
+	}
+	if code := query.Get("error"); code != "" {
+		return nil, &AuthorizationError{Code: code, Description: query.Get("error_description")}
+	}
+
+	resp, err := f.client.AuthenticateWithCode(r.Context(), query.Get("code"), f.redirectUri, AuthenticationOptions{
+		CodeVerifier: state.CodeVerifier,
+		Nonce:        state.Nonce,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{AuthenticationResponse: resp, ReturnTo: state.ReturnTo}, nil
+}
+
+// IsLocalRedirect reports whether target is a path on the current site, such
+// as "/dashboard?tab=1", and so is safe to redirect to without creating an
+// open redirect.
+func IsLocalRedirect(target string) bool {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.ContainsAny(target, "\\\r\n") {
+		return false
+	}
+	u, err := url.Parse(target)
+	return err == nil && u.Scheme == "" && u.Host == ""
+}
+
+// randomValue returns 32 random bytes, base64url encoded.
+func randomValue() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
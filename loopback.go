@@ -0,0 +1,166 @@
+package scalekit
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// DefaultLoopbackTimeout is how long LoginWithLoopback waits for the browser
+// to return when LoopbackLoginOptions.Timeout is zero.
+const DefaultLoopbackTimeout = 5 * time.Minute
+
+const loopbackCallbackPath = "/callback"
+
+const loopbackResponsePage = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>%[1]s</title></head>
+<body><p>%[1]s You can close this window and return to the application.</p></body></html>
+`
+
+// LoopbackLoginOptions configures LoginWithLoopback.
+type LoopbackLoginOptions struct {
+	// AuthorizationUrlOptions are passed to GetAuthorizationUrl. Their State,
+	// Nonce, CodeChallenge and CodeChallengeMethod are generated and must be
+	// left empty.
+	AuthorizationUrlOptions
+
+	// OpenBrowser sends the user to the authorization URL. Defaults to opening
+	// the system browser. Replace it to print the URL as well, for users on a
+	// machine without one.
+	OpenBrowser func(authorizationUrl string) error
+
+	// Timeout bounds the wait for the browser to return. Defaults to
+	// DefaultLoopbackTimeout.
+	Timeout time.Duration
+}
+
+// loopbackCallback is what the browser brought back to the loopback listener.
+type loopbackCallback struct {
+	code string
+	err  error
+}
+
+// LoginWithLoopback signs a user in from a desktop or command-line application
+// with a loopback redirect (RFC 8252). It listens on 127.0.0.1 on an ephemeral
+// port, opens the authorization URL with PKCE, state and nonce, waits for the
+// browser to return to http://127.0.0.1:<port>/callback, and exchanges the
+// code as a public client, without the client secret. The listener is shut
+// down before LoginWithLoopback returns.
+//
+// Register http://127.0.0.1/callback as a redirect URI of the application;
+// the port is chosen at run time.
+//
+// It returns an *AuthorizationError when the user or Scalekit rejects the
+// login, and ErrLoginTimeout when the browser does not return within the
+// timeout.
+func LoginWithLoopback(ctx context.Context, client Scalekit, options LoopbackLoginOptions) (*AuthenticationResponse, error) {
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = DefaultLoopbackTimeout
+	}
+	openBrowser := options.OpenBrowser
+	if openBrowser == nil {
+		openBrowser = openSystemBrowser
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	redirectUri := fmt.Sprintf("http://%s%s", listener.Addr().String(), loopbackCallbackPath)
+
+	pkce, err := client.GeneratePKCEConfiguration(PKCEOptions{})
+	if err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	state, nonce := randomValue(), randomValue()
+	urlOptions := options.AuthorizationUrlOptions
+	urlOptions.State = state
+	urlOptions.Nonce = nonce
+	urlOptions.CodeChallenge = pkce.CodeChallenge
+	urlOptions.CodeChallengeMethod = pkce.CodeChallengeMethod
+	authUrl, err := client.GetAuthorizationUrl(redirectUri, urlOptions)
+	if err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+
+	callbacks := make(chan loopbackCallback, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+loopbackCallbackPath, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		// Requests without this login's state come from elsewhere and are
+		// ignored, so they cannot end the wait.
+		if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(state)) != 1 {
+			http.Error(w, ErrInvalidLoginState.Error(), http.StatusBadRequest)
+			return
+		}
+		callback := loopbackCallback{code: query.Get("code")}
+		message := "Login complete."
+		if code := query.Get("error"); code != "" {
+			callback.err = &AuthorizationError{Code: code, Description: query.Get("error_description")}
+			message = "Login failed."
+		}
+		// The page is flushed before the callback is handed over, because the
+		// server is closed as soon as it is.
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, loopbackResponsePage, message)
+		_ = http.NewResponseController(w).Flush()
+		select {
+		case callbacks <- callback:
+		default:
+		}
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = server.Serve(listener) }()
+	// Close rather than Shutdown: browsers keep speculative connections open,
+	// which Shutdown would wait on for seconds.
+	defer func() { _ = server.Close() }()
+
+	if err := openBrowser(authUrl.String()); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var callback loopbackCallback
+	select {
+	case callback = <-callbacks:
+	case <-timer.C:
+		return nil, ErrLoginTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if callback.err != nil {
+		return nil, callback.err
+	}
+	return client.WithSecret("").AuthenticateWithCode(ctx, callback.code, redirectUri, AuthenticationOptions{
+		CodeVerifier: pkce.CodeVerifier,
+		Nonce:        nonce,
+	})
+}
+
+// openSystemBrowser opens target in the user's default browser.
+func openSystemBrowser(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	if err := cmd.Start(); err != nil {
+		return errors.Join(ErrBrowserUnavailable, err)
+	}
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
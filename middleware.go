@@ -0,0 +1,205 @@
+package scalekit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TokenExtractor reads an access token from a request. It returns an empty
+// token and a nil error when the request carries none, and an error when the
+// credential is present but malformed.
+type TokenExtractor func(r *http.Request) (string, error)
+
+// TokenFromAuthorizationHeader extracts a token sent as
+// "Authorization: Bearer <token>" (RFC 6750 section 2.1).
+func TokenFromAuthorizationHeader(r *http.Request) (string, error) {
+	return bearerToken(r.Header)
+}
+
+// bearerToken returns the Bearer token in the Authorization header of h, or ""
+// when there is no such header.
+func bearerToken(h http.Header) (string, error) {
+	header := h.Get("Authorization")
+	if header == "" {
+		return "", nil
+	}
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", ErrInvalidAuthorizationHeader
+	}
+	return strings.TrimSpace(token), nil
+}
+
+// TokenFromCookie returns a TokenExtractor that reads the named cookie.
+func TokenFromCookie(name string) TokenExtractor {
+	return func(r *http.Request) (string, error) {
+		cookie, err := r.Cookie(name)
+		if errors.Is(err, http.ErrNoCookie) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return cookie.Value, nil
+	}
+}
+
+// TokenFromQuery returns a TokenExtractor that reads the named query
+// parameter. RFC 6750 discourages query tokens because URLs end up in logs;
+// prefer the Authorization header where clients can set it.
+func TokenFromQuery(name string) TokenExtractor {
+	return func(r *http.Request) (string, error) {
+		return r.URL.Query().Get(name), nil
+	}
+}
+
+// AuthMiddlewareOptions configures NewAuthMiddleware.
+type AuthMiddlewareOptions struct {
+	// ValidateTokenOptions holds the checks every request's token must pass,
+	// such as audiences and scopes.
+	ValidateTokenOptions
+
+	// Extractors are tried in order and the first token found is validated.
+	// Defaults to TokenFromAuthorizationHeader.
+	Extractors []TokenExtractor
+
+	// Realm is reported in the WWW-Authenticate challenge when set.
+	Realm string
+
+	// ErrorHandler writes the response for requests that fail authentication.
+	// Defaults to WriteBearerError with the configured realm and scopes.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// NewAuthMiddleware returns net/http middleware that requires a valid access
+// token on every request. Tokens are validated with
+// GetAccessTokenClaimsWithOptions when client implements
+// AccessTokenClaimsValidator, as NewScalekitClient's does, and the claims of accepted tokens are
+// available to the wrapped handler through AccessTokenClaimsFromContext.
+//
+//	mux.Handle("/api/", scalekit.NewAuthMiddleware(client, scalekit.AuthMiddlewareOptions{
+//		ValidateTokenOptions: scalekit.ValidateTokenOptions{Audience: []string{"https://api.example.com"}},
+//	})(apiHandler))
+func NewAuthMiddleware(client Scalekit, options AuthMiddlewareOptions) func(http.Handler) http.Handler {
+	extractors := options.Extractors
+	if len(extractors) == 0 {
+		extractors = []TokenExtractor{TokenFromAuthorizationHeader}
+	}
+	errorHandler := options.ErrorHandler
+	if errorHandler == nil {
+		errorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			WriteBearerError(w, err, options.Realm, options.Scopes...)
+		}
+	}
+	validateOptions := options.ValidateTokenOptions
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := extractToken(r, extractors)
+			if err != nil {
+				errorHandler(w, r, err)
+				return
+			}
+			claims, err := accessTokenClaims(r.Context(), client, token, &validateOptions)
+			if err != nil {
+				errorHandler(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ContextWithAccessTokenClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// extractToken returns the first token found by extractors, or an error
+// matching ErrTokenRequired when there is none.
+func extractToken(r *http.Request, extractors []TokenExtractor) (string, error) {
+	for _, extract := range extractors {
+		token, err := extract(r)
+		if err != nil {
+			return "", err
+		}
+		if token != "" {
+			return token, nil
+		}
+	}
+	return "", ErrTokenRequired
+}
+
+type accessTokenClaimsKey struct{}
+
+// ContextWithAccessTokenClaims returns a context carrying claims, as
+// NewAuthMiddleware does for authenticated requests.
+func ContextWithAccessTokenClaims(ctx context.Context, claims *AccessTokenClaims) context.Context {
+	return context.WithValue(ctx, accessTokenClaimsKey{}, claims)
+}
+
+// AccessTokenClaimsFromContext returns the claims of the access token that
+// authenticated the request, as stored by NewAuthMiddleware and
+// NewAuthInterceptor.
+func AccessTokenClaimsFromContext(ctx context.Context) (*AccessTokenClaims, bool) {
+	claims, ok := ctx.Value(accessTokenClaimsKey{}).(*AccessTokenClaims)
+	return claims, ok && claims != nil
+}
+
+// WriteBearerError writes an RFC 6750 error response for a failed
+// authentication:
+//   - no token: 401 with a bare Bearer challenge;
+//   - ErrInvalidAuthorizationHeader: 400 invalid_request;
+//   - ErrInsufficientScope: 403 insufficient_scope, listing scopes;
+//   - an SDK *Error, such as a failed JWKS fetch: 503 without a challenge;
+//   - any other error: 401 invalid_token.
+func WriteBearerError(w http.ResponseWriter, err error, realm string, scopes ...string) {
+	var params []string
+	if realm != "" {
+		params = append(params, fmt.Sprintf("realm=%q", bearerParam(realm)))
+	}
+
+	var status int
+	var code string
+	var sdkErr *Error
+	switch {
+	case errors.Is(err, ErrTokenRequired):
+		status = http.StatusUnauthorized
+	case errors.Is(err, ErrInvalidAuthorizationHeader):
+		status, code = http.StatusBadRequest, "invalid_request"
+	case errors.Is(err, ErrInsufficientScope):
+		status, code = http.StatusForbidden, "insufficient_scope"
+	case errors.As(err, &sdkErr):
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	default:
+		status, code = http.StatusUnauthorized, "invalid_token"
+	}
+
+	if code != "" {
+		params = append(params,
+			fmt.Sprintf("error=%q", code),
+			fmt.Sprintf("error_description=%q", bearerParam(err.Error())))
+		if code == "insufficient_scope" && len(scopes) > 0 {
+			params = append(params, fmt.Sprintf("scope=%q", bearerParam(strings.Join(scopes, " "))))
+		}
+	}
+	challenge := "Bearer"
+	if len(params) > 0 {
+		challenge += " " + strings.Join(params, ", ")
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, http.StatusText(status), status)
+}
+
+// bearerParam drops the characters RFC 6750 does not allow in challenge
+// parameter values, so %q never needs to escape anything.
+func bearerParam(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '"':
+			return '\''
+		case r == '\\' || r < 0x20 || r > 0x7e:
+			return -1
+		}
+		return r
+	}, value)
+}
@@ -0,0 +1,161 @@
+package scalekit
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// Option configures a client created with NewScalekitClient. Options are passed
+// in the variadic opts argument alongside (or instead of) the client secret.
+type Option func(*coreClient)
+
+// WithHTTPClient sets the HTTP client used for every request the SDK makes: the
+// management RPCs as well as the OAuth token and JWKS endpoints. Its Transport,
+// Timeout, Jar and CheckRedirect settings are honored; the client itself is not
+// modified.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *coreClient) {
+		c.baseHTTPClient = client
+	}
+}
+
+// WithTransport sets the RoundTripper used for every request the SDK makes.
+// Use it to add proxies, mTLS or connection-pool tuning in one place. It takes
+// precedence over the Transport of a client passed to WithHTTPClient.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *coreClient) {
+		c.transport = transport
+	}
+}
+
+// WithTimeout sets the deadline applied to each request whose context has no
+// deadline of its own. A zero or negative timeout disables the SDK deadline.
+// Defaults to 10 seconds.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *coreClient) {
+		c.timeout = timeout
+	}
+}
+
+// WithUserAgentSuffix appends suffix to the SDK's User-Agent header, e.g. to
+// identify the calling application.
+func WithUserAgentSuffix(suffix string) Option {
+	return func(c *coreClient) {
+		c.userAgentSuffix = suffix
+	}
+}
+
+// WithAPIVersion overrides the x-api-version header sent with every request.
+func WithAPIVersion(version string) Option {
+	return func(c *coreClient) {
+		if version != "" {
+			c.apiVersion = version
+		}
+	}
+}
+
+// WithRetryPolicy sets the policy used to retry requests that fail with
+// transient errors. Use ContextWithRetryPolicy to override it for a single
+// call and NoRetryPolicy to disable retries. Defaults to DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *coreClient) {
+		c.retry = policy
+	}
+}
+
+// WithJWKSCacheTTL sets how long the environment's JSON Web Key Set is reused
+// before it is refetched. A shorter Cache-Control max-age returned by the server
+// takes precedence. Defaults to one hour.
+func WithJWKSCacheTTL(ttl time.Duration) Option {
+	return func(c *coreClient) {
+		if ttl > 0 {
+			c.jwksCacheTTL = ttl
+		}
+	}
+}
+
+// WithJWKSMinRefreshInterval sets the minimum time between two JWKS fetches
+// triggered by tokens whose kid is not in the cached key set. It is also the
+// lower bound for lifetimes derived from Cache-Control. Defaults to 30 seconds.
+func WithJWKSMinRefreshInterval(interval time.Duration) Option {
+	return func(c *coreClient) {
+		if interval >= 0 {
+			c.jwksMinRefreshInterval = interval
+		}
+	}
+}
+
+// WithTokenRefreshSkew sets how long before expiry the client-credentials token
+// used for management calls is refreshed. Tokens within twice the skew are also
+// refreshed in the background. Defaults to one minute.
+func WithTokenRefreshSkew(skew time.Duration) Option {
+	return func(c *coreClient) {
+		if skew >= 0 {
+			c.tokenRefreshSkew = skew
+		}
+	}
+}
+
+// WithIssuer sets the iss claim expected in tokens validated by the client.
+// Defaults to the environment URL; set it when tokens are issued under a
+// custom domain. An empty issuer disables the check.
+func WithIssuer(issuer string) Option {
+	return func(c *coreClient) {
+		c.issuer = issuer
+		c.issuerConfigured = true
+	}
+}
+
+// WithTokenLeeway sets the clock skew tolerated when the client checks the exp,
+// nbf and iat claims of tokens, so that servers whose clock drifts slightly
+// from Scalekit's accept fresh tokens. By default no skew is tolerated.
+func WithTokenLeeway(leeway time.Duration) Option {
+	return func(c *coreClient) {
+		c.tokenLeeway = leeway
+	}
+}
+
+// WithSigningAlgorithms sets the JWS algorithms accepted for the signatures of
+// tokens the client validates, such as jose.RS256 and jose.ES256. By default
+// the algorithms advertised by the environment's key set are accepted; use this
+// option to restrict them, or to accept others for keys without an alg, such as
+// PS256 for RSA keys. Symmetric algorithms are never accepted and are ignored.
+func WithSigningAlgorithms(algorithms ...jose.SignatureAlgorithm) Option {
+	return func(c *coreClient) {
+		c.signingAlgorithms = nil
+		for _, algorithm := range algorithms {
+			if slices.Contains(supportedSigningAlgorithms, algorithm) {
+				c.signingAlgorithms = append(c.signingAlgorithms, algorithm)
+			}
+		}
+	}
+}
+
+// WithDiscovery locates the authorization, token, JWKS, end-session and device
+// authorization endpoints through the environment's OpenID Provider metadata
+// instead of their default paths, and expects the issuer and ID token signing
+// algorithms it advertises unless WithIssuer or WithSigningAlgorithms is set.
+// Endpoints missing from the metadata, or all of them while it cannot be
+// fetched, fall back to the default paths. GetAuthorizationUrl and
+// GetLogoutUrl do not fetch the metadata; they use the discovered endpoints
+// once another call has fetched it. Metadata whose issuer is not the
+// environment URL, or the issuer set with WithIssuer, is rejected, and
+// discovered endpoints on another host fall back to the default paths.
+func WithDiscovery() Option {
+	return func(c *coreClient) {
+		c.discovery = true
+	}
+}
+
+// WithDiscoveryCacheTTL sets how long the OpenID Provider metadata is reused
+// before it is refetched. Defaults to one hour.
+func WithDiscoveryCacheTTL(ttl time.Duration) Option {
+	return func(c *coreClient) {
+		if ttl > 0 {
+			c.discoveryCacheTTL = ttl
+		}
+	}
+}
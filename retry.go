@@ -0,0 +1,256 @@
+package scalekit
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+)
+
+const (
+	defaultRetryMaxAttempts    = 3
+	defaultRetryInitialBackoff = 250 * time.Millisecond
+	defaultRetryMaxBackoff     = 5 * time.Second
+	defaultRetryMultiplier     = 2.0
+	defaultRetryJitter         = 0.2
+
+	// maxRetryAfter is the longest server-requested delay the SDK waits for
+	// before retrying; longer delays end the retries with the last error.
+	maxRetryAfter = time.Minute
+)
+
+// RetryPolicy controls how the SDK retries requests that fail with transient
+// errors. It applies to management RPCs as well as the OAuth token and JWKS
+// endpoints. Zero-valued fields fall back to the defaults of DefaultRetryPolicy,
+// except Jitter, where zero disables jitter.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Use 1, or NoRetryPolicy, to disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the exponential backoff delay. A Retry-After returned by
+	// the server may exceed it, up to one minute.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor the delay grows by after each attempt.
+	Multiplier float64
+
+	// Jitter randomizes each delay by up to ±Jitter of its value (0 to 1).
+	Jitter float64
+
+	// Retryable reports whether a failed attempt should be retried. idempotent
+	// reports whether repeating the request is safe. Defaults to DefaultRetryable.
+	Retryable func(err error, idempotent bool) bool
+}
+
+// DefaultRetryPolicy returns the policy used when none is configured: three
+// attempts with exponential backoff starting at 250ms, capped at 5s, with 20%
+// jitter, retrying the errors accepted by DefaultRetryable.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    defaultRetryMaxAttempts,
+		InitialBackoff: defaultRetryInitialBackoff,
+		MaxBackoff:     defaultRetryMaxBackoff,
+		Multiplier:     defaultRetryMultiplier,
+		Jitter:         defaultRetryJitter,
+		Retryable:      DefaultRetryable,
+	}
+}
+
+// NoRetryPolicy returns a policy that never retries.
+func NoRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 1}
+}
+
+// DefaultRetryable is the default RetryPolicy.Retryable. Rate-limited requests
+// (HTTP 429, CodeResourceExhausted or any response carrying Retry-After) were
+// not processed by the server and are always retried. Other transient failures
+// (HTTP 502, 503 and 504, CodeUnavailable, CodeDeadlineExceeded and network
+// errors) are retried only for idempotent requests, so creates are never
+// repeated after the server may already have applied them.
+func DefaultRetryable(err error, idempotent bool) bool {
+	if isRateLimitedError(err) {
+		return true
+	}
+	if !idempotent || isCertificateError(err) {
+		return false
+	}
+	var httpErr *Error
+	if errors.As(err, &httpErr) {
+		switch httpErr.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var connectErr *connect.Error
+	if errors.As(err, &connectErr) {
+		switch connectErr.Code() {
+		case connect.CodeUnavailable, connect.CodeDeadlineExceeded:
+			return true
+		}
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var opErr *net.OpError
+	var netErr net.Error
+	return errors.As(err, &opErr) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// isCertificateError reports whether err is a TLS certificate failure, which
+// no amount of retrying will fix.
+func isCertificateError(err error) bool {
+	var verificationErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	return errors.As(err, &verificationErr) || errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
+
+// isRateLimitedError reports whether err says the request was throttled,
+// either explicitly or by asking the client to come back later.
+func isRateLimitedError(err error) bool {
+	if IsRateLimited(err) {
+		return true
+	}
+	_, ok := retryAfter(err)
+	return ok
+}
+
+// retryAfter returns the delay requested by the Retry-After header of the
+// failed response, in either delay-seconds or HTTP-date form.
+func retryAfter(err error) (time.Duration, bool) {
+	var header http.Header
+	var httpErr *Error
+	var connectErr *connect.Error
+	switch {
+	case errors.As(err, &httpErr):
+		header = httpErr.header
+	case errors.As(err, &connectErr):
+		header = connectErr.Meta()
+	}
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 || seconds > math.MaxInt64/int64(time.Second) {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+type retryPolicyKey struct{}
+
+// ContextWithRetryPolicy returns a context that makes SDK calls using it follow
+// policy instead of the client's retry policy.
+func ContextWithRetryPolicy(ctx context.Context, policy RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, policy)
+}
+
+// retryPolicy returns the policy for a call made with ctx, with defaults applied.
+func (c *coreClient) retryPolicy(ctx context.Context) RetryPolicy {
+	policy := c.retry
+	if p, ok := ctx.Value(retryPolicyKey{}).(RetryPolicy); ok {
+		policy = p
+	}
+	if policy.MaxAttempts == 0 {
+		policy.MaxAttempts = defaultRetryMaxAttempts
+	}
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = defaultRetryInitialBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = defaultRetryMaxBackoff
+	}
+	if policy.Multiplier < 1 {
+		policy.Multiplier = defaultRetryMultiplier
+	}
+	policy.Jitter = min(max(policy.Jitter, 0), 1)
+	if policy.Retryable == nil {
+		policy.Retryable = DefaultRetryable
+	}
+	return policy
+}
+
+// backoff returns the delay before retry number attempt (starting at 1),
+// honoring the server's Retry-After when it asks for a longer wait. It reports
+// false when the server asks to wait longer than maxRetryAfter.
+func (p RetryPolicy) backoff(attempt int, err error) (time.Duration, bool) {
+	delay := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	delay = min(delay, float64(p.MaxBackoff))
+	delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	backoff := time.Duration(delay)
+	if after, ok := retryAfter(err); ok && after > backoff {
+		return after, after <= maxRetryAfter
+	}
+	return backoff, true
+}
+
+// withRetry calls attempt until it succeeds, the policy gives up, or ctx is
+// done. idempotent is consulted after a failure, so it may depend on details
+// learned during the attempt. The last error is returned when retries stop.
+func withRetry[T any](ctx context.Context, c *coreClient, idempotent func() bool, attempt func() (T, error)) (T, error) {
+	policy := c.retryPolicy(ctx)
+	for n := 1; ; n++ {
+		result, err := attempt()
+		if err == nil || n >= policy.MaxAttempts || ctx.Err() != nil || !policy.Retryable(err, idempotent()) {
+			return result, err
+		}
+		delay, ok := policy.backoff(n, err)
+		if !ok {
+			return result, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return result, err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+	}
+}
+
+// isIdempotentProcedure reports whether an RPC can safely be repeated. It uses
+// the idempotency level declared in the schema when present and otherwise the
+// method name: gets, lists and deletes are idempotent. Creates, updates and
+// other actions are not, as the API does not guarantee that repeating them has
+// no further effect.
+func isIdempotentProcedure(spec connect.Spec) bool {
+	if spec.IdempotencyLevel != connect.IdempotencyUnknown {
+		return true
+	}
+	method := spec.Procedure[strings.LastIndex(spec.Procedure, "/")+1:]
+	for _, prefix := range []string{"Get", "List", "Delete"} {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -9,7 +9,6 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
-	"strconv"
 	"strings"
 	"time"
 
@@ -23,15 +22,24 @@ const (
 	GrantTypeAuthorizationCode GrantType = "authorization_code"
 	GrantTypeRefreshToken      GrantType = "refresh_token"
 	GrantTypeClientCredentials GrantType = "client_credentials"
+	GrantTypeDeviceCode        GrantType = "urn:ietf:params:oauth:grant-type:device_code"
+	GrantTypeTokenExchange     GrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
 )
 
-var (
-	webhookTolerance        = 5 * time.Minute
-	webhookSignatureVersion = "v1"
-)
+const webhookSignatureVersion = "v1"
 
 type GrantType = string
 
+// Scalekit is a client of a Scalekit environment, created with
+// NewScalekitClient.
+//
+// The client returned by NewScalekitClient also implements the optional
+// interfaces AccessTokenClaimsValidator, DeviceAuthorizer, TokenExchanger,
+// ClientTokenSourcer and ProviderMetadataFetcher. They are kept out of
+// Scalekit so that other implementations, such as test doubles, do not break
+// when the client gains methods. Reach them with a type assertion:
+//
+//	exchanger, ok := client.(scalekit.TokenExchanger)
 type Scalekit interface {
 	Connection() Connection
 	Directory() Directory
@@ -67,6 +75,21 @@ type Scalekit interface {
 	WithSecret(clientSecret string) Scalekit
 }
 
+var _ interface {
+	Scalekit
+	AccessTokenClaimsValidator
+	DeviceAuthorizer
+	TokenExchanger
+	ClientTokenSourcer
+	ProviderMetadataFetcher
+} = (*scalekitClient)(nil)
+
+// AccessTokenClaimsValidator validates access tokens with per-call options and
+// returns their claims.
+type AccessTokenClaimsValidator interface {
+	GetAccessTokenClaimsWithOptions(ctx context.Context, accessToken string, options *ValidateTokenOptions) (*AccessTokenClaims, error)
+}
+
 type scalekitClient struct {
 	coreClient   *coreClient
 	connection   Connection
@@ -101,6 +124,9 @@ type AuthorizationUrlOptions struct {
 
 type AuthenticationOptions struct {
 	CodeVerifier string
+	// Nonce is the nonce sent in AuthorizationUrlOptions. When set, the ID
+	// token's nonce claim must match it.
+	Nonce string
 }
 
 // ValidateTokenOptions defines optional validations for token verification.
@@ -112,6 +138,25 @@ type ValidateTokenOptions struct {
 	// Scopes is the optional set of scopes that must be present in the token's
 	// space-delimited scope claim.
 	Scopes []string
+
+	// Issuer is the expected iss claim. Client methods default to the
+	// environment URL, or to the issuer configured with WithIssuer.
+	Issuer string
+
+	// Leeway is the clock skew tolerated when checking the exp, nbf and iat
+	// claims. Zero uses the client's leeway, which is none unless set with
+	// WithTokenLeeway. A negative value tolerates no skew.
+	Leeway time.Duration
+
+	// MaxAge rejects tokens whose iat claim is older than MaxAge. Zero accepts
+	// tokens of any age.
+	MaxAge time.Duration
+
+	// SigningAlgorithms are the JWS algorithms accepted for the token
+	// signature. When empty, the algorithms advertised by the key set's alg
+	// and kty values are accepted. Client methods default to the algorithms
+	// set with WithSigningAlgorithms.
+	SigningAlgorithms []jose.SignatureAlgorithm
 }
 
 type AuthenticationResponse struct {
@@ -157,13 +202,32 @@ func (i *IdTokenClaims) UnmarshalJSON(data []byte) error {
 
 type Audience []string
 
+// UnmarshalJSON accepts the aud claim as a single string or an array of
+// strings (RFC 7519 section 4.1.3).
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if json.Unmarshal(data, &single) == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+	return nil
+}
+
 type AccessTokenClaims struct {
 	Sub      string   `json:"sub"`
 	Iss      string   `json:"iss"`
 	Audience Audience `json:"aud,omitempty"`
 	Iat      int      `json:"iat"`
 	Exp      int      `json:"exp"`
-	Claims   Claims   `json:"-"`
+	// Act names the party acting on behalf of Sub in tokens issued by
+	// ExchangeToken.
+	Act    *Actor `json:"act,omitempty"`
+	Claims Claims `json:"-"`
 }
 
 func (a *AccessTokenClaims) UnmarshalJSON(data []byte) error {
@@ -238,7 +302,8 @@ type LogoutUrlOptions struct {
 // NewScalekitClient creates a new Scalekit client.
 //
 // For backward compatibility, when a value is provided in opts and opts[0] is a
-// string, it is treated as client_secret.
+// string, it is treated as client_secret. Any Option values in opts configure
+// the client; values of other types are ignored.
 func NewScalekitClient(envUrl, clientId string, opts ...any) Scalekit {
 	clientSecret := ""
 	if len(opts) > 0 {
@@ -246,7 +311,13 @@ func NewScalekitClient(envUrl, clientId string, opts ...any) Scalekit {
 			clientSecret = secret
 		}
 	}
-	return newScalekitClient(newCoreClient(envUrl, clientId, clientSecret))
+	var options []Option
+	for _, opt := range opts {
+		if option, ok := opt.(Option); ok {
+			options = append(options, option)
+		}
+	}
+	return newScalekitClient(newCoreClient(envUrl, clientId, clientSecret, options...))
 }
 
 func newScalekitClient(coreClient *coreClient) *scalekitClient {
@@ -270,7 +341,7 @@ func newScalekitClient(coreClient *coreClient) *scalekitClient {
 }
 
 func (s *scalekitClient) WithSecret(clientSecret string) Scalekit {
-	core := newCoreClient(s.coreClient.envUrl, s.coreClient.clientId, clientSecret)
+	core := newCoreClient(s.coreClient.envUrl, s.coreClient.clientId, clientSecret, s.coreClient.options...)
 	core.sdkVersion = s.coreClient.sdkVersion
 	core.apiVersion = s.coreClient.apiVersion
 	core.userAgent = s.coreClient.userAgent
@@ -375,7 +446,7 @@ func (s *scalekitClient) GetAuthorizationUrl(redirectUri string, options Authori
 		qs.Set("prompt", options.Prompt)
 	}
 
-	parsedUrl, err := url.Parse(fmt.Sprintf("%s/%s", s.coreClient.envUrl, authorizeEndpoint))
+	parsedUrl, err := url.Parse(s.coreClient.cachedEndpoints().AuthorizationEndpoint)
 	if err != nil {
 		return nil, err
 	}
@@ -414,10 +485,13 @@ func (s *scalekitClient) AuthenticateWithCode(
 	if authResp.IdToken == "" {
 		return nil, ErrAuthenticationResponseMissingIdToken
 	}
-	claims, err := ValidateToken[IdTokenClaims](ctx, authResp.IdToken, s.coreClient.GetJwks)
+	claims, err := validateToken[IdTokenClaims](ctx, authResp.IdToken, s.coreClient.getJwksForKeyId, s.coreClient.validateTokenOptions(ctx, nil))
 	if err != nil {
 		return nil, err
 	}
+	if err := checkIdTokenBinding(authResp.IdToken, claims.Claims, s.coreClient.clientId, options.Nonce, authResp.AccessToken, code); err != nil {
+		return nil, err
+	}
 
 	return &AuthenticationResponse{
 		User:         *claims,
@@ -429,15 +503,15 @@ func (s *scalekitClient) AuthenticateWithCode(
 }
 
 func (s *scalekitClient) GetIdpInitiatedLoginClaims(ctx context.Context, idpInitiateLoginToken string) (*IdpInitiatedLoginClaims, error) {
-	return ValidateToken[IdpInitiatedLoginClaims](ctx, idpInitiateLoginToken, s.coreClient.GetJwks)
+	return validateToken[IdpInitiatedLoginClaims](ctx, idpInitiateLoginToken, s.coreClient.getJwksForKeyId, s.coreClient.validateTokenOptions(ctx, nil))
 }
 
 func (s *scalekitClient) GetAccessTokenClaims(ctx context.Context, accessToken string) (*AccessTokenClaims, error) {
-	return ValidateToken[AccessTokenClaims](ctx, accessToken, s.coreClient.GetJwks)
+	return validateToken[AccessTokenClaims](ctx, accessToken, s.coreClient.getJwksForKeyId, s.coreClient.validateTokenOptions(ctx, nil))
 }
 
 func (s *scalekitClient) ValidateAccessToken(ctx context.Context, accessToken string) (bool, error) {
-	_, err := ValidateToken[AccessTokenClaims](ctx, accessToken, s.coreClient.GetJwks)
+	_, err := validateToken[AccessTokenClaims](ctx, accessToken, s.coreClient.getJwksForKeyId, s.coreClient.validateTokenOptions(ctx, nil))
 	if err != nil {
 		return false, err
 	}
@@ -447,43 +521,64 @@ func (s *scalekitClient) ValidateAccessToken(ctx context.Context, accessToken st
 // ValidateTokenWithOptions validates a signed JWT (access token or ID token)
 // and enforces optional checks such as audience and scope validation.
 func (s *scalekitClient) ValidateTokenWithOptions(ctx context.Context, token string, options *ValidateTokenOptions) (bool, error) {
-	claims, err := ValidateToken[TokenClaims](ctx, token, s.coreClient.GetJwks)
-	if err != nil {
+	if _, err := s.GetAccessTokenClaimsWithOptions(ctx, token, options); err != nil {
 		return false, err
 	}
-	if options == nil {
-		return true, nil
-	}
+	return true, nil
+}
 
-	if len(options.Audience) > 0 {
-		audienceSet := map[string]struct{}{}
-		for _, audience := range claims.Audience {
-			audienceSet[audience] = struct{}{}
-		}
+// GetAccessTokenClaimsWithOptions validates a signed JWT, enforces the checks
+// in options and returns the token's claims. A nil options applies the
+// client's default issuer and leeway checks only.
+func (s *scalekitClient) GetAccessTokenClaimsWithOptions(ctx context.Context, accessToken string, options *ValidateTokenOptions) (*AccessTokenClaims, error) {
+	return validateToken[AccessTokenClaims](ctx, accessToken, s.coreClient.getJwksForKeyId, s.coreClient.validateTokenOptions(ctx, options))
+}
 
-		matched := false
-		for _, audience := range options.Audience {
-			if _, ok := audienceSet[audience]; ok {
-				matched = true
-				break
-			}
-		}
-		if !matched {
-			return false, fmt.Errorf("none of the expected audiences found in token aud claim")
+// accessTokenClaims validates accessToken with client. Clients that do not
+// implement AccessTokenClaimsValidator validate it with
+// ValidateTokenWithOptions, then read its claims with GetAccessTokenClaims.
+func accessTokenClaims(ctx context.Context, client Scalekit, accessToken string, options *ValidateTokenOptions) (*AccessTokenClaims, error) {
+	if validator, ok := client.(AccessTokenClaimsValidator); ok {
+		return validator.GetAccessTokenClaimsWithOptions(ctx, accessToken, options)
+	}
+	if _, err := client.ValidateTokenWithOptions(ctx, accessToken, options); err != nil {
+		return nil, err
+	}
+	return client.GetAccessTokenClaims(ctx, accessToken)
+}
+
+// checkAudience succeeds when expected is empty or aud contains any of its
+// values.
+func checkAudience(aud Audience, expected []string) error {
+	if len(expected) == 0 {
+		return nil
+	}
+	audienceSet := map[string]struct{}{}
+	for _, audience := range aud {
+		audienceSet[audience] = struct{}{}
+	}
+	for _, audience := range expected {
+		if _, ok := audienceSet[audience]; ok {
+			return nil
 		}
 	}
+	return ErrAudienceMismatch
+}
 
-	if len(options.Scopes) == 0 {
-		return true, nil
+// checkScopes succeeds when the space-delimited scope claim contains every
+// required scope.
+func checkScopes(claims Claims, required []string) error {
+	if len(required) == 0 {
+		return nil
 	}
 
-	scopeClaim, ok := claims.Claims["scope"]
+	scopeClaim, ok := claims["scope"]
 	if !ok {
-		return false, fmt.Errorf("token missing scope claim")
+		return &tokenCheckError{sentinel: ErrInsufficientScope, message: "token missing scope claim"}
 	}
 	scopeValue, ok := scopeClaim.(string)
 	if !ok {
-		return false, fmt.Errorf("token scope claim must be a string")
+		return &tokenCheckError{sentinel: ErrInsufficientScope, message: "token scope claim must be a string"}
 	}
 
 	scopeSet := map[string]struct{}{}
@@ -491,13 +586,12 @@ func (s *scalekitClient) ValidateTokenWithOptions(ctx context.Context, token str
 		scopeSet[scope] = struct{}{}
 	}
 
-	for _, scope := range options.Scopes {
+	for _, scope := range required {
 		if _, ok := scopeSet[scope]; !ok {
-			return false, fmt.Errorf("missing expected scope %q in token scope claim", scope)
+			return &tokenCheckError{sentinel: ErrInsufficientScope, message: fmt.Sprintf("missing expected scope %q in token scope claim", scope)}
 		}
 	}
-
-	return true, nil
+	return nil
 }
 
 func (s *scalekitClient) VerifyWebhookPayload(
@@ -508,52 +602,18 @@ func (s *scalekitClient) VerifyWebhookPayload(
 	return s.VerifyPayloadSignature(secret, headers, payload)
 }
 
+// VerifyPayloadSignature verifies a webhook or interceptor payload signed with
+// secret, within DefaultWebhookTolerance. Use NewWebhookVerifier to accept
+// several secrets or another tolerance.
 func (s *scalekitClient) VerifyPayloadSignature(
 	secret string,
 	headers map[string]string,
 	payload []byte,
 ) (bool, error) {
-	normalizedHeaders := make(map[string]string, len(headers))
-	for k, v := range headers {
-		normalizedHeaders[strings.ToLower(k)] = v
-	}
-	webhookId := normalizedHeaders["webhook-id"]
-	webhookTimestamp := normalizedHeaders["webhook-timestamp"]
-	webhookSignature := normalizedHeaders["webhook-signature"]
-	if webhookId == "" || webhookTimestamp == "" || webhookSignature == "" {
-		return false, ErrMissingRequiredHeaders
-	}
-	secretParts := strings.Split(secret, "_")
-	if len(secretParts) < 2 {
-		return false, ErrInvalidSecret
-	}
-	secretBytes, err := base64.StdEncoding.DecodeString(secretParts[1])
-	if err != nil {
+	if err := verifyPayloadSignature(secret, headers, payload); err != nil {
 		return false, err
 	}
-	timestamp, err := verifyTimestamp(webhookTimestamp)
-	if err != nil {
-		return false, err
-	}
-	data := fmt.Sprintf("%s.%d.%s", webhookId, timestamp.Unix(), payload)
-	computedSignature := computeSignature(secretBytes, data)
-	receivedSignatures := strings.Split(webhookSignature, " ")
-	for _, versionedSignature := range receivedSignatures {
-		signatureParts := strings.Split(versionedSignature, ",")
-		if len(signatureParts) < 2 {
-			continue
-		}
-		version := signatureParts[0]
-		signature := signatureParts[1]
-		if version != webhookSignatureVersion {
-			continue
-		}
-		if hmac.Equal([]byte(signature), []byte(computedSignature)) {
-			return true, nil
-		}
-	}
-
-	return false, ErrInvalidSignature
+	return true, nil
 }
 
 func (s *scalekitClient) VerifyInterceptorPayload(
@@ -564,27 +624,19 @@ func (s *scalekitClient) VerifyInterceptorPayload(
 	return s.VerifyPayloadSignature(secret, headers, payload)
 }
 
-func verifyTimestamp(timestampStr string) (*time.Time, error) {
-	now := time.Now()
-	unixTimestamp, err := strconv.ParseInt(timestampStr, 10, 64)
-	if err != nil {
-		return nil, err
-	}
-	timestamp := time.Unix(unixTimestamp, 0)
-	if now.Sub(timestamp) > webhookTolerance {
-		return nil, ErrMessageTimestampTooOld
-	}
-	if timestamp.Unix() > now.Add(webhookTolerance).Unix() {
-		return nil, ErrMessageTimestampTooNew
-	}
-	return &timestamp, nil
-}
-
 // ValidateToken verifies a JWT's signature using keys from jwksFn, unmarshals the claims
-// into T, and checks that the token has a valid, non-expired exp claim.
+// into T, and checks the token's exp, nbf and iat claims.
 // Returns ErrTokenRequired if token is empty, ErrMissingExpClaim if exp is absent,
-// or ErrTokenExpired if it is in the past.
+// or ErrTokenExpired if it is in the past. Use ValidateTokenWithOptions to check
+// the issuer, audience, scopes or token age.
 func ValidateToken[T interface{}](ctx context.Context, token string, jwksFn func(context.Context) (*jose.JSONWebKeySet, error)) (*T, error) {
+	return ValidateTokenWithOptions[T](ctx, token, jwksFn, nil)
+}
+
+// ValidateTokenWithOptions is ValidateToken with the additional checks in
+// options. Unlike the client methods, it checks the issuer only when
+// options.Issuer is set.
+func ValidateTokenWithOptions[T interface{}](ctx context.Context, token string, jwksFn func(context.Context) (*jose.JSONWebKeySet, error), options *ValidateTokenOptions) (*T, error) {
 	if token == "" {
 		// Join ErrTokenRequired with ErrTokenValidationFailed so callers can rely on either
 		// sentinel when handling empty tokens, preserving backward compatibility.
@@ -593,16 +645,47 @@ func ValidateToken[T interface{}](ctx context.Context, token string, jwksFn func
 	if jwksFn == nil {
 		return nil, ErrJwksFunctionRequired
 	}
+	return validateToken[T](ctx, token, func(ctx context.Context, _ string) (*jose.JSONWebKeySet, error) {
+		return jwksFn(ctx)
+	}, options)
+}
+
+// registeredClaims are the claims validateToken checks for every token type.
+type registeredClaims struct {
+	Iss      *string  `json:"iss"`
+	Audience Audience `json:"aud"`
+	Exp      *float64 `json:"exp"`
+	Nbf      *float64 `json:"nbf"`
+	Iat      *float64 `json:"iat"`
+	Claims   Claims   `json:"-"`
+}
+
+// validateToken implements ValidateToken. keySetFn receives the kid from the
+// token header so the caller can refetch its key set when the kid is unknown.
+// A nil options checks only the token's signature and times.
+func validateToken[T interface{}](ctx context.Context, token string, keySetFn func(context.Context, string) (*jose.JSONWebKeySet, error), options *ValidateTokenOptions) (*T, error) {
+	if token == "" {
+		return nil, errors.Join(ErrTokenRequired, ErrTokenValidationFailed)
+	}
+	if options == nil {
+		options = &ValidateTokenOptions{}
+	}
 	var claims T
-	keySet, err := jwksFn(ctx)
+	jws, err := jose.ParseSigned(token, supportedSigningAlgorithms)
+	if err != nil {
+		return nil, err
+	}
+	// Compact serialization, the only one tokens use, has a single signature.
+	header := jws.Signatures[0].Header
+	keySet, err := keySetFn(ctx, header.KeyID)
 	if err != nil {
 		return nil, err
 	}
-	jws, err := jose.ParseSigned(token, []jose.SignatureAlgorithm{jose.RS256})
+	key, err := selectSigningKey(keySet, header, options.SigningAlgorithms)
 	if err != nil {
 		return nil, err
 	}
-	jwt, err := jws.Verify(keySet)
+	jwt, err := jws.Verify(key)
 	if err != nil {
 		return nil, err
 	}
@@ -611,24 +694,74 @@ func ValidateToken[T interface{}](ctx context.Context, token string, jwksFn func
 		return nil, err
 	}
 
-	// Check token expiration. Use a typed struct so json.Unmarshal handles
-	// the numeric conversion directly — no type assertion needed.
-	var expClaims struct {
-		Exp *float64 `json:"exp"`
+	// Use a typed struct so json.Unmarshal handles the numeric conversion
+	// directly — no type assertion needed.
+	var registered registeredClaims
+	if err = unmarshalJson(jwt, &registered, &registered.Claims); err != nil {
+		return nil, err
+	}
+	if err = checkTokenTimes(registered, options, time.Now()); err != nil {
+		return nil, err
 	}
-	if err = json.Unmarshal(jwt, &expClaims); err != nil {
+	if err = checkIssuer(registered.Iss, options.Issuer); err != nil {
 		return nil, err
 	}
-	if expClaims.Exp == nil {
-		return nil, ErrMissingExpClaim
+	if err = checkAudience(registered.Audience, options.Audience); err != nil {
+		return nil, err
 	}
-	if time.Now().Unix() >= int64(*expClaims.Exp) {
-		return nil, ErrTokenExpired
+	if err = checkScopes(registered.Claims, options.Scopes); err != nil {
+		return nil, err
 	}
 
 	return &claims, nil
 }
 
+// checkTokenTimes checks the exp, nbf and iat claims against now, tolerating
+// options.Leeway of clock skew.
+func checkTokenTimes(claims registeredClaims, options *ValidateTokenOptions, now time.Time) error {
+	leeway := max(options.Leeway, 0)
+	unix := func(claim float64) time.Time {
+		return time.Unix(int64(claim), 0)
+	}
+
+	if claims.Exp == nil {
+		return ErrMissingExpClaim
+	}
+	if !now.Add(-leeway).Before(unix(*claims.Exp)) {
+		return ErrTokenExpired
+	}
+	if claims.Nbf != nil && now.Add(leeway).Before(unix(*claims.Nbf)) {
+		return ErrTokenNotYetValid
+	}
+	if claims.Iat != nil && now.Add(leeway).Before(unix(*claims.Iat)) {
+		return ErrTokenIssuedInFuture
+	}
+	if options.MaxAge > 0 {
+		if claims.Iat == nil {
+			return ErrMissingIatClaim
+		}
+		if now.Sub(unix(*claims.Iat)) > options.MaxAge+leeway {
+			return ErrTokenTooOld
+		}
+	}
+	return nil
+}
+
+// checkIssuer succeeds when expected is empty or matches iss, ignoring a
+// trailing slash on either.
+func checkIssuer(iss *string, expected string) error {
+	if expected == "" {
+		return nil
+	}
+	if iss == nil {
+		return &tokenCheckError{sentinel: ErrInvalidIssuer, message: "token missing iss claim"}
+	}
+	if strings.TrimSuffix(*iss, "/") != strings.TrimSuffix(expected, "/") {
+		return &tokenCheckError{sentinel: ErrInvalidIssuer, message: fmt.Sprintf("token issuer %q does not match expected issuer %q", *iss, expected)}
+	}
+	return nil
+}
+
 func computeSignature(secret []byte, data string) string {
 	hash := hmac.New(sha256.New, secret)
 	hash.Write([]byte(data))
@@ -668,6 +801,8 @@ func (s *scalekitClient) RefreshAccessToken(ctx context.Context, refreshToken st
 //   - ClientID: required OAuth client identifier.
 //   - ClientSecret: required OAuth client secret for ClientID.
 //   - Scopes: optional scopes sent as a space-delimited "scope" parameter.
+//
+// Each call requests a new token; ClientTokenSource caches them.
 func (s *scalekitClient) GenerateClientToken(ctx context.Context, options GenerateClientTokenOptions) (*ClientTokenResponse, error) {
 	if options.ClientID == "" {
 		return nil, ErrClientIdRequired
@@ -710,7 +845,7 @@ func (s *scalekitClient) GetClientAccessToken(ctx context.Context) (string, erro
 }
 
 func (s *scalekitClient) ValidateToken(ctx context.Context, token string) (Claims, error) {
-	claims, err := ValidateToken[Claims](ctx, token, s.coreClient.GetJwks)
+	claims, err := validateToken[Claims](ctx, token, s.coreClient.getJwksForKeyId, s.coreClient.validateTokenOptions(ctx, nil))
 	if err != nil {
 		return nil, err
 	}
@@ -732,7 +867,7 @@ func (s *scalekitClient) GetLogoutUrl(options LogoutUrlOptions) (*url.URL, error
 		qs.Set("state", options.State)
 	}
 
-	parsedUrl, err := url.Parse(fmt.Sprintf("%s/%s", s.coreClient.envUrl, logoutEndpoint))
+	parsedUrl, err := url.Parse(s.coreClient.cachedEndpoints().EndSessionEndpoint)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,166 @@
+package scalekittest
+
+import (
+	"context"
+	"slices"
+
+	"connectrpc.com/connect"
+	clientsv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/clients"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/clients/clientsconnect"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// clientService serves organization (M2M) clients.
+type clientService struct {
+	clientsconnect.UnimplementedClientServiceHandler
+	s *Server
+}
+
+// findOrganizationClient looks a client up within an organization. s.mu must be held.
+func (s *Server) findOrganizationClient(organizationID, clientID string) (*clientsv1.M2MClient, error) {
+	client, ok := s.clients.get(clientID)
+	if !ok || client.OrganizationId != organizationID {
+		return nil, notFound("client", clientID)
+	}
+	return client, nil
+}
+
+// newClientSecret adds a secret to client and returns it with its plain value,
+// which the token endpoint then accepts for the client. s.mu must be held.
+func (s *Server) newClientSecret(client *clientsv1.M2MClient) (*clientsv1.ClientSecret, string) {
+	plain := "sks_" + randomHex(24)
+	now := timestamppb.Now()
+	secret := &clientsv1.ClientSecret{
+		Id:           s.nextID("sks"),
+		CreateTime:   now,
+		UpdateTime:   now,
+		SecretSuffix: plain[len(plain)-4:],
+		Status:       clientsv1.ClientSecretStatus_ACTIVE,
+	}
+	client.Secrets = append(client.Secrets, secret)
+	client.UpdateTime = now
+	s.clientSecrets[secret.Id] = plain
+	return secret, plain
+}
+
+func (c *clientService) CreateOrganizationClient(_ context.Context, req *connect.Request[clientsv1.CreateOrganizationClientRequest]) (*connect.Response[clientsv1.CreateOrganizationClientResponse], error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	org, err := c.s.findOrganization(req.Msg.GetOrganizationId(), "")
+	if err != nil {
+		return nil, err
+	}
+	in := req.Msg.GetClient()
+	now := timestamppb.Now()
+	client := &clientsv1.M2MClient{
+		ClientId:       c.s.nextID("m2morg"),
+		Name:           in.GetName(),
+		Description:    in.GetDescription(),
+		OrganizationId: org.Id,
+		CreateTime:     now,
+		UpdateTime:     now,
+		Scopes:         in.GetScopes(),
+		Audience:       in.GetAudience(),
+		CustomClaims:   in.GetCustomClaims(),
+		Expiry:         in.GetExpiry(),
+	}
+	_, plain := c.s.newClientSecret(client)
+	c.s.clients.put(client.ClientId, client)
+	return connect.NewResponse(&clientsv1.CreateOrganizationClientResponse{Client: clone(client), PlainSecret: plain}), nil
+}
+
+func (c *clientService) GetOrganizationClient(_ context.Context, req *connect.Request[clientsv1.GetOrganizationClientRequest]) (*connect.Response[clientsv1.GetOrganizationClientResponse], error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	client, err := c.s.findOrganizationClient(req.Msg.GetOrganizationId(), req.Msg.GetClientId())
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&clientsv1.GetOrganizationClientResponse{Client: clone(client)}), nil
+}
+
+func (c *clientService) UpdateOrganizationClient(_ context.Context, req *connect.Request[clientsv1.UpdateOrganizationClientRequest]) (*connect.Response[clientsv1.UpdateOrganizationClientResponse], error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	client, err := c.s.findOrganizationClient(req.Msg.GetOrganizationId(), req.Msg.GetClientId())
+	if err != nil {
+		return nil, err
+	}
+	in := req.Msg.GetClient()
+	if in.GetName() != "" {
+		client.Name = in.GetName()
+	}
+	if in.GetDescription() != "" {
+		client.Description = in.GetDescription()
+	}
+	if in.Scopes != nil {
+		client.Scopes = in.GetScopes()
+	}
+	if in.Audience != nil {
+		client.Audience = in.GetAudience()
+	}
+	if in.CustomClaims != nil {
+		client.CustomClaims = in.GetCustomClaims()
+	}
+	if in.GetExpiry() != 0 {
+		client.Expiry = in.GetExpiry()
+	}
+	client.UpdateTime = timestamppb.Now()
+	return connect.NewResponse(&clientsv1.UpdateOrganizationClientResponse{Client: clone(client)}), nil
+}
+
+func (c *clientService) DeleteOrganizationClient(_ context.Context, req *connect.Request[clientsv1.DeleteOrganizationClientRequest]) (*connect.Response[emptypb.Empty], error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	client, err := c.s.findOrganizationClient(req.Msg.GetOrganizationId(), req.Msg.GetClientId())
+	if err != nil {
+		return nil, err
+	}
+	c.s.clients.delete(client.ClientId)
+	return connect.NewResponse(&emptypb.Empty{}), nil
+}
+
+func (c *clientService) ListOrganizationClients(_ context.Context, req *connect.Request[clientsv1.ListOrganizationClientsRequest]) (*connect.Response[clientsv1.ListOrganizationClientsResponse], error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	clients := c.s.clients.list(func(client *clientsv1.M2MClient) bool {
+		return client.OrganizationId == req.Msg.GetOrganizationId()
+	})
+	page, next, prev, err := paginate(clients, req.Msg.GetPageSize(), req.Msg.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	resp := &clientsv1.ListOrganizationClientsResponse{NextPageToken: next, PrevPageToken: prev, TotalSize: uint32(len(clients))}
+	for _, client := range page {
+		resp.Clients = append(resp.Clients, clone(client))
+	}
+	return connect.NewResponse(resp), nil
+}
+
+func (c *clientService) CreateOrganizationClientSecret(_ context.Context, req *connect.Request[clientsv1.CreateOrganizationClientSecretRequest]) (*connect.Response[clientsv1.CreateOrganizationClientSecretResponse], error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	client, err := c.s.findOrganizationClient(req.Msg.GetOrganizationId(), req.Msg.GetClientId())
+	if err != nil {
+		return nil, err
+	}
+	secret, plain := c.s.newClientSecret(client)
+	return connect.NewResponse(&clientsv1.CreateOrganizationClientSecretResponse{PlainSecret: plain, Secret: clone(secret)}), nil
+}
+
+func (c *clientService) DeleteOrganizationClientSecret(_ context.Context, req *connect.Request[clientsv1.DeleteOrganizationClientSecretRequest]) (*connect.Response[emptypb.Empty], error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	client, err := c.s.findOrganizationClient(req.Msg.GetOrganizationId(), req.Msg.GetClientId())
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(client.Secrets, func(secret *clientsv1.ClientSecret) bool { return secret.Id == req.Msg.GetSecretId() })
+	if i < 0 {
+		return nil, notFound("client secret", req.Msg.GetSecretId())
+	}
+	client.Secrets = slices.Delete(client.Secrets, i, i+1)
+	client.UpdateTime = timestamppb.Now()
+	return connect.NewResponse(&emptypb.Empty{}), nil
+}
@@ -0,0 +1,136 @@
+package scalekittest
+
+import (
+	"context"
+
+	"connectrpc.com/connect"
+	connectionsv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/connections"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/connections/connectionsconnect"
+	domainsv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/domains"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type connectionService struct {
+	connectionsconnect.UnimplementedConnectionServiceHandler
+	s *Server
+}
+
+// findConnection looks a connection up within an organization. s.mu must be held.
+func (s *Server) findConnection(organizationID, id string) (*connectionsv1.Connection, error) {
+	connection, ok := s.connections.get(id)
+	if !ok || connection.GetOrganizationId() != organizationID {
+		return nil, notFound("connection", id)
+	}
+	return connection, nil
+}
+
+func (c *connectionService) CreateConnection(_ context.Context, req *connect.Request[connectionsv1.CreateConnectionRequest]) (*connect.Response[connectionsv1.CreateConnectionResponse], error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	org, err := c.s.findOrganization(req.Msg.GetOrganizationId(), "")
+	if err != nil {
+		return nil, err
+	}
+	in := req.Msg.GetConnection()
+	now := timestamppb.Now()
+	connection := &connectionsv1.Connection{
+		Id:             c.s.nextID("conn"),
+		Provider:       in.GetProvider(),
+		Type:           in.GetType(),
+		Status:         connectionsv1.ConnectionStatus_DRAFT,
+		OrganizationId: &org.Id,
+		ProviderKey:    in.GetProviderKey(),
+		KeyId:          in.KeyId,
+		CreateTime:     now,
+		UpdateTime:     now,
+	}
+	c.s.connections.put(connection.Id, connection)
+	return connect.NewResponse(&connectionsv1.CreateConnectionResponse{Connection: clone(connection)}), nil
+}
+
+func (c *connectionService) GetConnection(_ context.Context, req *connect.Request[connectionsv1.GetConnectionRequest]) (*connect.Response[connectionsv1.GetConnectionResponse], error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	connection, err := c.s.findConnection(req.Msg.GetOrganizationId(), req.Msg.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&connectionsv1.GetConnectionResponse{Connection: clone(connection)}), nil
+}
+
+// ListConnections lists the connections of an organization, or of the
+// organizations owning a domain. Disabled connections are only included when
+// Include is "all".
+func (c *connectionService) ListConnections(_ context.Context, req *connect.Request[connectionsv1.ListConnectionsRequest]) (*connect.Response[connectionsv1.ListConnectionsResponse], error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	orgIDs := map[string]bool{}
+	if req.Msg.OrganizationId != nil {
+		orgIDs[req.Msg.GetOrganizationId()] = true
+	}
+	if req.Msg.Domain != nil {
+		for _, domain := range c.s.domains.list(func(d *domainsv1.Domain) bool { return d.Domain == req.Msg.GetDomain() }) {
+			orgIDs[domain.OrganizationId] = true
+		}
+	}
+	connections := c.s.connections.list(func(conn *connectionsv1.Connection) bool {
+		return (req.Msg.OrganizationId == nil && req.Msg.Domain == nil || orgIDs[conn.GetOrganizationId()]) &&
+			(conn.Enabled || req.Msg.GetInclude() == "all")
+	})
+
+	resp := &connectionsv1.ListConnectionsResponse{}
+	for _, conn := range connections {
+		item := &connectionsv1.ListConnection{
+			Id:             conn.Id,
+			Provider:       conn.Provider,
+			Type:           conn.Type,
+			Status:         conn.Status,
+			Enabled:        conn.Enabled,
+			OrganizationId: conn.GetOrganizationId(),
+			UiButtonTitle:  conn.UiButtonTitle,
+			ProviderKey:    conn.ProviderKey,
+			KeyId:          conn.GetKeyId(),
+			CreatedAt:      conn.CreateTime,
+		}
+		if org, ok := c.s.organizations.get(conn.GetOrganizationId()); ok {
+			item.OrganizationName = org.DisplayName
+		}
+		for _, domain := range c.s.domains.list(func(d *domainsv1.Domain) bool { return d.OrganizationId == conn.GetOrganizationId() }) {
+			item.Domains = append(item.Domains, domain.Domain)
+		}
+		resp.Connections = append(resp.Connections, item)
+	}
+	return connect.NewResponse(resp), nil
+}
+
+func (c *connectionService) EnableConnection(_ context.Context, req *connect.Request[connectionsv1.ToggleConnectionRequest]) (*connect.Response[connectionsv1.ToggleConnectionResponse], error) {
+	return c.toggle(req.Msg, true)
+}
+
+func (c *connectionService) DisableConnection(_ context.Context, req *connect.Request[connectionsv1.ToggleConnectionRequest]) (*connect.Response[connectionsv1.ToggleConnectionResponse], error) {
+	return c.toggle(req.Msg, false)
+}
+
+func (c *connectionService) toggle(req *connectionsv1.ToggleConnectionRequest, enabled bool) (*connect.Response[connectionsv1.ToggleConnectionResponse], error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	connection, err := c.s.findConnection(req.GetOrganizationId(), req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	connection.Enabled = enabled
+	connection.UpdateTime = timestamppb.Now()
+	return connect.NewResponse(&connectionsv1.ToggleConnectionResponse{Enabled: enabled}), nil
+}
+
+func (c *connectionService) DeleteConnection(_ context.Context, req *connect.Request[connectionsv1.DeleteConnectionRequest]) (*connect.Response[emptypb.Empty], error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	connection, err := c.s.findConnection(req.Msg.GetOrganizationId(), req.Msg.GetId())
+	if err != nil {
+		return nil, err
+	}
+	c.s.connections.delete(connection.Id)
+	return connect.NewResponse(&emptypb.Empty{}), nil
+}
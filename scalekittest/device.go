@@ -0,0 +1,120 @@
+package scalekittest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	sessionsv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/sessions"
+)
+
+const (
+	deviceCodeLifetime = 10 * time.Minute
+	devicePollInterval = 5 * time.Second
+)
+
+// deviceAuthorization is a pending device authorization. grant is set once the
+// user approves it.
+type deviceAuthorization struct {
+	userCode  string
+	expiresAt time.Time
+	denied    bool
+	grant     *authorizationCode
+}
+
+// handleDeviceAuthorization starts a device authorization for the server's
+// client. The user code is approved or denied with ApproveDevice and
+// DenyDevice.
+func (s *Server) handleDeviceAuthorization(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if r.PostForm.Get("client_id") != s.ClientID {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "invalid client credentials")
+		return
+	}
+	deviceCode := "skdc_" + randomHex(16)
+	userCode := strings.ToUpper(randomHex(2) + "-" + randomHex(2))
+	s.mu.Lock()
+	s.deviceAuthorizations[deviceCode] = &deviceAuthorization{userCode: userCode, expiresAt: time.Now().Add(deviceCodeLifetime)}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"device_code":               deviceCode,
+		"user_code":                 userCode,
+		"verification_uri":          s.URL + "/device",
+		"verification_uri_complete": s.URL + "/device?user_code=" + userCode,
+		"expires_in":                int(deviceCodeLifetime.Seconds()),
+		"interval":                  int(devicePollInterval.Seconds()),
+	})
+}
+
+// ApproveDevice approves the pending device authorization with userCode, as if
+// user signed in on the verification page. The next poll receives tokens like
+// those of IssueAuthorizationCode.
+func (s *Server) ApproveDevice(userCode string, user scalekit.IdTokenClaims, opts ...TokenOption) error {
+	session := s.AddSession(&sessionsv1.SessionDetails{UserId: user.Id})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	authorization, err := s.findDeviceAuthorization(userCode)
+	if err != nil {
+		return err
+	}
+	authorization.grant = &authorizationCode{user: user, opts: opts, sessionID: session.SessionId}
+	return nil
+}
+
+// DenyDevice denies the pending device authorization with userCode. The next
+// poll fails with access_denied.
+func (s *Server) DenyDevice(userCode string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	authorization, err := s.findDeviceAuthorization(userCode)
+	if err != nil {
+		return err
+	}
+	authorization.denied = true
+	return nil
+}
+
+// findDeviceAuthorization returns the pending authorization with userCode.
+// s.mu must be held.
+func (s *Server) findDeviceAuthorization(userCode string) (*deviceAuthorization, error) {
+	for _, authorization := range s.deviceAuthorizations {
+		if authorization.userCode == userCode && !authorization.denied && authorization.grant == nil {
+			return authorization, nil
+		}
+	}
+	return nil, fmt.Errorf("scalekittest: no pending device authorization with user code %q", userCode)
+}
+
+// exchangeDeviceCode answers a device_code grant: authorization_pending until
+// the user code is approved or denied, then tokens or access_denied. Expired
+// codes fail with expired_token.
+func (s *Server) exchangeDeviceCode(w http.ResponseWriter, deviceCode string) {
+	s.mu.Lock()
+	authorization, ok := s.deviceAuthorizations[deviceCode]
+	var pending deviceAuthorization
+	if ok {
+		pending = *authorization
+		if pending.grant != nil || pending.denied {
+			delete(s.deviceAuthorizations, deviceCode)
+		}
+	}
+	s.mu.Unlock()
+
+	switch {
+	case !ok:
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "invalid or already used device code")
+	case pending.grant != nil:
+		s.issueTokens(w, *pending.grant)
+	case pending.denied:
+		writeOAuthError(w, http.StatusBadRequest, "access_denied", "the user denied the request")
+	case time.Now().After(pending.expiresAt):
+		writeOAuthError(w, http.StatusBadRequest, "expired_token", "the device code has expired")
+	default:
+		writeOAuthError(w, http.StatusBadRequest, "authorization_pending", "the user has not completed the request")
+	}
+}
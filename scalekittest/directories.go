@@ -0,0 +1,215 @@
+package scalekittest
+
+import (
+	"context"
+	"slices"
+
+	"connectrpc.com/connect"
+	directoriesv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/directories"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/directories/directoriesconnect"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// directoryRecord holds a directory and the users and groups synced into it.
+type directoryRecord struct {
+	directory *directoriesv1.Directory
+	users     []*directoriesv1.DirectoryUser
+	groups    []*directoriesv1.DirectoryGroup
+}
+
+type directoryService struct {
+	directoriesconnect.UnimplementedDirectoryServiceHandler
+	s *Server
+}
+
+// AddDirectoryUser adds user to a directory as if it had been synced from the
+// identity provider. A missing Id or UpdatedAt is filled in. It panics if the
+// directory does not exist.
+func (s *Server) AddDirectoryUser(directoryID string, user *directoriesv1.DirectoryUser) *directoriesv1.DirectoryUser {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := s.mustDirectory(directoryID)
+	user = clone(user)
+	if user.Id == "" {
+		user.Id = s.nextID("diruser")
+	}
+	if user.UpdatedAt == nil {
+		user.UpdatedAt = timestamppb.Now()
+	}
+	record.users = append(record.users, user)
+	record.directory.TotalUsers = int32(len(record.users))
+	return clone(user)
+}
+
+// AddDirectoryGroup adds group to a directory as if it had been synced from the
+// identity provider. A missing Id or UpdatedAt is filled in. It panics if the
+// directory does not exist.
+func (s *Server) AddDirectoryGroup(directoryID string, group *directoriesv1.DirectoryGroup) *directoriesv1.DirectoryGroup {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := s.mustDirectory(directoryID)
+	group = clone(group)
+	if group.Id == "" {
+		group.Id = s.nextID("dirgroup")
+	}
+	if group.UpdatedAt == nil {
+		group.UpdatedAt = timestamppb.Now()
+	}
+	record.groups = append(record.groups, group)
+	record.directory.TotalGroups = int32(len(record.groups))
+	return clone(group)
+}
+
+func (s *Server) mustDirectory(id string) *directoryRecord {
+	record, ok := s.directories.get(id)
+	if !ok {
+		panic("scalekittest: directory " + id + " does not exist")
+	}
+	return record
+}
+
+// findDirectory looks a directory up within an organization. s.mu must be held.
+func (s *Server) findDirectory(organizationID, id string) (*directoryRecord, error) {
+	record, ok := s.directories.get(id)
+	if !ok || record.directory.OrganizationId != organizationID {
+		return nil, notFound("directory", id)
+	}
+	return record, nil
+}
+
+func (d *directoryService) CreateDirectory(_ context.Context, req *connect.Request[directoriesv1.CreateDirectoryRequest]) (*connect.Response[directoriesv1.CreateDirectoryResponse], error) {
+	d.s.mu.Lock()
+	defer d.s.mu.Unlock()
+	org, err := d.s.findOrganization(req.Msg.GetOrganizationId(), "")
+	if err != nil {
+		return nil, err
+	}
+	in := req.Msg.GetDirectory()
+	id := d.s.nextID("dir")
+	directory := &directoriesv1.Directory{
+		Id:                id,
+		Name:              in.GetDirectoryProvider().String(),
+		DirectoryType:     in.GetDirectoryType(),
+		OrganizationId:    org.Id,
+		DirectoryProvider: in.GetDirectoryProvider(),
+		DirectoryEndpoint: d.s.URL + "/api/v1/directories/" + id + "/scim/v2",
+		Status:            "IN_PROGRESS",
+	}
+	d.s.directories.put(id, &directoryRecord{directory: directory})
+	return connect.NewResponse(&directoriesv1.CreateDirectoryResponse{Directory: clone(directory)}), nil
+}
+
+func (d *directoryService) ListDirectories(_ context.Context, req *connect.Request[directoriesv1.ListDirectoriesRequest]) (*connect.Response[directoriesv1.ListDirectoriesResponse], error) {
+	d.s.mu.Lock()
+	defer d.s.mu.Unlock()
+	resp := &directoriesv1.ListDirectoriesResponse{}
+	for _, record := range d.s.directories.list(nil) {
+		if record.directory.OrganizationId == req.Msg.GetOrganizationId() {
+			resp.Directories = append(resp.Directories, clone(record.directory))
+		}
+	}
+	return connect.NewResponse(resp), nil
+}
+
+func (d *directoryService) GetDirectory(_ context.Context, req *connect.Request[directoriesv1.GetDirectoryRequest]) (*connect.Response[directoriesv1.GetDirectoryResponse], error) {
+	d.s.mu.Lock()
+	defer d.s.mu.Unlock()
+	record, err := d.s.findDirectory(req.Msg.GetOrganizationId(), req.Msg.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&directoriesv1.GetDirectoryResponse{Directory: clone(record.directory)}), nil
+}
+
+func (d *directoryService) ListDirectoryUsers(_ context.Context, req *connect.Request[directoriesv1.ListDirectoryUsersRequest]) (*connect.Response[directoriesv1.ListDirectoryUsersResponse], error) {
+	d.s.mu.Lock()
+	defer d.s.mu.Unlock()
+	record, err := d.s.findDirectory(req.Msg.GetOrganizationId(), req.Msg.GetDirectoryId())
+	if err != nil {
+		return nil, err
+	}
+	var users []*directoriesv1.DirectoryUser
+	for _, user := range record.users {
+		if req.Msg.DirectoryGroupId != nil && !slices.ContainsFunc(user.Groups, func(g *directoriesv1.DirectoryGroup) bool {
+			return g.Id == req.Msg.GetDirectoryGroupId()
+		}) {
+			continue
+		}
+		if req.Msg.UpdatedAfter != nil && !user.UpdatedAt.AsTime().After(req.Msg.UpdatedAfter.AsTime()) {
+			continue
+		}
+		users = append(users, user)
+	}
+	page, next, prev, err := paginate(users, req.Msg.GetPageSize(), req.Msg.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	resp := &directoriesv1.ListDirectoryUsersResponse{NextPageToken: next, PrevPageToken: prev, TotalSize: uint32(len(users))}
+	for _, user := range page {
+		user = clone(user)
+		if !req.Msg.GetIncludeDetail() {
+			user.UserDetail = nil
+		}
+		resp.Users = append(resp.Users, user)
+	}
+	return connect.NewResponse(resp), nil
+}
+
+func (d *directoryService) ListDirectoryGroups(_ context.Context, req *connect.Request[directoriesv1.ListDirectoryGroupsRequest]) (*connect.Response[directoriesv1.ListDirectoryGroupsResponse], error) {
+	d.s.mu.Lock()
+	defer d.s.mu.Unlock()
+	record, err := d.s.findDirectory(req.Msg.GetOrganizationId(), req.Msg.GetDirectoryId())
+	if err != nil {
+		return nil, err
+	}
+	var groups []*directoriesv1.DirectoryGroup
+	for _, group := range record.groups {
+		if req.Msg.UpdatedAfter == nil || group.UpdatedAt.AsTime().After(req.Msg.UpdatedAfter.AsTime()) {
+			groups = append(groups, group)
+		}
+	}
+	page, next, prev, err := paginate(groups, req.Msg.GetPageSize(), req.Msg.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	resp := &directoriesv1.ListDirectoryGroupsResponse{NextPageToken: next, PrevPageToken: prev, TotalSize: uint32(len(groups))}
+	for _, group := range page {
+		group = clone(group)
+		if !req.Msg.GetIncludeDetail() {
+			group.GroupDetail = nil
+		}
+		resp.Groups = append(resp.Groups, group)
+	}
+	return connect.NewResponse(resp), nil
+}
+
+func (d *directoryService) EnableDirectory(_ context.Context, req *connect.Request[directoriesv1.ToggleDirectoryRequest]) (*connect.Response[directoriesv1.ToggleDirectoryResponse], error) {
+	return d.toggle(req.Msg, true)
+}
+
+func (d *directoryService) DisableDirectory(_ context.Context, req *connect.Request[directoriesv1.ToggleDirectoryRequest]) (*connect.Response[directoriesv1.ToggleDirectoryResponse], error) {
+	return d.toggle(req.Msg, false)
+}
+
+func (d *directoryService) toggle(req *directoriesv1.ToggleDirectoryRequest, enabled bool) (*connect.Response[directoriesv1.ToggleDirectoryResponse], error) {
+	d.s.mu.Lock()
+	defer d.s.mu.Unlock()
+	record, err := d.s.findDirectory(req.GetOrganizationId(), req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	record.directory.Enabled = enabled
+	return connect.NewResponse(&directoriesv1.ToggleDirectoryResponse{Enabled: enabled}), nil
+}
+
+func (d *directoryService) DeleteDirectory(_ context.Context, req *connect.Request[directoriesv1.DeleteDirectoryRequest]) (*connect.Response[emptypb.Empty], error) {
+	d.s.mu.Lock()
+	defer d.s.mu.Unlock()
+	record, err := d.s.findDirectory(req.Msg.GetOrganizationId(), req.Msg.GetId())
+	if err != nil {
+		return nil, err
+	}
+	d.s.directories.delete(record.directory.Id)
+	return connect.NewResponse(&emptypb.Empty{}), nil
+}
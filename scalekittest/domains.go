@@ -0,0 +1,112 @@
+package scalekittest
+
+import (
+	"context"
+
+	"connectrpc.com/connect"
+	domainsv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/domains"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/domains/domainsconnect"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type domainService struct {
+	domainsconnect.UnimplementedDomainServiceHandler
+	s *Server
+}
+
+// findDomain looks a domain up within an organization. s.mu must be held.
+func (s *Server) findDomain(id, organizationID, externalID string) (*domainsv1.Domain, error) {
+	org, err := s.findOrganization(organizationID, externalID)
+	if err != nil {
+		return nil, err
+	}
+	domain, ok := s.domains.get(id)
+	if !ok || domain.OrganizationId != org.Id {
+		return nil, notFound("domain", id)
+	}
+	return domain, nil
+}
+
+func (d *domainService) CreateDomain(_ context.Context, req *connect.Request[domainsv1.CreateDomainRequest]) (*connect.Response[domainsv1.CreateDomainResponse], error) {
+	in := req.Msg.GetDomain()
+	if in.GetDomain() == "" {
+		return nil, invalidArgument("domain is required")
+	}
+	d.s.mu.Lock()
+	defer d.s.mu.Unlock()
+	org, err := d.s.findOrganization(req.Msg.GetOrganizationId(), req.Msg.GetExternalId())
+	if err != nil {
+		return nil, err
+	}
+	for _, existing := range d.s.domains.list(nil) {
+		if existing.Domain == in.GetDomain() {
+			return nil, alreadyExists("domain", in.GetDomain())
+		}
+	}
+	now := timestamppb.Now()
+	domainType := in.GetDomainType()
+	if domainType == domainsv1.DomainType_DOMAIN_TYPE_UNSPECIFIED {
+		domainType = domainsv1.DomainType_ORGANIZATION_DOMAIN
+	}
+	domain := &domainsv1.Domain{
+		Id:                 d.s.nextID("dom"),
+		Domain:             in.GetDomain(),
+		OrganizationId:     org.Id,
+		TxtRecordKey:       "_scalekit-verification." + in.GetDomain(),
+		TxtRecordSecret:    randomHex(16),
+		VerificationStatus: domainsv1.VerificationStatus_PENDING,
+		CreateTime:         now,
+		UpdateTime:         now,
+		DomainType:         domainType,
+	}
+	d.s.domains.put(domain.Id, domain)
+	return connect.NewResponse(&domainsv1.CreateDomainResponse{Domain: clone(domain)}), nil
+}
+
+func (d *domainService) GetDomain(_ context.Context, req *connect.Request[domainsv1.GetDomainRequest]) (*connect.Response[domainsv1.GetDomainResponse], error) {
+	d.s.mu.Lock()
+	defer d.s.mu.Unlock()
+	domain, err := d.s.findDomain(req.Msg.GetId(), req.Msg.GetOrganizationId(), req.Msg.GetExternalId())
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&domainsv1.GetDomainResponse{Domain: clone(domain)}), nil
+}
+
+func (d *domainService) ListDomains(_ context.Context, req *connect.Request[domainsv1.ListDomainRequest]) (*connect.Response[domainsv1.ListDomainResponse], error) {
+	d.s.mu.Lock()
+	defer d.s.mu.Unlock()
+	org, err := d.s.findOrganization(req.Msg.GetOrganizationId(), req.Msg.GetExternalId())
+	if err != nil {
+		return nil, err
+	}
+	domains := d.s.domains.list(func(domain *domainsv1.Domain) bool {
+		return domain.OrganizationId == org.Id &&
+			(req.Msg.GetDomainType() == domainsv1.DomainType_DOMAIN_TYPE_UNSPECIFIED || domain.DomainType == req.Msg.GetDomainType())
+	})
+	pageSize := max(req.Msg.GetPageSize().GetValue(), 0)
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	pageNumber := max(req.Msg.GetPageNumber().GetValue(), 1)
+	start := min(int((pageNumber-1)*pageSize), len(domains))
+	end := min(start+int(pageSize), len(domains))
+
+	resp := &domainsv1.ListDomainResponse{PageSize: pageSize, PageNumber: pageNumber}
+	for _, domain := range domains[start:end] {
+		resp.Domains = append(resp.Domains, clone(domain))
+	}
+	return connect.NewResponse(resp), nil
+}
+
+func (d *domainService) DeleteDomain(_ context.Context, req *connect.Request[domainsv1.DeleteDomainRequest]) (*connect.Response[emptypb.Empty], error) {
+	d.s.mu.Lock()
+	defer d.s.mu.Unlock()
+	domain, err := d.s.findDomain(req.Msg.GetId(), req.Msg.GetOrganizationId(), req.Msg.GetExternalId())
+	if err != nil {
+		return nil, err
+	}
+	d.s.domains.delete(domain.Id)
+	return connect.NewResponse(&emptypb.Empty{}), nil
+}
@@ -0,0 +1,165 @@
+package scalekittest
+
+import (
+	"context"
+	"time"
+
+	"connectrpc.com/connect"
+	organizationsv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/organizations"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/organizations/organizationsconnect"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type organizationService struct {
+	organizationsconnect.UnimplementedOrganizationServiceHandler
+	s *Server
+}
+
+// findOrganization looks an organization up by id or external id. s.mu must be held.
+func (s *Server) findOrganization(id, externalID string) (*organizationsv1.Organization, error) {
+	if id != "" {
+		if org, ok := s.organizations.get(id); ok {
+			return org, nil
+		}
+		return nil, notFound("organization", id)
+	}
+	for _, org := range s.organizations.list(nil) {
+		if externalID != "" && org.GetExternalId() == externalID {
+			return org, nil
+		}
+	}
+	return nil, notFound("organization", externalID)
+}
+
+func (o *organizationService) CreateOrganization(_ context.Context, req *connect.Request[organizationsv1.CreateOrganizationRequest]) (*connect.Response[organizationsv1.CreateOrganizationResponse], error) {
+	in := req.Msg.GetOrganization()
+	if in.GetDisplayName() == "" {
+		return nil, invalidArgument("organization display name is required")
+	}
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+	if in.ExternalId != nil {
+		if _, err := o.s.findOrganization("", in.GetExternalId()); err == nil {
+			return nil, alreadyExists("organization with external id", in.GetExternalId())
+		}
+	}
+	now := timestamppb.Now()
+	org := &organizationsv1.Organization{
+		Id:          o.s.nextID("org"),
+		CreateTime:  now,
+		UpdateTime:  now,
+		DisplayName: in.GetDisplayName(),
+		RegionCode:  in.GetRegionCode(),
+		ExternalId:  in.ExternalId,
+		Metadata:    in.GetMetadata(),
+		Slug:        in.Slug,
+		Settings:    &organizationsv1.OrganizationSettings{},
+	}
+	o.s.organizations.put(org.Id, org)
+	return connect.NewResponse(&organizationsv1.CreateOrganizationResponse{Organization: clone(org)}), nil
+}
+
+func (o *organizationService) GetOrganization(_ context.Context, req *connect.Request[organizationsv1.GetOrganizationRequest]) (*connect.Response[organizationsv1.GetOrganizationResponse], error) {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+	org, err := o.s.findOrganization(req.Msg.GetId(), req.Msg.GetExternalId())
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&organizationsv1.GetOrganizationResponse{Organization: clone(org)}), nil
+}
+
+func (o *organizationService) ListOrganization(_ context.Context, req *connect.Request[organizationsv1.ListOrganizationsRequest]) (*connect.Response[organizationsv1.ListOrganizationsResponse], error) {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+	orgs := o.s.organizations.list(func(org *organizationsv1.Organization) bool {
+		return req.Msg.ExternalId == nil || org.GetExternalId() == req.Msg.GetExternalId()
+	})
+	page, next, prev, err := paginate(orgs, req.Msg.GetPageSize(), req.Msg.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	resp := &organizationsv1.ListOrganizationsResponse{
+		NextPageToken: next,
+		PrevPageToken: prev,
+		TotalSize:     uint32(len(orgs)),
+	}
+	for _, org := range page {
+		resp.Organizations = append(resp.Organizations, clone(org))
+	}
+	return connect.NewResponse(resp), nil
+}
+
+func (o *organizationService) UpdateOrganization(_ context.Context, req *connect.Request[organizationsv1.UpdateOrganizationRequest]) (*connect.Response[organizationsv1.UpdateOrganizationResponse], error) {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+	org, err := o.s.findOrganization(req.Msg.GetId(), req.Msg.GetExternalId())
+	if err != nil {
+		return nil, err
+	}
+	in := req.Msg.GetOrganization()
+	if in.DisplayName != nil {
+		org.DisplayName = in.GetDisplayName()
+	}
+	if in.ExternalId != nil {
+		org.ExternalId = in.ExternalId
+	}
+	if in.Slug != nil {
+		org.Slug = in.Slug
+	}
+	if in.Metadata != nil {
+		org.Metadata = in.GetMetadata()
+	}
+	org.UpdateTime = timestamppb.Now()
+	return connect.NewResponse(&organizationsv1.UpdateOrganizationResponse{Organization: clone(org)}), nil
+}
+
+func (o *organizationService) DeleteOrganization(_ context.Context, req *connect.Request[organizationsv1.DeleteOrganizationRequest]) (*connect.Response[emptypb.Empty], error) {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+	org, err := o.s.findOrganization(req.Msg.GetId(), req.Msg.GetExternalId())
+	if err != nil {
+		return nil, err
+	}
+	o.s.organizations.delete(org.Id)
+	return connect.NewResponse(&emptypb.Empty{}), nil
+}
+
+func (o *organizationService) GeneratePortalLink(_ context.Context, req *connect.Request[organizationsv1.GeneratePortalLinkRequest]) (*connect.Response[organizationsv1.GeneratePortalLinkResponse], error) {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+	org, err := o.s.findOrganization(req.Msg.GetId(), "")
+	if err != nil {
+		return nil, err
+	}
+	id := o.s.nextID("lnk")
+	return connect.NewResponse(&organizationsv1.GeneratePortalLinkResponse{Link: &organizationsv1.Link{
+		Id:         id,
+		Location:   o.s.URL + "/magicLink/" + id + "?organization_id=" + org.Id,
+		ExpireTime: timestamppb.New(time.Now().Add(7 * 24 * time.Hour)),
+	}}), nil
+}
+
+func (o *organizationService) UpdateOrganizationSettings(_ context.Context, req *connect.Request[organizationsv1.UpdateOrganizationSettingsRequest]) (*connect.Response[organizationsv1.GetOrganizationResponse], error) {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+	org, err := o.s.findOrganization(req.Msg.GetId(), "")
+	if err != nil {
+		return nil, err
+	}
+	for _, feature := range req.Msg.GetSettings().GetFeatures() {
+		updated := false
+		for _, existing := range org.Settings.Features {
+			if existing.Name == feature.GetName() {
+				existing.Enabled = feature.GetEnabled()
+				updated = true
+			}
+		}
+		if !updated {
+			org.Settings.Features = append(org.Settings.Features, clone(feature))
+		}
+	}
+	org.UpdateTime = timestamppb.Now()
+	return connect.NewResponse(&organizationsv1.GetOrganizationResponse{Organization: clone(org)}), nil
+}
@@ -0,0 +1,363 @@
+package scalekittest
+
+import (
+	"context"
+	"slices"
+
+	"connectrpc.com/connect"
+	commonsv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/commons"
+	rolesv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/roles"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/roles/rolesconnect"
+	usersv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/users"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// rolesService serves environment roles and permissions. Roles and permissions
+// are stored by name, which is how the API addresses them.
+type rolesService struct {
+	rolesconnect.UnimplementedRolesServiceHandler
+	s *Server
+}
+
+// findRole looks an environment role up by name. s.mu must be held.
+func (s *Server) findRole(name string) (*rolesv1.Role, bool) {
+	return s.roles.get(name)
+}
+
+// rolePermissions resolves permission names for role. s.mu must be held.
+func (s *Server) rolePermissions(roleName string, names []string) ([]*rolesv1.RolePermission, error) {
+	var out []*rolesv1.RolePermission
+	for _, name := range names {
+		p, ok := s.permissions.get(name)
+		if !ok {
+			return nil, invalidArgument("permission %q does not exist", name)
+		}
+		out = append(out, &rolesv1.RolePermission{
+			Id:          p.Id,
+			Name:        p.Name,
+			Description: p.Description,
+			CreateTime:  p.CreateTime,
+			UpdateTime:  p.UpdateTime,
+			RoleName:    roleName,
+		})
+	}
+	return out, nil
+}
+
+// effectivePermissions returns the permissions of a role, including those it
+// inherits through Extends. s.mu must be held.
+func (s *Server) effectivePermissions(roleName string) []*rolesv1.Permission {
+	var out []*rolesv1.Permission
+	seen := map[string]bool{}
+	for name := roleName; name != "" && !seen[name]; {
+		seen[name] = true
+		role, ok := s.findRole(name)
+		if !ok {
+			break
+		}
+		for _, rp := range role.Permissions {
+			if p, ok := s.permissions.get(rp.Name); ok && !slices.ContainsFunc(out, func(x *rolesv1.Permission) bool { return x.Name == p.Name }) {
+				out = append(out, clone(p))
+			}
+		}
+		name = role.GetExtends()
+	}
+	return out
+}
+
+func (r *rolesService) CreateRole(_ context.Context, req *connect.Request[rolesv1.CreateRoleRequest]) (*connect.Response[rolesv1.CreateRoleResponse], error) {
+	in := req.Msg.GetRole()
+	if in.GetName() == "" {
+		return nil, invalidArgument("role name is required")
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.findRole(in.GetName()); ok {
+		return nil, alreadyExists("role", in.GetName())
+	}
+	if in.Extends != nil {
+		if _, ok := r.s.findRole(in.GetExtends()); !ok {
+			return nil, invalidArgument("base role %q does not exist", in.GetExtends())
+		}
+	}
+	permissions, err := r.s.rolePermissions(in.GetName(), in.GetPermissions())
+	if err != nil {
+		return nil, err
+	}
+	role := &rolesv1.Role{
+		Id:          r.s.nextID("role"),
+		Name:        in.GetName(),
+		DisplayName: in.GetDisplayName(),
+		Description: in.GetDescription(),
+		Extends:     in.Extends,
+		Permissions: permissions,
+	}
+	r.s.roles.put(role.Name, role)
+	return connect.NewResponse(&rolesv1.CreateRoleResponse{Role: clone(role)}), nil
+}
+
+func (r *rolesService) GetRole(_ context.Context, req *connect.Request[rolesv1.GetRoleRequest]) (*connect.Response[rolesv1.GetRoleResponse], error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	role, ok := r.s.findRole(req.Msg.GetRoleName())
+	if !ok {
+		return nil, notFound("role", req.Msg.GetRoleName())
+	}
+	return connect.NewResponse(&rolesv1.GetRoleResponse{Role: r.s.roleWithDependents(role)}), nil
+}
+
+func (r *rolesService) ListRoles(context.Context, *connect.Request[rolesv1.ListRolesRequest]) (*connect.Response[rolesv1.ListRolesResponse], error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	resp := &rolesv1.ListRolesResponse{}
+	for _, role := range r.s.roles.list(nil) {
+		resp.Roles = append(resp.Roles, r.s.roleWithDependents(role))
+	}
+	return connect.NewResponse(resp), nil
+}
+
+func (r *rolesService) UpdateRole(_ context.Context, req *connect.Request[rolesv1.UpdateRoleRequest]) (*connect.Response[rolesv1.UpdateRoleResponse], error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	role, ok := r.s.findRole(req.Msg.GetRoleName())
+	if !ok {
+		return nil, notFound("role", req.Msg.GetRoleName())
+	}
+	in := req.Msg.GetRole()
+	if in.Permissions != nil {
+		permissions, err := r.s.rolePermissions(role.Name, in.GetPermissions())
+		if err != nil {
+			return nil, err
+		}
+		role.Permissions = permissions
+	}
+	if in.DisplayName != nil {
+		role.DisplayName = in.GetDisplayName()
+	}
+	if in.Description != nil {
+		role.Description = in.GetDescription()
+	}
+	if in.Extends != nil {
+		role.Extends = in.Extends
+	}
+	return connect.NewResponse(&rolesv1.UpdateRoleResponse{Role: r.s.roleWithDependents(role)}), nil
+}
+
+func (r *rolesService) DeleteRole(_ context.Context, req *connect.Request[rolesv1.DeleteRoleRequest]) (*connect.Response[emptypb.Empty], error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	name := req.Msg.GetRoleName()
+	if _, ok := r.s.findRole(name); !ok {
+		return nil, notFound("role", name)
+	}
+	reassign := req.Msg.GetReassignRoleName()
+	if reassign != "" {
+		if _, ok := r.s.findRole(reassign); !ok {
+			return nil, invalidArgument("reassign role %q does not exist", reassign)
+		}
+	}
+	for _, user := range r.s.users.list(nil) {
+		for _, m := range user.Memberships {
+			i := slices.IndexFunc(m.Roles, func(role *commonsv1.Role) bool { return role.GetName() == name })
+			if i < 0 {
+				continue
+			}
+			if reassign == "" {
+				m.Roles = slices.Delete(m.Roles, i, i+1)
+			} else {
+				m.Roles[i] = r.s.membershipRoles([]*commonsv1.Role{{Name: reassign}})[0]
+			}
+		}
+	}
+	r.s.roles.delete(name)
+	return connect.NewResponse(&emptypb.Empty{}), nil
+}
+
+func (r *rolesService) DeleteRoleBase(_ context.Context, req *connect.Request[rolesv1.DeleteRoleBaseRequest]) (*connect.Response[emptypb.Empty], error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	role, ok := r.s.findRole(req.Msg.GetRoleName())
+	if !ok {
+		return nil, notFound("role", req.Msg.GetRoleName())
+	}
+	role.Extends = nil
+	return connect.NewResponse(&emptypb.Empty{}), nil
+}
+
+func (r *rolesService) GetRoleUsersCount(_ context.Context, req *connect.Request[rolesv1.GetRoleUsersCountRequest]) (*connect.Response[rolesv1.GetRoleUsersCountResponse], error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	name := req.Msg.GetRoleName()
+	if _, ok := r.s.findRole(name); !ok {
+		return nil, notFound("role", name)
+	}
+	users := r.s.users.list(func(user *usersv1.User) bool {
+		return slices.ContainsFunc(user.Memberships, func(m *commonsv1.OrganizationMembership) bool {
+			return slices.ContainsFunc(m.Roles, func(role *commonsv1.Role) bool { return role.GetName() == name })
+		})
+	})
+	return connect.NewResponse(&rolesv1.GetRoleUsersCountResponse{Count: int64(len(users))}), nil
+}
+
+func (r *rolesService) ListDependentRoles(_ context.Context, req *connect.Request[rolesv1.ListDependentRolesRequest]) (*connect.Response[rolesv1.ListDependentRolesResponse], error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.findRole(req.Msg.GetRoleName()); !ok {
+		return nil, notFound("role", req.Msg.GetRoleName())
+	}
+	resp := &rolesv1.ListDependentRolesResponse{}
+	for _, role := range r.s.dependentRoles(req.Msg.GetRoleName()) {
+		resp.Roles = append(resp.Roles, r.s.roleWithDependents(role))
+	}
+	return connect.NewResponse(resp), nil
+}
+
+// dependentRoles returns the roles that extend roleName. s.mu must be held.
+func (s *Server) dependentRoles(roleName string) []*rolesv1.Role {
+	return s.roles.list(func(role *rolesv1.Role) bool { return role.GetExtends() == roleName })
+}
+
+// roleWithDependents returns a copy of role with DependentRolesCount filled in.
+// s.mu must be held.
+func (s *Server) roleWithDependents(role *rolesv1.Role) *rolesv1.Role {
+	out := clone(role)
+	out.DependentRolesCount = int32(len(s.dependentRoles(role.Name)))
+	return out
+}
+
+func (r *rolesService) CreatePermission(_ context.Context, req *connect.Request[rolesv1.CreatePermissionRequest]) (*connect.Response[rolesv1.CreatePermissionResponse], error) {
+	in := req.Msg.GetPermission()
+	if in.GetName() == "" {
+		return nil, invalidArgument("permission name is required")
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.permissions.get(in.GetName()); ok {
+		return nil, alreadyExists("permission", in.GetName())
+	}
+	now := timestamppb.Now()
+	permission := &rolesv1.Permission{
+		Id:          r.s.nextID("perm"),
+		Name:        in.GetName(),
+		Description: in.GetDescription(),
+		CreateTime:  now,
+		UpdateTime:  now,
+	}
+	r.s.permissions.put(permission.Name, permission)
+	return connect.NewResponse(&rolesv1.CreatePermissionResponse{Permission: clone(permission)}), nil
+}
+
+func (r *rolesService) GetPermission(_ context.Context, req *connect.Request[rolesv1.GetPermissionRequest]) (*connect.Response[rolesv1.GetPermissionResponse], error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	permission, ok := r.s.permissions.get(req.Msg.GetPermissionName())
+	if !ok {
+		return nil, notFound("permission", req.Msg.GetPermissionName())
+	}
+	return connect.NewResponse(&rolesv1.GetPermissionResponse{Permission: clone(permission)}), nil
+}
+
+func (r *rolesService) ListPermissions(_ context.Context, req *connect.Request[rolesv1.ListPermissionsRequest]) (*connect.Response[rolesv1.ListPermissionsResponse], error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	permissions := r.s.permissions.list(nil)
+	page, next, prev, err := paginate(permissions, req.Msg.GetPageSize(), req.Msg.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	resp := &rolesv1.ListPermissionsResponse{NextPageToken: next, PrevPageToken: prev, TotalSize: uint32(len(permissions))}
+	for _, p := range page {
+		resp.Permissions = append(resp.Permissions, clone(p))
+	}
+	return connect.NewResponse(resp), nil
+}
+
+func (r *rolesService) UpdatePermission(_ context.Context, req *connect.Request[rolesv1.UpdatePermissionRequest]) (*connect.Response[rolesv1.UpdatePermissionResponse], error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	permission, ok := r.s.permissions.get(req.Msg.GetPermissionName())
+	if !ok {
+		return nil, notFound("permission", req.Msg.GetPermissionName())
+	}
+	permission.Description = req.Msg.GetPermission().GetDescription()
+	permission.UpdateTime = timestamppb.Now()
+	return connect.NewResponse(&rolesv1.UpdatePermissionResponse{Permission: clone(permission)}), nil
+}
+
+func (r *rolesService) DeletePermission(_ context.Context, req *connect.Request[rolesv1.DeletePermissionRequest]) (*connect.Response[emptypb.Empty], error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	name := req.Msg.GetPermissionName()
+	if !r.s.permissions.delete(name) {
+		return nil, notFound("permission", name)
+	}
+	for _, role := range r.s.roles.list(nil) {
+		role.Permissions = slices.DeleteFunc(role.Permissions, func(p *rolesv1.RolePermission) bool { return p.Name == name })
+	}
+	return connect.NewResponse(&emptypb.Empty{}), nil
+}
+
+func (r *rolesService) ListRolePermissions(_ context.Context, req *connect.Request[rolesv1.ListRolePermissionsRequest]) (*connect.Response[rolesv1.ListRolePermissionsResponse], error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	role, ok := r.s.findRole(req.Msg.GetRoleName())
+	if !ok {
+		return nil, notFound("role", req.Msg.GetRoleName())
+	}
+	resp := &rolesv1.ListRolePermissionsResponse{}
+	for _, rp := range role.Permissions {
+		if p, ok := r.s.permissions.get(rp.Name); ok {
+			resp.Permissions = append(resp.Permissions, clone(p))
+		}
+	}
+	return connect.NewResponse(resp), nil
+}
+
+func (r *rolesService) AddPermissionsToRole(_ context.Context, req *connect.Request[rolesv1.AddPermissionsToRoleRequest]) (*connect.Response[rolesv1.AddPermissionsToRoleResponse], error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	role, ok := r.s.findRole(req.Msg.GetRoleName())
+	if !ok {
+		return nil, notFound("role", req.Msg.GetRoleName())
+	}
+	added, err := r.s.rolePermissions(role.Name, req.Msg.GetPermissionNames())
+	if err != nil {
+		return nil, err
+	}
+	resp := &rolesv1.AddPermissionsToRoleResponse{}
+	for _, rp := range added {
+		if !slices.ContainsFunc(role.Permissions, func(p *rolesv1.RolePermission) bool { return p.Name == rp.Name }) {
+			role.Permissions = append(role.Permissions, rp)
+		}
+		p, _ := r.s.permissions.get(rp.Name)
+		resp.Permissions = append(resp.Permissions, clone(p))
+	}
+	return connect.NewResponse(resp), nil
+}
+
+func (r *rolesService) RemovePermissionFromRole(_ context.Context, req *connect.Request[rolesv1.RemovePermissionFromRoleRequest]) (*connect.Response[emptypb.Empty], error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	role, ok := r.s.findRole(req.Msg.GetRoleName())
+	if !ok {
+		return nil, notFound("role", req.Msg.GetRoleName())
+	}
+	i := slices.IndexFunc(role.Permissions, func(p *rolesv1.RolePermission) bool { return p.Name == req.Msg.GetPermissionName() })
+	if i < 0 {
+		return nil, notFound("permission", req.Msg.GetPermissionName())
+	}
+	role.Permissions = slices.Delete(role.Permissions, i, i+1)
+	return connect.NewResponse(&emptypb.Empty{}), nil
+}
+
+func (r *rolesService) ListEffectiveRolePermissions(_ context.Context, req *connect.Request[rolesv1.ListEffectiveRolePermissionsRequest]) (*connect.Response[rolesv1.ListEffectiveRolePermissionsResponse], error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.findRole(req.Msg.GetRoleName()); !ok {
+		return nil, notFound("role", req.Msg.GetRoleName())
+	}
+	return connect.NewResponse(&rolesv1.ListEffectiveRolePermissionsResponse{
+		Permissions: r.s.effectivePermissions(req.Msg.GetRoleName()),
+	}), nil
+}
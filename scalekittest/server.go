@@ -0,0 +1,537 @@
+// Package scalekittest provides an in-memory fake of the Scalekit API for
+// tests that depend on the scalekit.Scalekit interface.
+//
+// NewServer starts an httptest server implementing the Connect services used
+// by the SDK (organizations, users, roles and permissions, domains,
+// connections, directories, API tokens, sessions and organization clients)
+// along with the oauth/token and keys endpoints. Client returns a
+// scalekit.Scalekit already configured to talk to it:
+//
+//	server := scalekittest.NewServer(t)
+//	client := server.Client()
+//	org, err := client.Organization().CreateOrganization(ctx, "Acme", scalekit.CreateOrganizationOptions{})
+//
+// State lives in memory and is discarded when the test ends. RPCs the fake
+// does not model return connect.CodeUnimplemented.
+//
+// Tokens the SDK validates locally, such as ID tokens, access tokens and
+// IdP-initiated login tokens, are minted with a Signer. Server.Signer mints
+// tokens the server's clients accept, and IssueAuthorizationCode prepares a
+// code for AuthenticateWithCode. NewSigner serves tests that only need a JWKS.
+//
+// Intercept, FailRPC and SetAccessTokenLifetime let a test script failures
+// and edge cases, such as outages, unusual token lifetimes or custom
+// endpoints, on top of the modeled behavior.
+//
+// NewWebhookRequest builds signed webhook deliveries for testing webhook
+// handlers.
+package scalekittest
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	clientsv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/clients"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/clients/clientsconnect"
+	connectionsv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/connections"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/connections/connectionsconnect"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/directories/directoriesconnect"
+	domainsv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/domains"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/domains/domainsconnect"
+	organizationsv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/organizations"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/organizations/organizationsconnect"
+	rolesv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/roles"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/roles/rolesconnect"
+	sessionsv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/sessions"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/sessions/sessionsconnect"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/tokens/tokensconnect"
+	usersv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/users"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/users/usersconnect"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// DefaultClientID and DefaultClientSecret are the credentials the server
+	// accepts for the client_credentials grant, along with those of the
+	// organization clients it holds.
+	DefaultClientID     = "skc_test_client"
+	DefaultClientSecret = "test_client_secret"
+
+	defaultAccessTokenLifetime = time.Hour
+	defaultPageSize            = 100
+)
+
+// Server is an in-memory Scalekit environment served over HTTPS.
+type Server struct {
+	// URL is the environment URL to pass to scalekit.NewScalekitClient.
+	URL string
+	// ClientID and ClientSecret are the accepted client credentials.
+	ClientID     string
+	ClientSecret string
+
+	httpServer *httptest.Server
+	mux        *http.ServeMux
+	signer     *Signer
+
+	mu                   sync.Mutex
+	seq                  int
+	interceptors         []func(http.ResponseWriter, *http.Request) bool
+	rpcErrors            map[string]error
+	accessTokenLifetime  time.Duration
+	accessTokens         map[string]time.Time
+	authorizationCodes   map[string]authorizationCode
+	refreshTokens        map[string]authorizationCode
+	deviceAuthorizations map[string]*deviceAuthorization
+	clientSecrets        map[string]string
+
+	organizations table[*organizationsv1.Organization]
+	users         table[*usersv1.User]
+	roles         table[*rolesv1.Role]
+	permissions   table[*rolesv1.Permission]
+	domains       table[*domainsv1.Domain]
+	connections   table[*connectionsv1.Connection]
+	directories   table[*directoryRecord]
+	apiTokens     table[*apiTokenRecord]
+	sessions      table[*sessionsv1.SessionDetails]
+	clients       table[*clientsv1.M2MClient]
+}
+
+// NewServer starts a Server and stops it when tb finishes.
+func NewServer(tb testing.TB) *Server {
+	tb.Helper()
+	signer, err := NewSigner("")
+	if err != nil {
+		tb.Fatalf("scalekittest: %v", err)
+	}
+	s := &Server{
+		ClientID:             DefaultClientID,
+		ClientSecret:         DefaultClientSecret,
+		signer:               signer,
+		rpcErrors:            map[string]error{},
+		accessTokenLifetime:  defaultAccessTokenLifetime,
+		accessTokens:         map[string]time.Time{},
+		authorizationCodes:   map[string]authorizationCode{},
+		refreshTokens:        map[string]authorizationCode{},
+		deviceAuthorizations: map[string]*deviceAuthorization{},
+		clientSecrets:        map[string]string{},
+	}
+
+	opts := connect.WithInterceptors(connect.UnaryInterceptorFunc(s.authorize), connect.UnaryInterceptorFunc(s.failRPC))
+	mux := http.NewServeMux()
+	mux.Handle(organizationsconnect.NewOrganizationServiceHandler(&organizationService{s: s}, opts))
+	mux.Handle(usersconnect.NewUserServiceHandler(&userService{s: s}, opts))
+	mux.Handle(rolesconnect.NewRolesServiceHandler(&rolesService{s: s}, opts))
+	mux.Handle(domainsconnect.NewDomainServiceHandler(&domainService{s: s}, opts))
+	mux.Handle(connectionsconnect.NewConnectionServiceHandler(&connectionService{s: s}, opts))
+	mux.Handle(directoriesconnect.NewDirectoryServiceHandler(&directoryService{s: s}, opts))
+	mux.Handle(tokensconnect.NewApiTokenServiceHandler(&apiTokenService{s: s}, opts))
+	mux.Handle(sessionsconnect.NewSessionServiceHandler(&sessionService{s: s}, opts))
+	mux.Handle(clientsconnect.NewClientServiceHandler(&clientService{s: s}, opts))
+	mux.HandleFunc("POST /oauth/token", s.handleToken)
+	mux.HandleFunc("POST /oauth/device/code", s.handleDeviceAuthorization)
+	mux.Handle("GET /keys", signer)
+	mux.HandleFunc("GET /.well-known/openid-configuration", s.handleDiscovery)
+
+	s.mux = mux
+	s.httpServer = httptest.NewUnstartedServer(http.HandlerFunc(s.serveHTTP))
+	s.httpServer.EnableHTTP2 = true
+	s.httpServer.StartTLS()
+	s.URL = s.httpServer.URL
+	signer.Issuer = s.URL
+	tb.Cleanup(s.httpServer.Close)
+	return s
+}
+
+// HTTPClient returns an HTTP client that trusts the server's certificate.
+func (s *Server) HTTPClient() *http.Client {
+	return s.httpServer.Client()
+}
+
+// Client returns a Scalekit client for the server. opts are passed to
+// scalekit.NewScalekitClient after the server's credentials and HTTP client.
+func (s *Server) Client(opts ...any) scalekit.Scalekit {
+	args := append([]any{s.ClientSecret, scalekit.WithHTTPClient(s.HTTPClient())}, opts...)
+	return scalekit.NewScalekitClient(s.URL, s.ClientID, args...)
+}
+
+// Close shuts the server down. It is called automatically when the test ends.
+func (s *Server) Close() {
+	s.httpServer.Close()
+}
+
+// Signer returns the signer behind the server's keys endpoint. Tokens it mints
+// are accepted by ValidateToken, GetIdpInitiatedLoginClaims and the other
+// token methods of the server's clients.
+func (s *Server) Signer() *Signer {
+	return s.signer
+}
+
+// Intercept runs fn before the server handles each request, in the order the
+// functions were added. fn may inspect or modify the request, set response
+// headers, or answer the request itself and return true to skip the server's
+// handling:
+//
+//	server.Intercept(func(w http.ResponseWriter, r *http.Request) bool {
+//		if r.URL.Path != "/oauth/token" {
+//			return false
+//		}
+//		w.WriteHeader(http.StatusServiceUnavailable)
+//		return true
+//	})
+func (s *Server) Intercept(fn func(w http.ResponseWriter, r *http.Request) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interceptors = append(s.interceptors, fn)
+}
+
+// FailRPC makes authorized calls to procedure, such as
+// organizationsconnect.OrganizationServiceGetOrganizationProcedure, fail with
+// err. Wrap err in a *connect.Error to choose its code and details. A nil err
+// restores the modeled behavior.
+func (s *Server) FailRPC(procedure string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		delete(s.rpcErrors, procedure)
+		return
+	}
+	s.rpcErrors[procedure] = err
+}
+
+// SetAccessTokenLifetime sets the lifetime of access tokens issued afterwards.
+// It defaults to an hour. Zero issues tokens that never expire and omits
+// expires_in from client_credentials responses.
+func (s *Server) SetAccessTokenLifetime(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessTokenLifetime = d
+}
+
+// serveHTTP runs the interceptors, then the server's handlers.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	interceptors := slices.Clone(s.interceptors)
+	s.mu.Unlock()
+	for _, intercept := range interceptors {
+		if intercept(w, r) {
+			return
+		}
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// authorizationCode is what a code or refresh token is exchanged for.
+type authorizationCode struct {
+	user      scalekit.IdTokenClaims
+	opts      []TokenOption
+	sessionID string
+}
+
+// IssueAuthorizationCode returns a single-use code that the token endpoint
+// exchanges for an ID token describing user, an access token and a refresh
+// token, as after a completed login. The login's session is added to the
+// server, and its ID is the sid claim of the tokens. opts customize the ID
+// token, whose audience is the server's client ID and whose at_hash matches
+// the access token by default; pass WithClaims with a "nonce" to answer a
+// login that sent one. Refreshing reissues the same user's tokens.
+func (s *Server) IssueAuthorizationCode(user scalekit.IdTokenClaims, opts ...TokenOption) string {
+	session := s.AddSession(&sessionsv1.SessionDetails{UserId: user.Id})
+	code := "skcode_" + randomHex(16)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authorizationCodes[code] = authorizationCode{user: user, opts: opts, sessionID: session.SessionId}
+	return code
+}
+
+// handleDiscovery serves the server's OpenID Provider metadata.
+func (s *Server) handleDiscovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, scalekit.ProviderMetadata{
+		Issuer:                            s.URL,
+		AuthorizationEndpoint:             s.URL + "/oauth/authorize",
+		TokenEndpoint:                     s.URL + "/oauth/token",
+		JwksUri:                           s.URL + "/keys",
+		EndSessionEndpoint:                s.URL + "/oidc/logout",
+		ResponseTypesSupported:            []string{"code"},
+		DeviceAuthorizationEndpoint:       s.URL + "/oauth/device/code",
+		GrantTypesSupported:               []string{"authorization_code", "refresh_token", "client_credentials", string(scalekit.GrantTypeDeviceCode)},
+		IdTokenSigningAlgValuesSupported:  []string{"RS256"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_post"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+	})
+}
+
+// handleToken implements the client_credentials, authorization_code,
+// refresh_token and device_code grants of the token endpoint.
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	// Public clients, such as native apps, redeem codes with a PKCE verifier
+	// instead of the client secret.
+	publicClient := r.PostForm.Get("client_secret") == "" && r.PostForm.Get("code_verifier") != "" &&
+		r.PostForm.Get("grant_type") == string(scalekit.GrantTypeAuthorizationCode)
+	if !s.validClient(r.PostForm.Get("client_id"), r.PostForm.Get("client_secret"), publicClient) {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "invalid client credentials")
+		return
+	}
+
+	switch grantType := r.PostForm.Get("grant_type"); grantType {
+	case string(scalekit.GrantTypeClientCredentials):
+		token := "sktest_" + randomHex(16)
+		s.mu.Lock()
+		lifetime := s.accessTokenLifetime
+		s.accessTokens[token] = expiresAt(lifetime)
+		s.mu.Unlock()
+		resp := map[string]any{"access_token": token, "token_type": "Bearer"}
+		if lifetime != 0 {
+			resp["expires_in"] = int(lifetime.Seconds())
+		}
+		if scope := r.PostForm.Get("scope"); scope != "" {
+			resp["scope"] = scope
+		}
+		writeJSON(w, http.StatusOK, resp)
+	case string(scalekit.GrantTypeAuthorizationCode):
+		s.exchangeGrant(w, s.authorizationCodes, r.PostForm.Get("code"))
+	case string(scalekit.GrantTypeRefreshToken):
+		s.exchangeGrant(w, s.refreshTokens, r.PostForm.Get("refresh_token"))
+	case string(scalekit.GrantTypeDeviceCode):
+		s.exchangeDeviceCode(w, r.PostForm.Get("device_code"))
+	default:
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", fmt.Sprintf("grant type %q is not supported", grantType))
+	}
+}
+
+// validClient reports whether clientID and secret are the server's credentials
+// or those of an organization client. A public client has no secret.
+func (s *Server) validClient(clientID, secret string, publicClient bool) bool {
+	if clientID == s.ClientID {
+		return secret == s.ClientSecret || publicClient
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, ok := s.clients.get(clientID)
+	if !ok {
+		return false
+	}
+	return slices.ContainsFunc(client.Secrets, func(clientSecret *clientsv1.ClientSecret) bool {
+		return clientSecret.Status == clientsv1.ClientSecretStatus_ACTIVE && s.clientSecrets[clientSecret.Id] == secret
+	})
+}
+
+// expiresAt returns when a token issued now with lifetime expires. Tokens
+// with a zero lifetime never expire and have a zero expiry.
+func expiresAt(lifetime time.Duration) time.Time {
+	if lifetime == 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(lifetime)
+}
+
+// exchangeGrant consumes a code or refresh token from grants and responds with
+// freshly minted tokens, including a new refresh token.
+func (s *Server) exchangeGrant(w http.ResponseWriter, grants map[string]authorizationCode, key string) {
+	s.mu.Lock()
+	grant, ok := grants[key]
+	delete(grants, key)
+	s.mu.Unlock()
+	if !ok {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "invalid or already used grant")
+		return
+	}
+	s.issueTokens(w, grant)
+}
+
+// issueTokens responds with an ID token, an access token and a refresh token
+// for grant.
+func (s *Server) issueTokens(w http.ResponseWriter, grant authorizationCode) {
+	refreshToken := "skrt_" + randomHex(16)
+	s.mu.Lock()
+	s.refreshTokens[refreshToken] = grant
+	lifetime := s.accessTokenLifetime
+	s.mu.Unlock()
+	if lifetime == 0 {
+		// Signed access tokens always carry an expiry.
+		lifetime = defaultAccessTokenLifetime
+	}
+
+	accessToken, err := s.signer.MintAccessToken(
+		scalekit.AccessTokenClaims{Sub: grant.user.Id, Claims: scalekit.Claims{"sid": grant.sessionID}},
+		WithAudience(s.ClientID), WithExpiry(lifetime))
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	idTokenOpts := []TokenOption{WithAudience(s.ClientID), WithClaims(map[string]any{"at_hash": atHash(accessToken), "sid": grant.sessionID})}
+	idToken, err := s.signer.MintIDToken(grant.user, append(idTokenOpts, grant.opts...)...)
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id_token":      idToken,
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(lifetime.Seconds()),
+	})
+}
+
+// atHash returns the at_hash claim for an access token in an RS256 signed ID
+// token: the base64url encoded left half of its SHA-256 hash.
+func atHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
+
+// authorize rejects RPCs that do not carry an unexpired access token issued by
+// the token endpoint. Tokens with a zero expiry never expire.
+func (s *Server) authorize(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		token, ok := strings.CutPrefix(req.Header().Get("Authorization"), "Bearer ")
+		s.mu.Lock()
+		expiresAt, issued := s.accessTokens[token]
+		s.mu.Unlock()
+		if !ok || !issued || (!expiresAt.IsZero() && time.Now().After(expiresAt)) {
+			return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("missing or invalid access token"))
+		}
+		return next(ctx, req)
+	}
+}
+
+// failRPC returns the error set with FailRPC for the called procedure.
+func (s *Server) failRPC(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		s.mu.Lock()
+		err := s.rpcErrors[req.Spec().Procedure]
+		s.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+// nextID returns a new identifier with the given prefix. s.mu must be held.
+func (s *Server) nextID(prefix string) string {
+	s.seq++
+	return fmt.Sprintf("%s_%d", prefix, s.seq)
+}
+
+// table stores records by id and remembers insertion order for listing.
+type table[T any] struct {
+	byID map[string]T
+	ids  []string
+}
+
+func (t *table[T]) get(id string) (T, bool) {
+	v, ok := t.byID[id]
+	return v, ok
+}
+
+func (t *table[T]) put(id string, v T) {
+	if t.byID == nil {
+		t.byID = map[string]T{}
+	}
+	if _, ok := t.byID[id]; !ok {
+		t.ids = append(t.ids, id)
+	}
+	t.byID[id] = v
+}
+
+func (t *table[T]) delete(id string) bool {
+	if _, ok := t.byID[id]; !ok {
+		return false
+	}
+	delete(t.byID, id)
+	for i, v := range t.ids {
+		if v == id {
+			t.ids = append(t.ids[:i], t.ids[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// list returns the records accepted by keep, in insertion order. A nil keep
+// returns every record.
+func (t *table[T]) list(keep func(T) bool) []T {
+	var out []T
+	for _, id := range t.ids {
+		if v := t.byID[id]; keep == nil || keep(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// paginate returns one page of items. Page tokens are offsets into items.
+func paginate[T any](items []T, pageSize uint32, pageToken string) (page []T, next, prev string, err error) {
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	offset := 0
+	if pageToken != "" {
+		offset, err = strconv.Atoi(pageToken)
+		if err != nil || offset < 0 || offset > len(items) {
+			return nil, "", "", connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid page token %q", pageToken))
+		}
+	}
+	end := min(offset+int(pageSize), len(items))
+	if end < len(items) {
+		next = strconv.Itoa(end)
+	}
+	if offset > 0 {
+		prev = strconv.Itoa(max(offset-int(pageSize), 0))
+	}
+	return items[offset:end], next, prev, nil
+}
+
+func notFound(kind, id string) error {
+	return connect.NewError(connect.CodeNotFound, fmt.Errorf("%s %q not found", kind, id))
+}
+
+func alreadyExists(kind, id string) error {
+	return connect.NewError(connect.CodeAlreadyExists, fmt.Errorf("%s %q already exists", kind, id))
+}
+
+func invalidArgument(format string, args ...any) error {
+	return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf(format, args...))
+}
+
+// clone returns a deep copy of m so callers never share state with the store.
+func clone[T proto.Message](m T) T {
+	return proto.Clone(m).(T)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, map[string]string{"error": code, "error_description": description})
+}
@@ -0,0 +1,131 @@
+package scalekittest
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"connectrpc.com/connect"
+	sessionsv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/sessions"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/sessions/sessionsconnect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	sessionStatusActive  = "active"
+	sessionStatusRevoked = "revoked"
+)
+
+type sessionService struct {
+	sessionsconnect.UnimplementedSessionServiceHandler
+	s *Server
+}
+
+// AddSession stores a session for a user, as if the user had signed in. A
+// missing SessionId is generated, Status defaults to "active" and missing
+// timestamps default to now. The stored session is returned.
+func (s *Server) AddSession(session *sessionsv1.SessionDetails) *sessionsv1.SessionDetails {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session = clone(session)
+	if session.SessionId == "" {
+		session.SessionId = s.nextID("ses")
+	}
+	if session.Status == "" {
+		session.Status = sessionStatusActive
+	}
+	now := timestamppb.Now()
+	if session.CreatedAt == nil {
+		session.CreatedAt = now
+	}
+	if session.UpdatedAt == nil {
+		session.UpdatedAt = now
+	}
+	if session.LastActiveAt == nil {
+		session.LastActiveAt = now
+	}
+	s.sessions.put(session.SessionId, session)
+	return clone(session)
+}
+
+func (ss *sessionService) GetSession(_ context.Context, req *connect.Request[sessionsv1.SessionDetailsRequest]) (*connect.Response[sessionsv1.SessionDetails], error) {
+	ss.s.mu.Lock()
+	defer ss.s.mu.Unlock()
+	session, ok := ss.s.sessions.get(req.Msg.GetSessionId())
+	if !ok {
+		return nil, notFound("session", req.Msg.GetSessionId())
+	}
+	return connect.NewResponse(clone(session)), nil
+}
+
+func (ss *sessionService) GetUserSessions(_ context.Context, req *connect.Request[sessionsv1.UserSessionDetailsRequest]) (*connect.Response[sessionsv1.UserSessionDetails], error) {
+	ss.s.mu.Lock()
+	defer ss.s.mu.Unlock()
+	filter := req.Msg.GetFilter()
+	sessions := ss.s.sessions.list(func(session *sessionsv1.SessionDetails) bool {
+		if session.UserId != req.Msg.GetUserId() {
+			return false
+		}
+		if len(filter.GetStatus()) > 0 && !slices.Contains(filter.GetStatus(), session.Status) {
+			return false
+		}
+		created := session.CreatedAt.AsTime()
+		if filter.GetStartTime() != nil && created.Before(filter.GetStartTime().AsTime()) {
+			return false
+		}
+		return filter.GetEndTime() == nil || !created.After(filter.GetEndTime().AsTime())
+	})
+	page, next, prev, err := paginate(sessions, req.Msg.GetPageSize(), req.Msg.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	resp := &sessionsv1.UserSessionDetails{NextPageToken: next, PrevPageToken: prev, TotalSize: uint32(len(sessions))}
+	for _, session := range page {
+		resp.Sessions = append(resp.Sessions, clone(session))
+	}
+	return connect.NewResponse(resp), nil
+}
+
+func (ss *sessionService) RevokeSession(_ context.Context, req *connect.Request[sessionsv1.RevokeSessionRequest]) (*connect.Response[sessionsv1.RevokeSessionResponse], error) {
+	ss.s.mu.Lock()
+	defer ss.s.mu.Unlock()
+	session, ok := ss.s.sessions.get(req.Msg.GetSessionId())
+	if !ok {
+		return nil, notFound("session", req.Msg.GetSessionId())
+	}
+	return connect.NewResponse(&sessionsv1.RevokeSessionResponse{RevokedSession: revokeSession(session, time.Now())}), nil
+}
+
+func (ss *sessionService) RevokeAllUserSessions(_ context.Context, req *connect.Request[sessionsv1.RevokeAllUserSessionsRequest]) (*connect.Response[sessionsv1.RevokeAllUserSessionsResponse], error) {
+	ss.s.mu.Lock()
+	defer ss.s.mu.Unlock()
+	resp := &sessionsv1.RevokeAllUserSessionsResponse{}
+	now := time.Now()
+	for _, session := range ss.s.sessions.list(nil) {
+		if session.UserId == req.Msg.GetUserId() && session.Status == sessionStatusActive {
+			resp.RevokedSessions = append(resp.RevokedSessions, revokeSession(session, now))
+		}
+	}
+	resp.TotalRevoked = uint32(len(resp.RevokedSessions))
+	return connect.NewResponse(resp), nil
+}
+
+// revokeSession marks session as revoked and describes it. The server lock
+// must be held.
+func revokeSession(session *sessionsv1.SessionDetails, now time.Time) *sessionsv1.RevokedSessionDetails {
+	session.Status = sessionStatusRevoked
+	session.LogoutAt = timestamppb.New(now)
+	session.UpdatedAt = session.LogoutAt
+	return &sessionsv1.RevokedSessionDetails{
+		SessionId:         session.SessionId,
+		UserId:            session.UserId,
+		CreatedAt:         session.CreatedAt,
+		UpdatedAt:         session.UpdatedAt,
+		IdleExpiresAt:     session.IdleExpiresAt,
+		AbsoluteExpiresAt: session.AbsoluteExpiresAt,
+		ExpiredAt:         session.ExpiredAt,
+		LogoutAt:          session.LogoutAt,
+		Status:            session.Status,
+		LastActiveAt:      session.LastActiveAt,
+	}
+}
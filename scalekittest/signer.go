@@ -0,0 +1,327 @@
+package scalekittest
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+)
+
+const defaultTokenLifetime = time.Hour
+
+// Signer mints tokens signed like Scalekit's, with a locally generated key.
+// Its public keys are served as a JWKS by ServeHTTP, so a client whose
+// environment URL serves the Signer at /keys validates the minted tokens.
+// Server uses a Signer for its keys endpoint; see Server.Signer.
+type Signer struct {
+	// Issuer is the iss claim of minted tokens unless overridden with WithIssuer.
+	Issuer string
+
+	mu sync.Mutex
+	// keys holds the signing key first, followed by the keys it replaced.
+	keys []signingKey
+}
+
+// signingKey is a private key with the kid and algorithm it signs with.
+type signingKey struct {
+	key       crypto.Signer
+	keyID     string
+	algorithm jose.SignatureAlgorithm
+	signer    jose.Signer
+}
+
+// SignerOption configures a Signer.
+type SignerOption func(*signerOptions)
+
+type signerOptions struct {
+	key       crypto.Signer
+	keyID     string
+	algorithm jose.SignatureAlgorithm
+}
+
+// WithAlgorithm generates a key for algorithm instead of the default RS256.
+// RSA, ECDSA and EdDSA algorithms are supported.
+func WithAlgorithm(algorithm jose.SignatureAlgorithm) SignerOption {
+	return func(o *signerOptions) { o.algorithm = algorithm }
+}
+
+// WithSigningKey signs with key and algorithm instead of a generated key.
+func WithSigningKey(key crypto.Signer, algorithm jose.SignatureAlgorithm) SignerOption {
+	return func(o *signerOptions) {
+		o.key = key
+		o.algorithm = algorithm
+	}
+}
+
+// WithKeyID sets the kid of the signing key. It defaults to a random
+// "snk_" identifier.
+func WithKeyID(keyID string) SignerOption {
+	return func(o *signerOptions) { o.keyID = keyID }
+}
+
+// NewSigner returns a Signer for tokens issued by issuer. Without options it
+// signs with RS256 and a newly generated RSA key.
+func NewSigner(issuer string, opts ...SignerOption) (*Signer, error) {
+	o := signerOptions{algorithm: jose.RS256}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	key, err := newSigningKey(o.key, o.keyID, o.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{Issuer: issuer, keys: []signingKey{key}}, nil
+}
+
+// newSigningKey returns a signing key for algorithm, generating the private
+// key when key is nil and the kid when keyID is empty.
+func newSigningKey(key crypto.Signer, keyID string, algorithm jose.SignatureAlgorithm) (signingKey, error) {
+	if key == nil {
+		var err error
+		if key, err = generateKey(algorithm); err != nil {
+			return signingKey{}, fmt.Errorf("generate signing key: %w", err)
+		}
+	}
+	if keyID == "" {
+		keyID = "snk_" + randomHex(8)
+	}
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: algorithm, Key: jose.JSONWebKey{Key: key, KeyID: keyID}},
+		(&jose.SignerOptions{}).WithType("JWT"),
+	)
+	if err != nil {
+		return signingKey{}, err
+	}
+	return signingKey{key: key, keyID: keyID, algorithm: algorithm, signer: signer}, nil
+}
+
+func generateKey(algorithm jose.SignatureAlgorithm) (crypto.Signer, error) {
+	switch algorithm {
+	case jose.RS256, jose.RS384, jose.RS512, jose.PS256, jose.PS384, jose.PS512:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case jose.ES256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case jose.ES384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case jose.ES512:
+		return ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	case jose.EdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+}
+
+// Rotate replaces the signing key with a newly generated one for the same
+// algorithm. The replaced keys stay in the JWKS after the new one, so tokens
+// minted before the rotation still validate.
+func (s *Signer) Rotate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, err := newSigningKey(nil, "", s.keys[0].algorithm)
+	if err != nil {
+		return err
+	}
+	s.keys = append([]signingKey{key}, s.keys...)
+	return nil
+}
+
+// current returns the key minted tokens are signed with.
+func (s *Signer) current() signingKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys[0]
+}
+
+// KeyID returns the kid header of minted tokens.
+func (s *Signer) KeyID() string {
+	return s.current().keyID
+}
+
+// JWKS returns the key set containing the signer's public keys, the signing
+// key first.
+func (s *Signer) JWKS() *jose.JSONWebKeySet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keySet := &jose.JSONWebKeySet{}
+	for _, key := range s.keys {
+		keySet.Keys = append(keySet.Keys, jose.JSONWebKey{
+			Key:       key.key.Public(),
+			KeyID:     key.keyID,
+			Algorithm: string(key.algorithm),
+			Use:       "sig",
+		})
+	}
+	return keySet
+}
+
+// KeySet returns the signer's JWKS. Its signature matches the jwksFn argument
+// of scalekit.ValidateToken, so minted tokens validate without any server:
+//
+//	claims, err := scalekit.ValidateToken[scalekit.IdTokenClaims](ctx, token, signer.KeySet)
+func (s *Signer) KeySet(context.Context) (*jose.JSONWebKeySet, error) {
+	return s.JWKS(), nil
+}
+
+// ServeHTTP serves the signer's JWKS.
+func (s *Signer) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.JWKS())
+}
+
+// TokenOption customizes a minted token.
+type TokenOption func(*tokenOptions)
+
+type tokenOptions struct {
+	issuer    string
+	issuedAt  time.Time
+	expiresAt time.Time
+	audience  []string
+	scopes    []string
+	claims    map[string]any
+}
+
+// WithExpiry sets the token's lifetime from now. A negative d mints a token
+// that has already expired. Tokens live for an hour by default.
+func WithExpiry(d time.Duration) TokenOption {
+	return func(o *tokenOptions) { o.expiresAt = time.Now().Add(d) }
+}
+
+// WithExpiresAt sets the token's exp claim.
+func WithExpiresAt(t time.Time) TokenOption {
+	return func(o *tokenOptions) { o.expiresAt = t }
+}
+
+// WithIssuedAt sets the token's iat claim. It defaults to now.
+func WithIssuedAt(t time.Time) TokenOption {
+	return func(o *tokenOptions) { o.issuedAt = t }
+}
+
+// WithAudience sets the token's aud claim.
+func WithAudience(audience ...string) TokenOption {
+	return func(o *tokenOptions) { o.audience = audience }
+}
+
+// WithScopes sets the token's space-delimited scope claim.
+func WithScopes(scopes ...string) TokenOption {
+	return func(o *tokenOptions) { o.scopes = scopes }
+}
+
+// WithIssuer overrides the signer's issuer for one token.
+func WithIssuer(issuer string) TokenOption {
+	return func(o *tokenOptions) { o.issuer = issuer }
+}
+
+// WithClaims adds custom claims to the token. They take precedence over the
+// claims built from the claims struct and the other options.
+func WithClaims(claims map[string]any) TokenOption {
+	return func(o *tokenOptions) {
+		if o.claims == nil {
+			o.claims = map[string]any{}
+		}
+		maps.Copy(o.claims, claims)
+	}
+}
+
+// MintIDToken returns an ID token carrying claims, as returned by
+// AuthenticateWithCode. claims.Claims is merged into the payload.
+func (s *Signer) MintIDToken(claims scalekit.IdTokenClaims, opts ...TokenOption) (string, error) {
+	return s.mint(claims, claims.Claims, opts)
+}
+
+// MintAccessToken returns an access token carrying claims. Non-zero Iss, Iat,
+// Exp and Audience fields take precedence over the signer's defaults, and
+// claims.Claims is merged into the payload.
+func (s *Signer) MintAccessToken(claims scalekit.AccessTokenClaims, opts ...TokenOption) (string, error) {
+	return s.mint(claims, claims.Claims, opts)
+}
+
+// MintIdpInitiatedLoginToken returns the token passed to the redirect URI of an
+// IdP-initiated login, as read by GetIdpInitiatedLoginClaims.
+func (s *Signer) MintIdpInitiatedLoginToken(claims scalekit.IdpInitiatedLoginClaims, opts ...TokenOption) (string, error) {
+	return s.mint(claims, nil, opts)
+}
+
+// Mint returns a token carrying exactly the given claims, plus iss, iat and exp
+// when they are missing.
+func (s *Signer) Mint(claims map[string]any, opts ...TokenOption) (string, error) {
+	return s.mint(nil, claims, opts)
+}
+
+// mint signs the non-zero fields of claimsStruct merged with extra, the
+// registered claims derived from opts, and the custom claims of opts.
+func (s *Signer) mint(claimsStruct any, extra map[string]any, opts []TokenOption) (string, error) {
+	now := time.Now()
+	o := tokenOptions{issuer: s.Issuer, issuedAt: now, expiresAt: now.Add(defaultTokenLifetime)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	payload := map[string]any{
+		"iss": o.issuer,
+		"iat": o.issuedAt.Unix(),
+		"exp": o.expiresAt.Unix(),
+	}
+	if claimsStruct != nil {
+		fields, err := nonZeroFields(claimsStruct)
+		if err != nil {
+			return "", err
+		}
+		maps.Copy(payload, fields)
+	}
+	maps.Copy(payload, extra)
+	if len(o.audience) > 0 {
+		payload["aud"] = o.audience
+	}
+	if len(o.scopes) > 0 {
+		payload["scope"] = strings.Join(o.scopes, " ")
+	}
+	maps.Copy(payload, o.claims)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	signed, err := s.current().signer.Sign(body)
+	if err != nil {
+		return "", err
+	}
+	return signed.CompactSerialize()
+}
+
+// nonZeroFields returns the JSON form of v without its zero-valued fields, so
+// minted tokens only carry the claims a test actually set.
+func nonZeroFields(v any) (map[string]any, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range fields {
+		if value == nil || reflect.ValueOf(value).IsZero() {
+			delete(fields, name)
+			continue
+		}
+		if rv := reflect.ValueOf(value); (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Map) && rv.Len() == 0 {
+			delete(fields, name)
+		}
+	}
+	return fields, nil
+}
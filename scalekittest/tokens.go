@@ -0,0 +1,135 @@
+package scalekittest
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"connectrpc.com/connect"
+	tokensv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/tokens"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/tokens/tokensconnect"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// apiTokenRecord is an issued API token and its metadata.
+type apiTokenRecord struct {
+	secret string
+	info   *tokensv1.Token
+}
+
+type apiTokenService struct {
+	tokensconnect.UnimplementedApiTokenServiceHandler
+	s *Server
+}
+
+// findAPIToken looks an API token up by its value or its id. s.mu must be held.
+func (s *Server) findAPIToken(token string) (*apiTokenRecord, error) {
+	if record, ok := s.apiTokens.get(token); ok {
+		return record, nil
+	}
+	for _, record := range s.apiTokens.list(nil) {
+		if record.secret == token {
+			return record, nil
+		}
+	}
+	return nil, notFound("token", token)
+}
+
+func (t *apiTokenService) CreateToken(_ context.Context, req *connect.Request[tokensv1.CreateTokenRequest]) (*connect.Response[tokensv1.CreateTokenResponse], error) {
+	in := req.Msg.GetToken()
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+	org, err := t.s.findOrganization(in.GetOrganizationId(), "")
+	if err != nil {
+		return nil, err
+	}
+	secret := "apit_" + randomHex(20)
+	info := &tokensv1.Token{
+		TokenId:                t.s.nextID("apit"),
+		OrganizationId:         org.Id,
+		OrganizationExternalId: org.GetExternalId(),
+		CustomClaims:           in.GetCustomClaims(),
+		Expiry:                 in.GetExpiry(),
+		CreatedAt:              timestamppb.Now(),
+		TokenSuffix:            secret[len(secret)-4:],
+	}
+	if in.GetUserId() != "" {
+		user, err := t.s.findUser(in.GetUserId(), "")
+		if err != nil {
+			return nil, err
+		}
+		info.UserId = &user.Id
+		info.UserExternalId = user.ExternalId
+		info.Email = &user.Email
+	}
+	if in.GetDescription() != "" {
+		description := in.GetDescription()
+		info.Description = &description
+	}
+	t.s.apiTokens.put(info.TokenId, &apiTokenRecord{secret: secret, info: info})
+	return connect.NewResponse(&tokensv1.CreateTokenResponse{
+		Token:     secret,
+		TokenId:   info.TokenId,
+		TokenInfo: clone(info),
+	}), nil
+}
+
+func (t *apiTokenService) ValidateToken(_ context.Context, req *connect.Request[tokensv1.ValidateTokenRequest]) (*connect.Response[tokensv1.ValidateTokenResponse], error) {
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+	record, err := t.s.findAPIToken(req.Msg.GetToken())
+	if err != nil {
+		return nil, err
+	}
+	if expiry := record.info.GetExpiry(); expiry != nil && time.Now().After(expiry.AsTime()) {
+		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("token has expired"))
+	}
+	return connect.NewResponse(&tokensv1.ValidateTokenResponse{TokenInfo: clone(record.info)}), nil
+}
+
+func (t *apiTokenService) InvalidateToken(_ context.Context, req *connect.Request[tokensv1.InvalidateTokenRequest]) (*connect.Response[emptypb.Empty], error) {
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+	record, err := t.s.findAPIToken(req.Msg.GetToken())
+	if err != nil {
+		return nil, err
+	}
+	t.s.apiTokens.delete(record.info.TokenId)
+	return connect.NewResponse(&emptypb.Empty{}), nil
+}
+
+func (t *apiTokenService) UpdateToken(_ context.Context, req *connect.Request[tokensv1.UpdateTokenRequest]) (*connect.Response[tokensv1.UpdateTokenResponse], error) {
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+	record, err := t.s.findAPIToken(req.Msg.GetToken())
+	if err != nil {
+		return nil, err
+	}
+	if req.Msg.CustomClaims != nil {
+		record.info.CustomClaims = req.Msg.GetCustomClaims()
+	}
+	if req.Msg.Description != nil {
+		description := req.Msg.GetDescription()
+		record.info.Description = &description
+	}
+	return connect.NewResponse(&tokensv1.UpdateTokenResponse{TokenInfo: clone(record.info)}), nil
+}
+
+func (t *apiTokenService) ListTokens(_ context.Context, req *connect.Request[tokensv1.ListTokensRequest]) (*connect.Response[tokensv1.ListTokensResponse], error) {
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+	records := t.s.apiTokens.list(func(record *apiTokenRecord) bool {
+		return record.info.OrganizationId == req.Msg.GetOrganizationId() &&
+			(req.Msg.UserId == nil || record.info.GetUserId() == req.Msg.GetUserId())
+	})
+	page, next, prev, err := paginate(records, uint32(max(req.Msg.GetPageSize(), 0)), req.Msg.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	resp := &tokensv1.ListTokensResponse{NextPageToken: next, PrevPageToken: prev, TotalCount: int32(len(records))}
+	for _, record := range page {
+		resp.Tokens = append(resp.Tokens, clone(record.info))
+	}
+	return connect.NewResponse(resp), nil
+}
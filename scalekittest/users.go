@@ -0,0 +1,339 @@
+package scalekittest
+
+import (
+	"context"
+
+	"connectrpc.com/connect"
+	commonsv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/commons"
+	usersv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/users"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/users/usersconnect"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type userService struct {
+	usersconnect.UnimplementedUserServiceHandler
+	s *Server
+}
+
+// findUser looks a user up by id or external id. s.mu must be held.
+func (s *Server) findUser(id, externalID string) (*usersv1.User, error) {
+	if id != "" {
+		if user, ok := s.users.get(id); ok {
+			return user, nil
+		}
+		return nil, notFound("user", id)
+	}
+	for _, user := range s.users.list(nil) {
+		if externalID != "" && user.GetExternalId() == externalID {
+			return user, nil
+		}
+	}
+	return nil, notFound("user", externalID)
+}
+
+// findMembership returns the user's membership in organizationID, or nil.
+func findMembership(user *usersv1.User, organizationID string) *commonsv1.OrganizationMembership {
+	for _, m := range user.Memberships {
+		if m.OrganizationId == organizationID {
+			return m
+		}
+	}
+	return nil
+}
+
+// membershipRoles resolves the requested roles against the environment's role
+// definitions so responses carry their ids and display names. s.mu must be held.
+func (s *Server) membershipRoles(roles []*commonsv1.Role) []*commonsv1.Role {
+	var out []*commonsv1.Role
+	for _, r := range roles {
+		role := clone(r)
+		if def, ok := s.findRole(r.GetName()); ok {
+			role.Id = def.Id
+			role.DisplayName = def.DisplayName
+		}
+		out = append(out, role)
+	}
+	return out
+}
+
+// addMembership adds user to organizationID. s.mu must be held.
+func (s *Server) addMembership(user *usersv1.User, organizationID string, in *usersv1.CreateMembership, invite bool) error {
+	if _, err := s.findOrganization(organizationID, ""); err != nil {
+		return err
+	}
+	if findMembership(user, organizationID) != nil {
+		return alreadyExists("membership in organization", organizationID)
+	}
+	now := timestamppb.Now()
+	membership := &commonsv1.OrganizationMembership{
+		OrganizationId:   organizationID,
+		JoinTime:         now,
+		CreatedAt:        now,
+		MembershipStatus: commonsv1.MembershipStatus_ACTIVE,
+		Roles:            s.membershipRoles(in.GetRoles()),
+		Metadata:         in.GetMetadata(),
+		InviterEmail:     in.InviterEmail,
+	}
+	if invite {
+		membership.MembershipStatus = commonsv1.MembershipStatus_PENDING_INVITE
+	} else {
+		membership.AcceptedAt = now
+	}
+	user.Memberships = append(user.Memberships, membership)
+	user.UpdateTime = now
+	return nil
+}
+
+func (u *userService) CreateUserAndMembership(_ context.Context, req *connect.Request[usersv1.CreateUserAndMembershipRequest]) (*connect.Response[usersv1.CreateUserAndMembershipResponse], error) {
+	in := req.Msg.GetUser()
+	if in.GetEmail() == "" {
+		return nil, invalidArgument("user email is required")
+	}
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+	for _, existing := range u.s.users.list(nil) {
+		if existing.Email == in.GetEmail() {
+			return nil, alreadyExists("user with email", in.GetEmail())
+		}
+	}
+	now := timestamppb.Now()
+	user := &usersv1.User{
+		Id:         u.s.nextID("usr"),
+		CreateTime: now,
+		UpdateTime: now,
+		Email:      in.GetEmail(),
+		ExternalId: in.ExternalId,
+		Metadata:   in.GetMetadata(),
+	}
+	user.UserProfile = &commonsv1.UserProfile{Id: user.Id}
+	if profile := in.GetUserProfile(); profile != nil {
+		user.UserProfile = &commonsv1.UserProfile{
+			Id:                user.Id,
+			GivenName:         profile.GetGivenName(),
+			FamilyName:        profile.GetFamilyName(),
+			Name:              profile.GetName(),
+			Locale:            profile.GetLocale(),
+			PhoneNumber:       profile.GetPhoneNumber(),
+			Metadata:          profile.GetMetadata(),
+			CustomAttributes:  profile.GetCustomAttributes(),
+			PreferredUsername: profile.GetPreferredUsername(),
+			Picture:           profile.GetPicture(),
+			Gender:            profile.GetGender(),
+			Groups:            profile.GetGroups(),
+		}
+	}
+	if err := u.s.addMembership(user, req.Msg.GetOrganizationId(), in.GetMembership(), req.Msg.GetSendInvitationEmail()); err != nil {
+		return nil, err
+	}
+	u.s.users.put(user.Id, user)
+	return connect.NewResponse(&usersv1.CreateUserAndMembershipResponse{User: clone(user)}), nil
+}
+
+func (u *userService) GetUser(_ context.Context, req *connect.Request[usersv1.GetUserRequest]) (*connect.Response[usersv1.GetUserResponse], error) {
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+	user, err := u.s.findUser(req.Msg.GetId(), req.Msg.GetExternalId())
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&usersv1.GetUserResponse{User: clone(user)}), nil
+}
+
+func (u *userService) ListUsers(_ context.Context, req *connect.Request[usersv1.ListUsersRequest]) (*connect.Response[usersv1.ListUsersResponse], error) {
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+	users := u.s.users.list(nil)
+	page, next, prev, err := paginate(users, req.Msg.GetPageSize(), req.Msg.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	resp := &usersv1.ListUsersResponse{NextPageToken: next, PrevPageToken: prev, TotalSize: uint32(len(users))}
+	for _, user := range page {
+		resp.Users = append(resp.Users, clone(user))
+	}
+	return connect.NewResponse(resp), nil
+}
+
+func (u *userService) ListOrganizationUsers(_ context.Context, req *connect.Request[usersv1.ListOrganizationUsersRequest]) (*connect.Response[usersv1.ListOrganizationUsersResponse], error) {
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+	if _, err := u.s.findOrganization(req.Msg.GetOrganizationId(), ""); err != nil {
+		return nil, err
+	}
+	users := u.s.users.list(func(user *usersv1.User) bool {
+		return findMembership(user, req.Msg.GetOrganizationId()) != nil
+	})
+	page, next, prev, err := paginate(users, req.Msg.GetPageSize(), req.Msg.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	resp := &usersv1.ListOrganizationUsersResponse{NextPageToken: next, PrevPageToken: prev, TotalSize: uint32(len(users))}
+	for _, user := range page {
+		resp.Users = append(resp.Users, clone(user))
+	}
+	return connect.NewResponse(resp), nil
+}
+
+func (u *userService) UpdateUser(_ context.Context, req *connect.Request[usersv1.UpdateUserRequest]) (*connect.Response[usersv1.UpdateUserResponse], error) {
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+	user, err := u.s.findUser(req.Msg.GetId(), req.Msg.GetExternalId())
+	if err != nil {
+		return nil, err
+	}
+	in := req.Msg.GetUser()
+	if in.ExternalId != nil {
+		user.ExternalId = in.ExternalId
+	}
+	if in.Metadata != nil {
+		user.Metadata = in.GetMetadata()
+	}
+	if profile := in.GetUserProfile(); profile != nil {
+		p := user.UserProfile
+		if profile.GivenName != nil {
+			p.GivenName = profile.GetGivenName()
+		}
+		if profile.FamilyName != nil {
+			p.FamilyName = profile.GetFamilyName()
+		}
+		if profile.Name != nil {
+			p.Name = profile.GetName()
+		}
+		if profile.Locale != nil {
+			p.Locale = profile.GetLocale()
+		}
+		if profile.PhoneNumber != nil {
+			p.PhoneNumber = profile.GetPhoneNumber()
+		}
+		if profile.PreferredUsername != nil {
+			p.PreferredUsername = profile.GetPreferredUsername()
+		}
+		if profile.Picture != nil {
+			p.Picture = profile.GetPicture()
+		}
+		if profile.Gender != nil {
+			p.Gender = profile.GetGender()
+		}
+		if profile.Metadata != nil {
+			p.Metadata = profile.GetMetadata()
+		}
+		if profile.CustomAttributes != nil {
+			p.CustomAttributes = profile.GetCustomAttributes()
+		}
+		if profile.Groups != nil {
+			p.Groups = profile.GetGroups()
+		}
+	}
+	user.UpdateTime = timestamppb.Now()
+	return connect.NewResponse(&usersv1.UpdateUserResponse{User: clone(user)}), nil
+}
+
+func (u *userService) DeleteUser(_ context.Context, req *connect.Request[usersv1.DeleteUserRequest]) (*connect.Response[emptypb.Empty], error) {
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+	user, err := u.s.findUser(req.Msg.GetId(), req.Msg.GetExternalId())
+	if err != nil {
+		return nil, err
+	}
+	u.s.users.delete(user.Id)
+	return connect.NewResponse(&emptypb.Empty{}), nil
+}
+
+func (u *userService) CreateMembership(_ context.Context, req *connect.Request[usersv1.CreateMembershipRequest]) (*connect.Response[usersv1.CreateMembershipResponse], error) {
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+	user, err := u.s.findUser(req.Msg.GetId(), req.Msg.GetExternalId())
+	if err != nil {
+		return nil, err
+	}
+	if err := u.s.addMembership(user, req.Msg.GetOrganizationId(), req.Msg.GetMembership(), req.Msg.GetSendInvitationEmail()); err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&usersv1.CreateMembershipResponse{User: clone(user)}), nil
+}
+
+func (u *userService) UpdateMembership(_ context.Context, req *connect.Request[usersv1.UpdateMembershipRequest]) (*connect.Response[usersv1.UpdateMembershipResponse], error) {
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+	user, err := u.s.findUser(req.Msg.GetId(), req.Msg.GetExternalId())
+	if err != nil {
+		return nil, err
+	}
+	membership := findMembership(user, req.Msg.GetOrganizationId())
+	if membership == nil {
+		return nil, notFound("membership in organization", req.Msg.GetOrganizationId())
+	}
+	if roles := req.Msg.GetMembership().GetRoles(); roles != nil {
+		membership.Roles = u.s.membershipRoles(roles)
+	}
+	if metadata := req.Msg.GetMembership().GetMetadata(); metadata != nil {
+		membership.Metadata = metadata
+	}
+	user.UpdateTime = timestamppb.Now()
+	return connect.NewResponse(&usersv1.UpdateMembershipResponse{User: clone(user)}), nil
+}
+
+func (u *userService) DeleteMembership(_ context.Context, req *connect.Request[usersv1.DeleteMembershipRequest]) (*connect.Response[emptypb.Empty], error) {
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+	user, err := u.s.findUser(req.Msg.GetId(), req.Msg.GetExternalId())
+	if err != nil {
+		return nil, err
+	}
+	for i, m := range user.Memberships {
+		if m.OrganizationId == req.Msg.GetOrganizationId() {
+			user.Memberships = append(user.Memberships[:i], user.Memberships[i+1:]...)
+			user.UpdateTime = timestamppb.Now()
+			return connect.NewResponse(&emptypb.Empty{}), nil
+		}
+	}
+	return nil, notFound("membership in organization", req.Msg.GetOrganizationId())
+}
+
+func (u *userService) ListUserRoles(_ context.Context, req *connect.Request[usersv1.ListUserRolesRequest]) (*connect.Response[usersv1.ListUserRolesResponse], error) {
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+	membership, err := u.s.findUserMembership(req.Msg.GetUserId(), req.Msg.GetOrganizationId())
+	if err != nil {
+		return nil, err
+	}
+	resp := &usersv1.ListUserRolesResponse{}
+	for _, role := range membership.Roles {
+		resp.Roles = append(resp.Roles, clone(role))
+	}
+	return connect.NewResponse(resp), nil
+}
+
+func (u *userService) ListUserPermissions(_ context.Context, req *connect.Request[usersv1.ListUserPermissionsRequest]) (*connect.Response[usersv1.ListUserPermissionsResponse], error) {
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+	membership, err := u.s.findUserMembership(req.Msg.GetUserId(), req.Msg.GetOrganizationId())
+	if err != nil {
+		return nil, err
+	}
+	resp := &usersv1.ListUserPermissionsResponse{}
+	seen := map[string]bool{}
+	for _, role := range membership.Roles {
+		for _, p := range u.s.effectivePermissions(role.GetName()) {
+			if !seen[p.Name] {
+				seen[p.Name] = true
+				resp.Permissions = append(resp.Permissions, &usersv1.Permission{Id: p.Id, Name: p.Name, Description: p.Description})
+			}
+		}
+	}
+	return connect.NewResponse(resp), nil
+}
+
+// findUserMembership returns a user's membership in an organization. s.mu must be held.
+func (s *Server) findUserMembership(userID, organizationID string) (*commonsv1.OrganizationMembership, error) {
+	user, err := s.findUser(userID, "")
+	if err != nil {
+		return nil, err
+	}
+	membership := findMembership(user, organizationID)
+	if membership == nil {
+		return nil, notFound("membership in organization", organizationID)
+	}
+	return membership, nil
+}
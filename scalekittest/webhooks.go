@@ -0,0 +1,31 @@
+package scalekittest
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+)
+
+// NewWebhookRequest returns an incoming POST request to target delivering
+// payload signed with secret, as Scalekit delivers webhooks, for passing to a
+// handler with httptest.NewRecorder. An empty id is replaced with a random
+// one; reuse an id to simulate a redelivery. Like httptest.NewRequest, it
+// panics on invalid input, such as a malformed secret.
+func NewWebhookRequest(target, secret, id string, payload []byte) *http.Request {
+	if id == "" {
+		id = "msg_" + randomHex(12)
+	}
+	headers, err := scalekit.SignWebhookPayload(secret, id, time.Now(), payload)
+	if err != nil {
+		panic("scalekittest: " + err.Error())
+	}
+	request := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(payload))
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	return request
+}
@@ -0,0 +1,78 @@
+package scalekit
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+// minSealSecretLength is the shortest secret accepted for sealing cookies.
+const minSealSecretLength = 32
+
+// sealer encrypts and authenticates values with AES-256-GCM, for data the SDK
+// stores in the browser. An AEAD authenticates the ciphertext and the
+// additional data in one step, which leaves no MAC to misorder or compare in
+// variable time. The key is derived from one secret and a purpose with
+// HMAC-SHA256, so a value sealed for one purpose does not open as another.
+// Callers pass the cookie name as additional data, so a value does not open
+// under another cookie's name.
+type sealer struct {
+	aead cipher.AEAD
+}
+
+func newSealer(secret []byte, purpose string) (*sealer, error) {
+	if len(secret) < minSealSecretLength {
+		return nil, ErrCookieSecretTooShort
+	}
+	block, err := aes.NewCipher(deriveKey(secret, "scalekit "+purpose+" encryption"))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &sealer{aead: aead}, nil
+}
+
+// deriveKey returns HMAC-SHA256(secret, label).
+func deriveKey(secret []byte, label string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(label))
+	return mac.Sum(nil)
+}
+
+// seal returns the base64url encoding of a random nonce followed by the
+// ciphertext of plaintext, bound to additionalData.
+func (s *sealer) seal(plaintext []byte, additionalData string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize(), s.aead.NonceSize()+len(plaintext)+s.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := s.aead.Seal(nonce, nonce, plaintext, []byte(additionalData))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// open verifies and decrypts a value returned by seal with the same
+// additionalData.
+func (s *sealer) open(value string, additionalData string) ([]byte, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(sealed) < s.aead.NonceSize()+s.aead.Overhead() {
+		return nil, errSealedValueInvalid
+	}
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, []byte(additionalData))
+	if err != nil {
+		return nil, errSealedValueInvalid
+	}
+	return plaintext, nil
+}
+
+// errSealedValueInvalid is returned by open for values that were not produced
+// by seal with the same secret, purpose and additional data, or were modified
+// since.
+var errSealedValueInvalid = errors.New("sealed value is invalid")
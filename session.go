@@ -0,0 +1,306 @@
+package scalekit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSessionCookieName is the name of the cookie NewCookieSessionStore
+// sets when CookieOptions.Name is empty.
+const DefaultSessionCookieName = "scalekit_session"
+
+// DefaultSessionCookieMaxAge is the lifetime of the cookie
+// NewCookieSessionStore sets when CookieOptions.MaxAge is zero.
+const DefaultSessionCookieMaxAge = 7 * 24 * time.Hour
+
+// maxCookieValueLength keeps a cookie, with its name and attributes, within
+// the 4096 bytes browsers are required to store.
+const maxCookieValueLength = 3800
+
+// maxSessionCookieChunks bounds the number of cookies a session is split
+// into, so that a session stays well within the cookies browsers keep per
+// site.
+const maxSessionCookieChunks = 5
+
+// Session is the application session established by a completed login.
+type Session struct {
+	// SessionId is the Scalekit session ID, the sid claim of the login's
+	// tokens. Logging out revokes it.
+	SessionId    string    `json:"sid,omitempty"`
+	User         User      `json:"user"`
+	IdToken      string    `json:"id_token,omitempty"`
+	AccessToken  string    `json:"access_token,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type sessionAlias Session
+
+// MarshalJSON writes User with all the claims of the login's ID token, which
+// IdTokenClaims.UnmarshalJSON restores to User.Claims when the session is
+// loaded.
+func (s Session) MarshalJSON() ([]byte, error) {
+	user, err := json.Marshal(s.User)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.User.Claims) > 0 {
+		claims := maps.Clone(s.User.Claims)
+		if err := json.Unmarshal(user, &claims); err != nil {
+			return nil, err
+		}
+		if user, err = json.Marshal(claims); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(struct {
+		sessionAlias
+		User json.RawMessage `json:"user"`
+	}{sessionAlias(s), user})
+}
+
+// SessionStore keeps the Session of each browser.
+type SessionStore interface {
+	// Load returns the session of the browser making r, or nil when it has
+	// none.
+	Load(r *http.Request) (*Session, error)
+	Save(w http.ResponseWriter, r *http.Request, session *Session) error
+	Clear(w http.ResponseWriter, r *http.Request) error
+}
+
+type cookieSessionStore struct {
+	sealer  *sealer
+	options CookieOptions
+}
+
+// NewCookieSessionStore returns a SessionStore that keeps the session in the
+// browser, encrypted and authenticated with a key derived from secret. secret
+// must be at least 32 bytes, and must differ from the secret of any other
+// store. A session too large for one cookie is split across up to five
+// cookies, named after CookieOptions.Name with a "_1", "_2", ... suffix. The
+// cookie lifetime defaults to DefaultSessionCookieMaxAge.
+//
+// A session that does not fit in five cookies is rejected with
+// ErrSessionTooLarge.
+func NewCookieSessionStore(secret []byte, options CookieOptions) (SessionStore, error) {
+	sealer, err := newSealer(secret, "session")
+	if err != nil {
+		return nil, err
+	}
+	if options.Name == "" {
+		options.Name = DefaultSessionCookieName
+	}
+	if options.MaxAge == 0 {
+		options.MaxAge = DefaultSessionCookieMaxAge
+	}
+	return &cookieSessionStore{sealer: sealer, options: options}, nil
+}
+
+// chunkName returns the name of the i-th session cookie.
+func (s *cookieSessionStore) chunkName(i int) string {
+	if i == 0 {
+		return s.options.Name
+	}
+	return fmt.Sprintf("%s_%d", s.options.Name, i)
+}
+
+func (s *cookieSessionStore) Load(r *http.Request) (*Session, error) {
+	var value strings.Builder
+	for i := 0; i < maxSessionCookieChunks; i++ {
+		cookie, err := r.Cookie(s.chunkName(i))
+		if err != nil {
+			break
+		}
+		value.WriteString(cookie.Value)
+	}
+	if value.Len() == 0 {
+		return nil, nil
+	}
+	data, err := s.sealer.open(value.String(), s.options.Name)
+	if err != nil {
+		return nil, nil
+	}
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, nil
+	}
+	return &session, nil
+}
+
+func (s *cookieSessionStore) Save(w http.ResponseWriter, r *http.Request, session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	value, err := s.sealer.seal(data, s.options.Name)
+	if err != nil {
+		return err
+	}
+	chunks := (len(value) + maxCookieValueLength - 1) / maxCookieValueLength
+	if chunks > maxSessionCookieChunks {
+		return ErrSessionTooLarge
+	}
+	for i := 0; i < chunks; i++ {
+		chunk := value[i*maxCookieValueLength : min((i+1)*maxCookieValueLength, len(value))]
+		http.SetCookie(w, s.options.cookie(s.chunkName(i), chunk, s.options.MaxAge))
+	}
+	s.clearChunks(w, r, chunks)
+	return nil
+}
+
+func (s *cookieSessionStore) Clear(w http.ResponseWriter, r *http.Request) error {
+	http.SetCookie(w, s.options.cookie(s.options.Name, "", -1))
+	s.clearChunks(w, r, 1)
+	return nil
+}
+
+// clearChunks deletes the session cookies from the from-th on that the
+// browser sent with r, left over from a larger session.
+func (s *cookieSessionStore) clearChunks(w http.ResponseWriter, r *http.Request, from int) {
+	for i := from; i < maxSessionCookieChunks; i++ {
+		if _, err := r.Cookie(s.chunkName(i)); err == nil {
+			http.SetCookie(w, s.options.cookie(s.chunkName(i), "", -1))
+		}
+	}
+}
+
+// SessionBackend stores sessions on the server for NewServerSessionStore,
+// for example in Redis or a database table.
+type SessionBackend interface {
+	// Get returns the session stored under id, or nil when there is none or
+	// it has expired.
+	Get(ctx context.Context, id string) (*Session, error)
+	// Set stores session under id for ttl.
+	Set(ctx context.Context, id string, session *Session, ttl time.Duration) error
+	Delete(ctx context.Context, id string) error
+}
+
+type serverSessionStore struct {
+	backend SessionBackend
+	options CookieOptions
+}
+
+// NewServerSessionStore returns a SessionStore that keeps sessions in backend
+// and only a random session ID in the browser's cookie. Sessions and cookies
+// expire after CookieOptions.MaxAge, DefaultSessionCookieMaxAge by default.
+// Saving a session for another Scalekit session than the stored one, as after
+// a new login, issues a new session ID.
+func NewServerSessionStore(backend SessionBackend, options CookieOptions) SessionStore {
+	if options.Name == "" {
+		options.Name = DefaultSessionCookieName
+	}
+	if options.MaxAge == 0 {
+		options.MaxAge = DefaultSessionCookieMaxAge
+	}
+	return &serverSessionStore{backend: backend, options: options}
+}
+
+func (s *serverSessionStore) Load(r *http.Request) (*Session, error) {
+	cookie, err := r.Cookie(s.options.Name)
+	if err != nil || cookie.Value == "" {
+		return nil, nil
+	}
+	return s.backend.Get(r.Context(), cookie.Value)
+}
+
+func (s *serverSessionStore) Save(w http.ResponseWriter, r *http.Request, session *Session) error {
+	id := ""
+	if cookie, err := r.Cookie(s.options.Name); err == nil && cookie.Value != "" {
+		stored, err := s.backend.Get(r.Context(), cookie.Value)
+		if err != nil {
+			return err
+		}
+		if stored != nil && stored.SessionId != "" && stored.SessionId == session.SessionId {
+			id = cookie.Value
+		} else if err := s.backend.Delete(r.Context(), cookie.Value); err != nil {
+			return err
+		}
+	}
+	if id == "" {
+		id = randomValue()
+	}
+	if err := s.backend.Set(r.Context(), id, session, s.options.MaxAge); err != nil {
+		return err
+	}
+	http.SetCookie(w, s.options.cookie(s.options.Name, id, s.options.MaxAge))
+	return nil
+}
+
+func (s *serverSessionStore) Clear(w http.ResponseWriter, r *http.Request) error {
+	if cookie, err := r.Cookie(s.options.Name); err == nil && cookie.Value != "" {
+		if err := s.backend.Delete(r.Context(), cookie.Value); err != nil {
+			return err
+		}
+	}
+	http.SetCookie(w, s.options.cookie(s.options.Name, "", -1))
+	return nil
+}
+
+type memorySessionBackend struct {
+	mu       sync.Mutex
+	sessions map[string]memorySession
+}
+
+type memorySession struct {
+	session   Session
+	expiresAt time.Time
+}
+
+// NewMemorySessionBackend returns a SessionBackend that keeps sessions in
+// memory. Sessions are lost on restart and not shared between instances, so
+// it suits development and single-instance services.
+func NewMemorySessionBackend() SessionBackend {
+	return &memorySessionBackend{sessions: make(map[string]memorySession)}
+}
+
+func (b *memorySessionBackend) Get(_ context.Context, id string) (*Session, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stored, ok := b.sessions[id]
+	if !ok || time.Now().After(stored.expiresAt) {
+		return nil, nil
+	}
+	session := stored.session
+	return &session, nil
+}
+
+func (b *memorySessionBackend) Set(_ context.Context, id string, session *Session, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	for key, stored := range b.sessions {
+		if now.After(stored.expiresAt) {
+			delete(b.sessions, key)
+		}
+	}
+	b.sessions[id] = memorySession{session: *session, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (b *memorySessionBackend) Delete(_ context.Context, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.sessions, id)
+	return nil
+}
+
+// newSession returns the session established by a login.
+func newSession(resp *AuthenticationResponse, sessionId string) *Session {
+	session := &Session{
+		SessionId:    sessionId,
+		User:         resp.User,
+		IdToken:      resp.IdToken,
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+	}
+	if resp.ExpiresIn > 0 {
+		session.ExpiresAt = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second).Truncate(time.Second)
+	}
+	return session
+}
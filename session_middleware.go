@@ -0,0 +1,209 @@
+package scalekit
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultSessionRefreshBefore is how long before the access token of a session
+// expires NewSessionMiddleware refreshes it.
+const DefaultSessionRefreshBefore = time.Minute
+
+// refreshReuseWindow is how long the tokens of a refresh are handed to other
+// requests that still carry the refresh token they replaced, such as requests
+// the browser sent before it received the rotated session cookie.
+const refreshReuseWindow = 30 * time.Second
+
+// SessionMiddlewareOptions configures NewSessionMiddleware.
+type SessionMiddlewareOptions struct {
+	// RefreshBefore is how long before the access token expires it is
+	// refreshed. Defaults to DefaultSessionRefreshBefore.
+	RefreshBefore time.Duration
+
+	// Required rejects requests without a session with OnUnauthenticated.
+	// Otherwise they reach the wrapped handler without a session in their
+	// context.
+	Required bool
+
+	// OnUnauthenticated writes the response to a request without a session
+	// when Required is set, for example a redirect to the login route.
+	// Defaults to a 401 response.
+	OnUnauthenticated func(w http.ResponseWriter, r *http.Request)
+
+	// OnError writes the response when the session cannot be loaded, saved or
+	// refreshed. Defaults to a 500 response, or 502 when Scalekit could not be
+	// reached.
+	OnError func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// NewSessionMiddleware returns net/http middleware that loads the session of
+// each request from store and makes it available through
+// SessionFromContext. Access tokens that expire within RefreshBefore are
+// refreshed with RefreshAccessToken, and the session, with the rotated
+// refresh token, is saved before the wrapped handler runs. Concurrent
+// requests carrying the same refresh token share a single refresh.
+//
+// A session whose refresh token Scalekit rejects is cleared, as is a session
+// whose access token has expired and that has no refresh token.
+func NewSessionMiddleware(client Scalekit, store SessionStore, options SessionMiddlewareOptions) func(http.Handler) http.Handler {
+	if options.RefreshBefore <= 0 {
+		options.RefreshBefore = DefaultSessionRefreshBefore
+	}
+	if options.OnUnauthenticated == nil {
+		options.OnUnauthenticated = func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		}
+	}
+	if options.OnError == nil {
+		options.OnError = func(w http.ResponseWriter, _ *http.Request, err error) {
+			var sdkErr *Error
+			if errors.As(err, &sdkErr) {
+				http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+				return
+			}
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	}
+	refresher := &sessionRefresher{client: client, recent: make(map[string]refreshedTokens)}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session, err := store.Load(r)
+			if err != nil {
+				options.OnError(w, r, err)
+				return
+			}
+			if session != nil && session.RefreshToken != "" && !session.ExpiresAt.IsZero() &&
+				time.Until(session.ExpiresAt) < options.RefreshBefore {
+				session, err = refreshSession(w, r, store, refresher, session)
+				if err != nil {
+					options.OnError(w, r, err)
+					return
+				}
+			} else if session != nil && session.RefreshToken == "" && !session.ExpiresAt.IsZero() &&
+				!time.Now().Before(session.ExpiresAt) {
+				if err := store.Clear(w, r); err != nil {
+					options.OnError(w, r, err)
+					return
+				}
+				session = nil
+			}
+			if session == nil {
+				if options.Required {
+					options.OnUnauthenticated(w, r)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ContextWithSession(r.Context(), session)))
+		})
+	}
+}
+
+// refreshSession refreshes the tokens of session and saves it. It returns the
+// session unchanged when the refresh fails but its access token is still
+// valid, and nil after clearing it when its refresh token was rejected.
+func refreshSession(w http.ResponseWriter, r *http.Request, store SessionStore, refresher *sessionRefresher, session *Session) (*Session, error) {
+	tokens, err := refresher.refresh(r.Context(), session.RefreshToken)
+	if err != nil {
+		var sdkErr *Error
+		switch {
+		case errors.As(err, &sdkErr) && sdkErr.Reason == "invalid_grant":
+			return nil, store.Clear(w, r)
+		case time.Now().Before(session.ExpiresAt):
+			return session, nil
+		default:
+			return nil, err
+		}
+	}
+
+	refreshed := *session
+	refreshed.AccessToken = tokens.AccessToken
+	if tokens.RefreshToken != "" {
+		refreshed.RefreshToken = tokens.RefreshToken
+	}
+	if tokens.IdToken != "" {
+		refreshed.IdToken = tokens.IdToken
+	}
+	refreshed.ExpiresAt = time.Time{}
+	if tokens.ExpiresIn > 0 {
+		refreshed.ExpiresAt = time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second).Truncate(time.Second)
+	}
+	if err := store.Save(w, r, &refreshed); err != nil {
+		return nil, err
+	}
+	return &refreshed, nil
+}
+
+// sessionRefresher refreshes session tokens, once per refresh token.
+type sessionRefresher struct {
+	client Scalekit
+	group  singleflight.Group
+
+	// recent holds the tokens of recent refreshes by the SHA-256 hash of the
+	// refresh token, so that refresh tokens are not kept as keys.
+	mu     sync.Mutex
+	recent map[string]refreshedTokens
+}
+
+type refreshedTokens struct {
+	tokens    *TokenResponse
+	refreshed time.Time
+}
+
+// refresh exchanges refreshToken for new tokens. Callers refreshing the same
+// token concurrently, or within refreshReuseWindow of each other, get the
+// tokens of the first refresh.
+func (s *sessionRefresher) refresh(ctx context.Context, refreshToken string) (*TokenResponse, error) {
+	sum := sha256.Sum256([]byte(refreshToken))
+	key := string(sum[:])
+	s.mu.Lock()
+	recent, ok := s.recent[key]
+	s.mu.Unlock()
+	if ok && time.Since(recent.refreshed) < refreshReuseWindow {
+		return recent.tokens, nil
+	}
+
+	tokens, err, _ := s.group.Do(key, func() (any, error) {
+		// Use WithoutCancel so one caller's context cancellation does not fail all waiters.
+		tokens, err := s.client.RefreshAccessToken(context.WithoutCancel(ctx), refreshToken)
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for stale, recent := range s.recent {
+			if now.Sub(recent.refreshed) >= refreshReuseWindow {
+				delete(s.recent, stale)
+			}
+		}
+		s.recent[key] = refreshedTokens{tokens: tokens, refreshed: now}
+		return tokens, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tokens.(*TokenResponse), nil
+}
+
+type sessionKey struct{}
+
+// ContextWithSession returns a context carrying session, as
+// NewSessionMiddleware does for requests with a session.
+func ContextWithSession(ctx context.Context, session *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+// SessionFromContext returns the session loaded by NewSessionMiddleware.
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	session, ok := ctx.Value(sessionKey{}).(*Session)
+	return session, ok && session != nil
+}
@@ -0,0 +1,127 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthHandlers(t *testing.T) {
+	ctx := context.Background()
+	fake := scalekittest.NewServer(t)
+	client := fake.Client()
+
+	mux := http.NewServeMux()
+	app := httptest.NewServer(mux)
+	defer app.Close()
+	flow, err := scalekit.NewLoginFlow(client, scalekit.LoginFlowOptions{
+		RedirectUri: app.URL + "/auth/callback",
+		Secret:      testCookieSecret,
+		Cookie:      scalekit.CookieOptions{Insecure: true},
+	})
+	require.NoError(t, err)
+	sessions, err := scalekit.NewCookieSessionStore([]byte("fedcba9876543210fedcba9876543210"), scalekit.CookieOptions{Insecure: true})
+	require.NoError(t, err)
+	scalekit.NewAuthHandlers(flow, scalekit.AuthHandlersOptions{
+		Sessions:              sessions,
+		PostLogoutRedirectUri: app.URL + "/",
+	}).Register(mux, "/auth")
+	mux.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		session, err := sessions.Load(r)
+		require.NoError(t, err)
+		if session == nil {
+			http.Error(w, "signed out", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, "%s %s", session.User.Id, session.SessionId)
+	})
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	browser := &http.Client{Jar: jar, CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	get := func(target string) *http.Response {
+		resp, err := browser.Get(target)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp
+	}
+	me := func() (int, string) {
+		resp, err := browser.Get(app.URL + "/me")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	// login follows /auth/login?query through Scalekit back to the callback
+	// and returns the callback's redirect.
+	login := func(query string) string {
+		resp := get(app.URL + "/auth/login?" + query)
+		require.Equal(t, http.StatusFound, resp.StatusCode)
+		authUrl, err := url.Parse(resp.Header.Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, app.URL+"/auth/callback", authUrl.Query().Get("redirect_uri"))
+		if orgId := authUrl.Query().Get("organization_id"); orgId != "" {
+			assert.Equal(t, "org_1", orgId)
+		}
+
+		code := fake.IssueAuthorizationCode(scalekit.IdTokenClaims{Id: "usr_1"},
+			scalekittest.WithClaims(map[string]any{"nonce": authUrl.Query().Get("nonce")}))
+		resp = get(app.URL + "/auth/callback?" + url.Values{"code": {code}, "state": {authUrl.Query().Get("state")}}.Encode())
+		require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+		return resp.Header.Get("Location")
+	}
+
+	assert.Equal(t, "/dashboard", login("organization_id=org_1&return_to=/dashboard"))
+
+	status, body := me()
+	require.Equal(t, http.StatusOK, status)
+	userId, sessionId, _ := strings.Cut(body, " ")
+	assert.Equal(t, "usr_1", userId)
+	require.NotEmpty(t, sessionId)
+
+	resp, err := browser.Post(app.URL+"/auth/logout", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	logoutUrl, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.NotEmpty(t, logoutUrl.Query().Get("id_token_hint"))
+	assert.Equal(t, app.URL+"/", logoutUrl.Query().Get("post_logout_redirect_uri"))
+	status, _ = me()
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	// Logging out takes a POST, and clears a session cookie that cannot be
+	// opened.
+	assert.Equal(t, http.StatusMethodNotAllowed, get(app.URL+"/auth/logout").StatusCode)
+	appUrl, err := url.Parse(app.URL)
+	require.NoError(t, err)
+	jar.SetCookies(appUrl, []*http.Cookie{{Name: scalekit.DefaultSessionCookieName, Value: "corrupt"}})
+	resp, err = browser.Post(app.URL+"/auth/logout", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Empty(t, jar.Cookies(appUrl))
+	details, err := client.Session().GetSession(ctx, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, "revoked", details.GetStatus())
+
+	// Return paths on other sites are replaced by the default.
+	assert.Equal(t, "/", login("return_to=https://evil.example.com/"))
+
+	// A callback without a login started by this browser is rejected.
+	resp = get(app.URL + "/auth/callback?" + url.Values{"code": {"skcode_x"}, "state": {"forged"}}.Encode())
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
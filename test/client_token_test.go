@@ -0,0 +1,101 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newClientTokenServer returns a fake Scalekit server issuing tokens that
+// live for lifetime, with counters of issued tokens and of the distinct
+// tokens RPCs were sent with.
+func newClientTokenServer(t *testing.T, lifetime time.Duration) (*scalekittest.Server, *atomic.Int32, func() int) {
+	t.Helper()
+	server := scalekittest.NewServer(t)
+	server.SetAccessTokenLifetime(lifetime)
+	var tokenCount atomic.Int32
+	var mu sync.Mutex
+	used := map[string]bool{}
+	server.Intercept(func(_ http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/oauth/token" {
+			tokenCount.Add(1)
+		} else if authorization := r.Header.Get("Authorization"); authorization != "" {
+			mu.Lock()
+			used[authorization] = true
+			mu.Unlock()
+		}
+		return false
+	})
+	return server, &tokenCount, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(used)
+	}
+}
+
+func TestClientTokenRefreshBeforeExpiry(t *testing.T) {
+	tests := []struct {
+		name       string
+		lifetime   time.Duration
+		opts       []any
+		wantTokens int32
+	}{
+		{
+			name:       "long-lived token is reused",
+			lifetime:   time.Hour,
+			wantTokens: 1,
+		},
+		{
+			name:       "token within skew is refreshed before each call",
+			lifetime:   30 * time.Second,
+			wantTokens: 3,
+		},
+		{
+			name:       "custom skew keeps short-lived token",
+			lifetime:   30 * time.Second,
+			opts:       []any{scalekit.WithTokenRefreshSkew(10 * time.Second)},
+			wantTokens: 1,
+		},
+		{
+			name:       "token without expires_in is reused",
+			lifetime:   0,
+			wantTokens: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, tokenCount, usedTokens := newClientTokenServer(t, tt.lifetime)
+			client := server.Client(tt.opts...)
+
+			for i := 0; i < 3; i++ {
+				_, err := client.Organization().ListOrganization(context.Background(), nil)
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantTokens, tokenCount.Load())
+			assert.Equal(t, int(tt.wantTokens), usedTokens(), "calls should always be sent with the latest token")
+		})
+	}
+}
+
+func TestClientTokenBackgroundRefresh(t *testing.T) {
+	// 90s is past the 60s skew but within twice the skew, so the token is used
+	// as-is and refreshed in the background.
+	server, tokenCount, _ := newClientTokenServer(t, 90*time.Second)
+	client := server.Client()
+
+	_, err := client.Organization().ListOrganization(context.Background(), nil)
+	require.NoError(t, err)
+	_, err = client.Organization().ListOrganization(context.Background(), nil)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return tokenCount.Load() >= 2 }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,119 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	organizationsv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/organizations"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/organizations/organizationsconnect"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const watchProcedure = "/test.v1.WatchService/Watch"
+
+// claimsOrganizationService echoes the caller's subject as the organization name.
+type claimsOrganizationService struct {
+	organizationsconnect.UnimplementedOrganizationServiceHandler
+}
+
+func (claimsOrganizationService) GetOrganization(ctx context.Context, _ *connect.Request[organizationsv1.GetOrganizationRequest]) (*connect.Response[organizationsv1.GetOrganizationResponse], error) {
+	name := "anonymous"
+	if claims, ok := scalekit.AccessTokenClaimsFromContext(ctx); ok {
+		name = claims.Sub
+	}
+	return connect.NewResponse(&organizationsv1.GetOrganizationResponse{Organization: &organizationsv1.Organization{DisplayName: name}}), nil
+}
+
+func (claimsOrganizationService) CreateOrganization(context.Context, *connect.Request[organizationsv1.CreateOrganizationRequest]) (*connect.Response[organizationsv1.CreateOrganizationResponse], error) {
+	return connect.NewResponse(&organizationsv1.CreateOrganizationResponse{}), nil
+}
+
+// newAuthInterceptorServer serves an app protected by an auth interceptor
+// that trusts the tokens of a fake Scalekit server, and returns that server's
+// signer with the app server.
+func newAuthInterceptorServer(t *testing.T, options scalekit.AuthInterceptorOptions) (*scalekittest.Signer, *httptest.Server) {
+	t.Helper()
+	fake := scalekittest.NewServer(t)
+	signer, client := fake.Signer(), fake.Client()
+	interceptor := connect.WithInterceptors(scalekit.NewAuthInterceptor(client, options))
+
+	mux := http.NewServeMux()
+	mux.Handle(organizationsconnect.NewOrganizationServiceHandler(claimsOrganizationService{}, interceptor))
+	mux.Handle(watchProcedure, connect.NewServerStreamHandler(watchProcedure,
+		func(ctx context.Context, _ *connect.Request[emptypb.Empty], stream *connect.ServerStream[wrapperspb.StringValue]) error {
+			claims, _ := scalekit.AccessTokenClaimsFromContext(ctx)
+			return stream.Send(wrapperspb.String(claims.Sub))
+		}, interceptor))
+	server := httptest.NewUnstartedServer(mux)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return signer, server
+}
+
+func TestAuthInterceptorUnary(t *testing.T) {
+	ctx := context.Background()
+	signer, server := newAuthInterceptorServer(t, scalekit.AuthInterceptorOptions{
+		ValidateTokenOptions: scalekit.ValidateTokenOptions{Scopes: []string{"orgs"}},
+		Procedures: map[string]scalekit.ProcedureRequirements{
+			organizationsconnect.OrganizationServiceCreateOrganizationProcedure: {Permissions: []string{"orgs:create"}},
+		},
+		PublicProcedures: []string{organizationsconnect.OrganizationServiceGetOrganizationProcedure},
+	})
+	client := organizationsconnect.NewOrganizationServiceClient(server.Client(), server.URL, connect.WithGRPC())
+	mint := func(opts ...scalekittest.TokenOption) string {
+		token, err := signer.MintAccessToken(scalekit.AccessTokenClaims{Sub: "usr_1"}, opts...)
+		require.NoError(t, err)
+		return token
+	}
+	create := func(token string) error {
+		req := connect.NewRequest(&organizationsv1.CreateOrganizationRequest{})
+		if token != "" {
+			req.Header().Set("Authorization", "Bearer "+token)
+		}
+		_, err := client.CreateOrganization(ctx, req)
+		return err
+	}
+
+	// Public procedures run without a token.
+	resp, err := client.GetOrganization(ctx, connect.NewRequest(&organizationsv1.GetOrganizationRequest{}))
+	require.NoError(t, err)
+	assert.Equal(t, "anonymous", resp.Msg.Organization.DisplayName)
+
+	assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(create("")))
+	assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(create("not-a-jwt")))
+	assert.Equal(t, connect.CodePermissionDenied, connect.CodeOf(create(mint(scalekittest.WithScopes("profile")))))
+	assert.Equal(t, connect.CodePermissionDenied, connect.CodeOf(create(mint(scalekittest.WithScopes("orgs")))))
+	assert.NoError(t, create(mint(
+		scalekittest.WithScopes("orgs"),
+		scalekittest.WithClaims(map[string]any{"permissions": []string{"orgs:create"}}),
+	)))
+}
+
+func TestAuthInterceptorStreaming(t *testing.T) {
+	ctx := context.Background()
+	signer, server := newAuthInterceptorServer(t, scalekit.AuthInterceptorOptions{})
+	client := connect.NewClient[emptypb.Empty, wrapperspb.StringValue](server.Client(), server.URL+watchProcedure, connect.WithGRPC())
+
+	stream, err := client.CallServerStream(ctx, connect.NewRequest(&emptypb.Empty{}))
+	require.NoError(t, err)
+	assert.False(t, stream.Receive())
+	assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(stream.Err()))
+
+	token, err := signer.MintAccessToken(scalekit.AccessTokenClaims{Sub: "usr_1"})
+	require.NoError(t, err)
+	req := connect.NewRequest(&emptypb.Empty{})
+	req.Header().Set("Authorization", "Bearer "+token)
+	stream, err = client.CallServerStream(ctx, req)
+	require.NoError(t, err)
+	require.True(t, stream.Receive(), "unexpected error: %v", stream.Err())
+	assert.Equal(t, "usr_1", stream.Msg().Value)
+}
@@ -0,0 +1,152 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// onDevicePoll calls fn with the number of each device_code token request
+// before the server answers it. fn may answer the request itself and return
+// true.
+func onDevicePoll(server *scalekittest.Server, fn func(n int, w http.ResponseWriter) bool) {
+	var polls atomic.Int32
+	server.Intercept(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != "/oauth/token" || r.PostFormValue("grant_type") != string(scalekit.GrantTypeDeviceCode) {
+			return false
+		}
+		return fn(int(polls.Add(1)), w)
+	})
+}
+
+func TestDeviceAuthorization(t *testing.T) {
+	ctx := context.Background()
+	user := scalekit.IdTokenClaims{Id: "usr_1"}
+
+	// start requests a device authorization polled every 10ms.
+	start := func(t *testing.T, client scalekit.DeviceAuthorizer) *scalekit.DeviceAuthorization {
+		t.Helper()
+		authorization, err := client.RequestDeviceAuthorization(ctx, scalekit.DeviceAuthorizationOptions{})
+		require.NoError(t, err)
+		authorization.Interval = 10 * time.Millisecond
+		return authorization
+	}
+
+	t.Run("authorization", func(t *testing.T) {
+		server := scalekittest.NewServer(t)
+		var scope string
+		server.Intercept(func(_ http.ResponseWriter, r *http.Request) bool {
+			if r.URL.Path == "/oauth/device/code" {
+				scope = r.PostFormValue("scope")
+			}
+			return false
+		})
+
+		authorization, err := server.Client().(scalekit.DeviceAuthorizer).RequestDeviceAuthorization(ctx, scalekit.DeviceAuthorizationOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "openid profile email offline_access", scope)
+		assert.NotEmpty(t, authorization.DeviceCode)
+		assert.NotEmpty(t, authorization.UserCode)
+		assert.Equal(t, server.URL+"/device", authorization.VerificationUri)
+		assert.Equal(t, 5*time.Second, authorization.Interval)
+		assert.WithinDuration(t, time.Now().Add(10*time.Minute), authorization.ExpiresAt, 5*time.Second)
+	})
+	t.Run("no expiry", func(t *testing.T) {
+		server := scalekittest.NewServer(t)
+		server.Intercept(func(w http.ResponseWriter, r *http.Request) bool {
+			if r.URL.Path != "/oauth/device/code" {
+				return false
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"device_code":"dc_1","user_code":"ABCD-EFGH","verification_uri":"https://auth.example.com/device"}`))
+			return true
+		})
+
+		authorization, err := server.Client().(scalekit.DeviceAuthorizer).RequestDeviceAuthorization(ctx, scalekit.DeviceAuthorizationOptions{})
+		require.NoError(t, err)
+		assert.True(t, authorization.ExpiresAt.IsZero())
+	})
+	t.Run("approved", func(t *testing.T) {
+		server := scalekittest.NewServer(t)
+		client := server.Client().(scalekit.DeviceAuthorizer)
+		authorization := start(t, client)
+		onDevicePoll(server, func(n int, _ http.ResponseWriter) bool {
+			if n == 3 {
+				require.NoError(t, server.ApproveDevice(authorization.UserCode, user))
+			}
+			return false
+		})
+
+		resp, err := client.PollDeviceToken(ctx, authorization)
+		require.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+		assert.NotEmpty(t, resp.RefreshToken)
+		require.NotNil(t, resp.IdTokenClaims)
+		assert.Equal(t, "usr_1", resp.IdTokenClaims.Id)
+	})
+	t.Run("denied", func(t *testing.T) {
+		server := scalekittest.NewServer(t)
+		client := server.Client().(scalekit.DeviceAuthorizer)
+		authorization := start(t, client)
+		onDevicePoll(server, func(n int, _ http.ResponseWriter) bool {
+			if n == 2 {
+				require.NoError(t, server.DenyDevice(authorization.UserCode))
+			}
+			return false
+		})
+
+		_, err := client.PollDeviceToken(ctx, authorization)
+		assert.True(t, errors.Is(err, scalekit.ErrDeviceAccessDenied), "unexpected error: %v", err)
+	})
+	t.Run("expired", func(t *testing.T) {
+		server := scalekittest.NewServer(t)
+		client := server.Client().(scalekit.DeviceAuthorizer)
+		authorization := start(t, client)
+		authorization.ExpiresAt = time.Now().Add(25 * time.Millisecond)
+
+		_, err := client.PollDeviceToken(ctx, authorization)
+		assert.True(t, errors.Is(err, scalekit.ErrDeviceCodeExpired), "unexpected error: %v", err)
+	})
+	t.Run("canceled", func(t *testing.T) {
+		server := scalekittest.NewServer(t)
+		client := server.Client().(scalekit.DeviceAuthorizer)
+		authorization := start(t, client)
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := client.PollDeviceToken(canceled, authorization)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+	t.Run("slow down", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("slow_down waits five seconds")
+		}
+		server := scalekittest.NewServer(t)
+		client := server.Client().(scalekit.DeviceAuthorizer)
+		authorization := start(t, client)
+		require.NoError(t, server.ApproveDevice(authorization.UserCode, user))
+		onDevicePoll(server, func(n int, w http.ResponseWriter) bool {
+			if n > 1 {
+				return false
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"slow_down"}`))
+			return true
+		})
+
+		started := time.Now()
+		resp, err := client.PollDeviceToken(ctx, authorization)
+		require.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+		assert.GreaterOrEqual(t, time.Since(started), 5*time.Second)
+	})
+}
@@ -0,0 +1,252 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newDiscoveryTestServer returns a fake Scalekit server whose provider
+// metadata advertises endpoints under /custom, with counters of the requests
+// to each path.
+func newDiscoveryTestServer(t *testing.T) (*scalekittest.Server, map[string]*atomic.Int32) {
+	t.Helper()
+	server := scalekittest.NewServer(t)
+	hits := map[string]*atomic.Int32{}
+	for _, path := range []string{"/.well-known/openid-configuration", "/custom/keys", "/custom/token", "/keys", "/oauth/token"} {
+		hits[path] = &atomic.Int32{}
+	}
+	server.Intercept(func(w http.ResponseWriter, r *http.Request) bool {
+		if hit, ok := hits[r.URL.Path]; ok {
+			hit.Add(1)
+		}
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(scalekit.ProviderMetadata{
+				Issuer:                           server.URL,
+				AuthorizationEndpoint:            server.URL + "/custom/authorize",
+				TokenEndpoint:                    server.URL + "/custom/token",
+				JwksUri:                          server.URL + "/custom/keys",
+				UserinfoEndpoint:                 server.URL + "/custom/userinfo",
+				IdTokenSigningAlgValuesSupported: []string{"RS256", "HS256"},
+			})
+			return true
+		case "/custom/keys", "/custom/token":
+			r.URL.Path = strings.Replace(r.URL.Path, "/custom/token", "/oauth/token", 1)
+			r.URL.Path = strings.Replace(r.URL.Path, "/custom/keys", "/keys", 1)
+		}
+		return false
+	})
+	return server, hits
+}
+
+// serveProviderMetadata makes server answer discovery requests with metadata.
+func serveProviderMetadata(server *scalekittest.Server, metadata scalekit.ProviderMetadata) {
+	server.Intercept(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != "/.well-known/openid-configuration" {
+			return false
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(metadata)
+		return true
+	})
+}
+
+func TestDiscovery(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("uses discovered endpoints and issuer", func(t *testing.T) {
+		server, hits := newDiscoveryTestServer(t)
+		client := server.Client(scalekit.WithDiscovery())
+
+		// Building URLs does not fetch the metadata, so the default paths are
+		// used until another call has fetched it.
+		authUrl, err := client.GetAuthorizationUrl("https://app.example.com/callback", scalekit.AuthorizationUrlOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, server.URL+"/oauth/authorize", authUrl.Scheme+"://"+authUrl.Host+authUrl.Path)
+		assert.Zero(t, hits["/.well-known/openid-configuration"].Load())
+
+		_, err = client.GetClientAccessToken(ctx)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, hits["/custom/token"].Load())
+		assert.Zero(t, hits["/oauth/token"].Load())
+
+		authUrl, err = client.GetAuthorizationUrl("https://app.example.com/callback", scalekit.AuthorizationUrlOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, server.URL+"/custom/authorize", authUrl.Scheme+"://"+authUrl.Host+authUrl.Path)
+
+		logoutUrl, err := client.GetLogoutUrl(scalekit.LogoutUrlOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, server.URL+"/oidc/logout", logoutUrl.String())
+
+		token, err := server.Signer().MintAccessToken(scalekit.AccessTokenClaims{Sub: "usr_1"})
+		require.NoError(t, err)
+		claims, err := client.GetAccessTokenClaims(ctx, token)
+		require.NoError(t, err)
+		assert.Equal(t, server.URL, claims.Iss)
+		assert.EqualValues(t, 1, hits["/custom/keys"].Load())
+		assert.Zero(t, hits["/keys"].Load())
+
+		metadata, err := client.(scalekit.ProviderMetadataFetcher).GetProviderMetadata(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, server.URL+"/custom/userinfo", metadata.UserinfoEndpoint)
+		metadata.IdTokenSigningAlgValuesSupported[0] = "none"
+		metadata, err = client.(scalekit.ProviderMetadataFetcher).GetProviderMetadata(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"RS256", "HS256"}, metadata.IdTokenSigningAlgValuesSupported)
+		assert.EqualValues(t, 1, hits["/.well-known/openid-configuration"].Load())
+	})
+
+	t.Run("configured issuer takes precedence", func(t *testing.T) {
+		server, _ := newDiscoveryTestServer(t)
+		client := server.Client(scalekit.WithDiscovery(), scalekit.WithIssuer("https://other.example.com"))
+		token, err := server.Signer().MintAccessToken(scalekit.AccessTokenClaims{Sub: "usr_1"})
+		require.NoError(t, err)
+		_, err = client.GetAccessTokenClaims(ctx, token)
+		assert.ErrorIs(t, err, scalekit.ErrInvalidIssuer)
+	})
+
+	t.Run("rejects metadata of another issuer", func(t *testing.T) {
+		server := scalekittest.NewServer(t)
+		serveProviderMetadata(server, scalekit.ProviderMetadata{
+			Issuer:        "https://auth.example.com",
+			TokenEndpoint: "https://auth.example.com/oauth/token",
+		})
+		client := server.Client(scalekit.WithDiscovery())
+		_, err := client.(scalekit.ProviderMetadataFetcher).GetProviderMetadata(ctx)
+		assert.ErrorIs(t, err, scalekit.ErrInvalidProviderMetadata)
+
+		// The endpoints of the rejected metadata are not used.
+		_, err = client.GetClientAccessToken(ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("accepts an issuer with a trailing slash", func(t *testing.T) {
+		server := scalekittest.NewServer(t)
+		serveProviderMetadata(server, scalekit.ProviderMetadata{Issuer: server.URL + "/"})
+		client := server.Client(scalekit.WithDiscovery())
+		metadata, err := client.(scalekit.ProviderMetadataFetcher).GetProviderMetadata(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, server.URL+"/", metadata.Issuer)
+	})
+
+	t.Run("accepts metadata of the configured issuer", func(t *testing.T) {
+		server := scalekittest.NewServer(t)
+		serveProviderMetadata(server, scalekit.ProviderMetadata{
+			Issuer:        "https://auth.example.com/",
+			TokenEndpoint: server.URL + "/oauth/token",
+		})
+		client := server.Client(scalekit.WithDiscovery(), scalekit.WithIssuer("https://auth.example.com"))
+		metadata, err := client.(scalekit.ProviderMetadataFetcher).GetProviderMetadata(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "https://auth.example.com/", metadata.Issuer)
+		_, err = client.GetClientAccessToken(ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("ignores endpoints on other hosts", func(t *testing.T) {
+		var requests atomic.Int32
+		other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			http.Error(w, "unexpected request", http.StatusTeapot)
+		}))
+		t.Cleanup(other.Close)
+		server := scalekittest.NewServer(t)
+		serveProviderMetadata(server, scalekit.ProviderMetadata{
+			Issuer:        server.URL,
+			TokenEndpoint: other.URL + "/oauth/token",
+			JwksUri:       other.URL + "/keys",
+		})
+		client := server.Client(scalekit.WithDiscovery())
+		_, err := client.GetClientAccessToken(ctx)
+		require.NoError(t, err)
+		token, err := server.Signer().MintAccessToken(scalekit.AccessTokenClaims{Sub: "usr_1"})
+		require.NoError(t, err)
+		_, err = client.GetAccessTokenClaims(ctx, token)
+		require.NoError(t, err)
+		assert.Zero(t, requests.Load())
+	})
+
+	t.Run("sends the access token only to the environment", func(t *testing.T) {
+		var authorization atomic.Value
+		issuer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization.Store(r.Header.Get("Authorization"))
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}))
+		t.Cleanup(issuer.Close)
+		server := scalekittest.NewServer(t)
+		serveProviderMetadata(server, scalekit.ProviderMetadata{
+			Issuer:  issuer.URL,
+			JwksUri: issuer.URL + "/keys",
+		})
+		client := server.Client(scalekit.WithDiscovery(), scalekit.WithIssuer(issuer.URL))
+		_, err := client.GetClientAccessToken(ctx)
+		require.NoError(t, err)
+		token, err := server.Signer().MintAccessToken(scalekit.AccessTokenClaims{Iss: issuer.URL, Sub: "usr_1"})
+		require.NoError(t, err)
+		_, err = client.GetAccessTokenClaims(ctx, token)
+		require.Error(t, err)
+		assert.Equal(t, "", authorization.Load())
+	})
+
+	t.Run("uses default endpoints without discovery", func(t *testing.T) {
+		server, hits := newDiscoveryTestServer(t)
+		client := server.Client()
+		_, err := client.GetClientAccessToken(ctx)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, hits["/oauth/token"].Load())
+		assert.Zero(t, hits["/.well-known/openid-configuration"].Load())
+
+		metadata, err := client.(scalekit.ProviderMetadataFetcher).GetProviderMetadata(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, server.URL+"/custom/token", metadata.TokenEndpoint)
+	})
+
+	t.Run("falls back to default endpoints", func(t *testing.T) {
+		server := scalekittest.NewServer(t)
+		var fetches atomic.Int32
+		server.Intercept(func(w http.ResponseWriter, r *http.Request) bool {
+			if r.URL.Path != "/.well-known/openid-configuration" {
+				return false
+			}
+			fetches.Add(1)
+			http.NotFound(w, r)
+			return true
+		})
+		client := server.Client(scalekit.WithDiscovery())
+
+		authUrl, err := client.GetAuthorizationUrl("https://app.example.com/callback", scalekit.AuthorizationUrlOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, server.URL+"/oauth/authorize", authUrl.Scheme+"://"+authUrl.Host+authUrl.Path)
+		_, err = client.GetClientAccessToken(ctx)
+		require.NoError(t, err)
+		_, err = client.(scalekit.ProviderMetadataFetcher).GetProviderMetadata(ctx)
+		var sdkErr *scalekit.Error
+		require.ErrorAs(t, err, &sdkErr)
+		assert.Equal(t, http.StatusNotFound, sdkErr.StatusCode)
+		assert.EqualValues(t, 1, fetches.Load())
+	})
+
+	t.Run("fake server serves its metadata", func(t *testing.T) {
+		server := scalekittest.NewServer(t)
+		client := server.Client(scalekit.WithDiscovery())
+		metadata, err := client.(scalekit.ProviderMetadataFetcher).GetProviderMetadata(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, server.URL, metadata.Issuer)
+		token, err := server.Signer().MintAccessToken(scalekit.AccessTokenClaims{Sub: "usr_1"})
+		require.NoError(t, err)
+		valid, err := client.ValidateAccessToken(ctx, token)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+}
@@ -0,0 +1,127 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/errdetails"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/organizations/organizationsconnect"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRPCErrorDetails(t *testing.T) {
+	rpcErr := connect.NewError(connect.CodeInvalidArgument, errors.New("invalid organization"))
+	detail, err := connect.NewErrorDetail(&errdetails.ErrorInfo{
+		ErrorCode: "INVALID_ORGANIZATION",
+		RequestInfo: &errdetails.RequestInfo{
+			RequestId: "req_123",
+		},
+		ValidationErrorInfo: &errdetails.ValidationErrorInfo{
+			FieldViolations: []*errdetails.ValidationErrorInfo_FieldViolation{
+				{Field: "display_name", Description: "must not be empty", Constraint: "required"},
+			},
+		},
+		HelpInfo: &errdetails.HelpInfo{
+			Links: []*errdetails.HelpInfo_Link{{Description: "Organizations", Url: "https://docs.scalekit.com/apis"}},
+		},
+		LocalizedMessageInfo: &errdetails.LocalizedMessageInfo{Locale: "en", Message: "Organization name is required"},
+	})
+	require.NoError(t, err)
+	rpcErr.AddDetail(detail)
+
+	server := scalekittest.NewServer(t)
+	server.FailRPC(organizationsconnect.OrganizationServiceGetOrganizationProcedure, rpcErr)
+	client := server.Client()
+
+	_, err = client.Organization().GetOrganization(context.Background(), "org_123")
+	require.Error(t, err)
+
+	var sdkErr *scalekit.Error
+	require.True(t, errors.As(err, &sdkErr), "RPC errors should be *scalekit.Error, got %T", err)
+	assert.Equal(t, connect.CodeInvalidArgument, sdkErr.Code)
+	assert.Equal(t, http.StatusBadRequest, sdkErr.StatusCode)
+	assert.Equal(t, "invalid organization", sdkErr.Message)
+	assert.Equal(t, "INVALID_ORGANIZATION", sdkErr.Reason)
+	assert.Equal(t, "req_123", sdkErr.RequestID)
+	assert.Equal(t, "Organization name is required", sdkErr.LocalizedMessage)
+	assert.Equal(t, []scalekit.FieldViolation{{Field: "display_name", Description: "must not be empty", Constraint: "required"}}, sdkErr.Violations)
+	assert.Equal(t, []scalekit.HelpLink{{Description: "Organizations", URL: "https://docs.scalekit.com/apis"}}, sdkErr.HelpLinks)
+	assert.True(t, scalekit.IsInvalidArgument(err))
+
+	// The original Connect error is still reachable.
+	var connectErr *connect.Error
+	require.True(t, errors.As(err, &connectErr))
+	assert.Equal(t, connect.CodeInvalidArgument, connectErr.Code())
+}
+
+func TestErrorHelpers(t *testing.T) {
+	tests := []struct {
+		name       string
+		rpcCode    connect.Code
+		httpStatus int
+		check      func(error) bool
+	}{
+		{name: "not found", rpcCode: connect.CodeNotFound, httpStatus: http.StatusNotFound, check: scalekit.IsNotFound},
+		{name: "already exists", rpcCode: connect.CodeAlreadyExists, httpStatus: http.StatusConflict, check: scalekit.IsAlreadyExists},
+		{name: "permission denied", rpcCode: connect.CodePermissionDenied, httpStatus: http.StatusForbidden, check: scalekit.IsPermissionDenied},
+		{name: "rate limited", rpcCode: connect.CodeResourceExhausted, httpStatus: http.StatusTooManyRequests, check: scalekit.IsRateLimited},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" (rpc)", func(t *testing.T) {
+			server := scalekittest.NewServer(t)
+			server.FailRPC(organizationsconnect.OrganizationServiceGetOrganizationProcedure, connect.NewError(tt.rpcCode, errors.New(tt.name)))
+			client := server.Client(scalekit.WithRetryPolicy(scalekit.NoRetryPolicy()))
+
+			_, err := client.Organization().GetOrganization(context.Background(), "org_123")
+			require.Error(t, err)
+			assert.True(t, tt.check(err), "unexpected error: %v", err)
+			assert.False(t, scalekit.IsUnauthenticated(err))
+		})
+		t.Run(tt.name+" (http)", func(t *testing.T) {
+			server := scalekittest.NewServer(t)
+			server.Intercept(func(w http.ResponseWriter, r *http.Request) bool {
+				w.Header().Set("X-Request-Id", "req_456")
+				w.WriteHeader(tt.httpStatus)
+				_, _ = w.Write([]byte(`{"error":"some_error","error_description":"details"}`))
+				return true
+			})
+			client := server.Client(scalekit.WithRetryPolicy(scalekit.NoRetryPolicy()))
+
+			_, err := client.GetClientAccessToken(context.Background())
+			require.Error(t, err)
+			assert.True(t, tt.check(err), "unexpected error: %v", err)
+
+			var sdkErr *scalekit.Error
+			require.True(t, errors.As(err, &sdkErr))
+			assert.Equal(t, tt.rpcCode, sdkErr.Code)
+			assert.Equal(t, "some_error", sdkErr.Reason)
+			assert.Equal(t, "req_456", sdkErr.RequestID)
+		})
+	}
+}
+
+func TestRPCRateLimitedByProxy(t *testing.T) {
+	server := scalekittest.NewServer(t)
+	server.Intercept(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/oauth/token" {
+			return false
+		}
+		w.WriteHeader(http.StatusTooManyRequests)
+		return true
+	})
+	client := server.Client(scalekit.WithRetryPolicy(scalekit.NoRetryPolicy()))
+
+	_, err := client.Organization().GetOrganization(context.Background(), "org_123")
+	require.Error(t, err)
+	assert.True(t, scalekit.IsRateLimited(err))
+	var sdkErr *scalekit.Error
+	require.True(t, errors.As(err, &sdkErr))
+	assert.Equal(t, http.StatusTooManyRequests, sdkErr.StatusCode)
+}
@@ -0,0 +1,87 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticateWithCodeIdTokenBinding(t *testing.T) {
+	ctx := context.Background()
+	server := scalekittest.NewServer(t)
+	client := server.Client()
+	user := scalekit.IdTokenClaims{Id: "usr_1", Email: "jane@example.com"}
+
+	tests := []struct {
+		name    string
+		opts    []scalekittest.TokenOption
+		nonce   string
+		wantErr error
+	}{
+		{
+			name:  "matching nonce",
+			opts:  []scalekittest.TokenOption{scalekittest.WithClaims(map[string]any{"nonce": "n-0S6_WzA2Mj"})},
+			nonce: "n-0S6_WzA2Mj",
+		},
+		{
+			name:    "other nonce",
+			opts:    []scalekittest.TokenOption{scalekittest.WithClaims(map[string]any{"nonce": "replayed"})},
+			nonce:   "n-0S6_WzA2Mj",
+			wantErr: scalekit.ErrNonceMismatch,
+		},
+		{
+			name:    "missing nonce",
+			nonce:   "n-0S6_WzA2Mj",
+			wantErr: scalekit.ErrNonceMismatch,
+		},
+		{
+			name:    "other audience",
+			opts:    []scalekittest.TokenOption{scalekittest.WithAudience("skc_other")},
+			wantErr: scalekit.ErrAudienceMismatch,
+		},
+		{
+			name:    "several audiences without azp",
+			opts:    []scalekittest.TokenOption{scalekittest.WithAudience(server.ClientID, "skc_other")},
+			wantErr: scalekit.ErrInvalidAuthorizedParty,
+		},
+		{
+			name: "several audiences with azp",
+			opts: []scalekittest.TokenOption{
+				scalekittest.WithAudience(server.ClientID, "skc_other"),
+				scalekittest.WithClaims(map[string]any{"azp": server.ClientID}),
+			},
+		},
+		{
+			name:    "azp of another client",
+			opts:    []scalekittest.TokenOption{scalekittest.WithClaims(map[string]any{"azp": "skc_other"})},
+			wantErr: scalekit.ErrInvalidAuthorizedParty,
+		},
+		{
+			name:    "at_hash of another access token",
+			opts:    []scalekittest.TokenOption{scalekittest.WithClaims(map[string]any{"at_hash": "77QmUPtjPfzWtF2AnpK9RQ"})},
+			wantErr: scalekit.ErrAtHashMismatch,
+		},
+		{
+			name:    "c_hash of another code",
+			opts:    []scalekittest.TokenOption{scalekittest.WithClaims(map[string]any{"c_hash": "LDktKdoQak3Pk0cnXxCltA"})},
+			wantErr: scalekit.ErrCHashMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := server.IssueAuthorizationCode(user, tt.opts...)
+			resp, err := client.AuthenticateWithCode(ctx, code, "https://app.example.com/callback", scalekit.AuthenticationOptions{Nonce: tt.nonce})
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "unexpected error: %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "usr_1", resp.User.Id)
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/interceptors"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const preSignupPayload = `{
+	"id": "icpt_1",
+	"trigger_point": "PRE_SIGNUP",
+	"environment_id": "env_1",
+	"timestamp": "2026-10-17T09:30:00Z",
+	"interceptor_context": {
+		"user_email": "jane@example.com",
+		"connection_type": "OIDC",
+		"provider": "GOOGLE",
+		"ip_address": "203.0.113.7"
+	}
+}`
+
+func TestInterceptorResponse(t *testing.T) {
+	allow, err := json.Marshal(interceptors.Allow().WithClaim("tier", "gold").WithMetadata("plan", "pro"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"decision": "ALLOW",
+		"response": {"claims": {"tier": "gold"}, "user_metadata": {"plan": "pro"}}
+	}`, string(allow))
+
+	deny, err := json.Marshal(interceptors.Deny("Sign up with your work email."))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"decision": "DENY", "error": {"message": "Sign up with your work email."}}`, string(deny))
+}
+
+func TestInterceptorHandler(t *testing.T) {
+	client := scalekit.NewScalekitClient("https://example.scalekit.com", "client_id", "client_secret")
+	verifier := interceptors.SecretVerifier(client, testWebhookSecret)
+
+	serve := func(handler http.Handler, request *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	t.Run("encodes the decision", func(t *testing.T) {
+		handler := interceptors.NewHandler(func(ctx context.Context, req *interceptors.Request) (*interceptors.Response, error) {
+			_, hasDeadline := ctx.Deadline()
+			assert.True(t, hasDeadline)
+			assert.Equal(t, interceptors.PreSignup, req.TriggerPoint)
+			var signup interceptors.PreSignupContext
+			if err := req.Decode(&signup); err != nil {
+				return nil, err
+			}
+			assert.Equal(t, "203.0.113.7", signup.IpAddress)
+			if !strings.HasSuffix(signup.UserEmail, "@acme.com") {
+				return interceptors.Deny("Sign up with your Acme email address."), nil
+			}
+			return interceptors.Allow(), nil
+		}, interceptors.HandlerOptions{Verifier: verifier})
+
+		recorder := serve(handler, scalekittest.NewWebhookRequest("/interceptors", testWebhookSecret, "", []byte(preSignupPayload)))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"decision": "DENY", "error": {"message": "Sign up with your Acme email address."}}`, recorder.Body.String())
+	})
+
+	t.Run("rejects invalid calls", func(t *testing.T) {
+		var rejected []error
+		handler := interceptors.NewHandler(func(context.Context, *interceptors.Request) (*interceptors.Response, error) {
+			t.Error("decision function called for an invalid call")
+			return interceptors.Allow(), nil
+		}, interceptors.HandlerOptions{
+			Verifier:     verifier,
+			MaxBodyBytes: 1024,
+			OnError:      func(_ *http.Request, _ *interceptors.Request, err error) { rejected = append(rejected, err) },
+		})
+
+		wrongMethod := scalekittest.NewWebhookRequest("/interceptors", testWebhookSecret, "", []byte(preSignupPayload))
+		wrongMethod.Method = http.MethodGet
+		assert.Equal(t, http.StatusMethodNotAllowed, serve(handler, wrongMethod).Code)
+
+		missingHeaders := scalekittest.NewWebhookRequest("/interceptors", testWebhookSecret, "", []byte(preSignupPayload))
+		missingHeaders.Header.Del("webhook-signature")
+		assert.Equal(t, http.StatusBadRequest, serve(handler, missingHeaders).Code)
+
+		wrongSecret := scalekittest.NewWebhookRequest("/interceptors", "whsec_b3RoZXJzZWNyZXQ=", "", []byte(preSignupPayload))
+		assert.Equal(t, http.StatusUnauthorized, serve(handler, wrongSecret).Code)
+
+		notARequest := scalekittest.NewWebhookRequest("/interceptors", testWebhookSecret, "", []byte(`{"id": "icpt_2"}`))
+		assert.Equal(t, http.StatusBadRequest, serve(handler, notARequest).Code)
+
+		tooLarge := scalekittest.NewWebhookRequest("/interceptors", testWebhookSecret, "", []byte(strings.Repeat(" ", 2048)))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, serve(handler, tooLarge).Code)
+
+		assert.Len(t, rejected, 4)
+		assert.ErrorIs(t, rejected[0], scalekit.ErrMissingRequiredHeaders)
+		assert.ErrorIs(t, rejected[1], scalekit.ErrInvalidSignature)
+		assert.ErrorIs(t, rejected[2], interceptors.ErrInvalidRequest)
+	})
+
+	t.Run("fails without a decision", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		tests := []struct {
+			name   string
+			decide interceptors.DecisionFunc
+			status int
+			err    error
+		}{
+			{"error", func(context.Context, *interceptors.Request) (*interceptors.Response, error) {
+				return nil, errors.New("database unavailable")
+			}, http.StatusInternalServerError, nil},
+			{"no response", func(context.Context, *interceptors.Request) (*interceptors.Response, error) {
+				return nil, nil
+			}, http.StatusInternalServerError, nil},
+			{"timeout", func(context.Context, *interceptors.Request) (*interceptors.Response, error) {
+				<-release
+				return interceptors.Allow(), nil
+			}, http.StatusGatewayTimeout, context.DeadlineExceeded},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var failure error
+				handler := interceptors.NewHandler(tt.decide, interceptors.HandlerOptions{
+					Verifier: verifier,
+					Timeout:  50 * time.Millisecond,
+					OnError:  func(_ *http.Request, req *interceptors.Request, err error) { failure = err; assert.NotNil(t, req) },
+				})
+				recorder := serve(handler, scalekittest.NewWebhookRequest("/interceptors", testWebhookSecret, "", []byte(preSignupPayload)))
+				assert.Equal(t, tt.status, recorder.Code)
+				require.Error(t, failure)
+				if tt.err != nil {
+					assert.ErrorIs(t, failure, tt.err)
+				}
+
+				handler = interceptors.NewHandler(tt.decide, interceptors.HandlerOptions{
+					Verifier: verifier,
+					Timeout:  50 * time.Millisecond,
+					Fallback: interceptors.Allow(),
+				})
+				recorder = serve(handler, scalekittest.NewWebhookRequest("/interceptors", testWebhookSecret, "", []byte(preSignupPayload)))
+				assert.Equal(t, http.StatusOK, recorder.Code)
+				assert.JSONEq(t, `{"decision": "ALLOW"}`, recorder.Body.String())
+			})
+		}
+	})
+
+	t.Run("requires a verifier", func(t *testing.T) {
+		assert.PanicsWithValue(t, "interceptors: HandlerOptions.Verifier is required", func() {
+			interceptors.NewHandler(func(context.Context, *interceptors.Request) (*interceptors.Response, error) {
+				return interceptors.Allow(), nil
+			}, interceptors.HandlerOptions{})
+		})
+	})
+}
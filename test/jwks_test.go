@@ -0,0 +1,120 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countJwksFetches counts the requests to the keys endpoint of server and
+// answers them with cacheControl, if set.
+func countJwksFetches(server *scalekittest.Server, cacheControl string) *atomic.Int32 {
+	var fetches atomic.Int32
+	server.Intercept(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/keys" {
+			fetches.Add(1)
+			if cacheControl != "" {
+				w.Header().Set("Cache-Control", cacheControl)
+			}
+		}
+		return false
+	})
+	return &fetches
+}
+
+func mintJwksTestToken(t *testing.T, signer *scalekittest.Signer) string {
+	t.Helper()
+	token, err := signer.MintAccessToken(scalekit.AccessTokenClaims{Sub: "usr_123"})
+	require.NoError(t, err)
+	return token
+}
+
+func TestJwksRefetchOnUnknownKid(t *testing.T) {
+	server := scalekittest.NewServer(t)
+	fetches := countJwksFetches(server, "")
+	client := server.Client(scalekit.WithJWKSMinRefreshInterval(0))
+	ctx := context.Background()
+
+	_, err := client.GetAccessTokenClaims(ctx, mintJwksTestToken(t, server.Signer()))
+	require.NoError(t, err)
+	_, err = client.GetAccessTokenClaims(ctx, mintJwksTestToken(t, server.Signer()))
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), fetches.Load(), "key set should be served from cache")
+
+	require.NoError(t, server.Signer().Rotate())
+	_, err = client.GetAccessTokenClaims(ctx, mintJwksTestToken(t, server.Signer()))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fetches.Load(), "unknown kid should trigger one refetch")
+}
+
+func TestJwksRefetchOnUnknownKidIsRateLimited(t *testing.T) {
+	server := scalekittest.NewServer(t)
+	fetches := countJwksFetches(server, "")
+	unknown, err := scalekittest.NewSigner(server.URL)
+	require.NoError(t, err)
+	client := server.Client()
+	ctx := context.Background()
+
+	_, err = client.GetAccessTokenClaims(ctx, mintJwksTestToken(t, server.Signer()))
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = client.GetAccessTokenClaims(ctx, mintJwksTestToken(t, unknown))
+		require.Error(t, err)
+	}
+	assert.Equal(t, int32(1), fetches.Load(), "refetches for unknown kids within the min interval should be suppressed")
+}
+
+func TestJwksCacheTTL(t *testing.T) {
+	tests := []struct {
+		name         string
+		cacheControl string
+		opts         []any
+		wantFetches  int32
+	}{
+		{
+			name:        "default TTL serves from cache",
+			wantFetches: 1,
+		},
+		{
+			name:        "expired TTL refetches",
+			opts:        []any{scalekit.WithJWKSCacheTTL(50 * time.Millisecond)},
+			wantFetches: 2,
+		},
+		{
+			name:         "Cache-Control max-age shortens TTL",
+			cacheControl: "public, max-age=0",
+			opts:         []any{scalekit.WithJWKSMinRefreshInterval(0)},
+			wantFetches:  2,
+		},
+		{
+			name:         "Cache-Control max-age is floored by min refresh interval",
+			cacheControl: "max-age=0",
+			wantFetches:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := scalekittest.NewServer(t)
+			fetches := countJwksFetches(server, tt.cacheControl)
+			client := server.Client(tt.opts...)
+			ctx := context.Background()
+
+			_, err := client.ValidateToken(ctx, mintJwksTestToken(t, server.Signer()))
+			require.NoError(t, err)
+			// Wait well past the 50ms TTL and well short of the default TTL
+			// and min refresh interval, so that no case depends on timing.
+			time.Sleep(250 * time.Millisecond)
+			_, err = client.ValidateToken(ctx, mintJwksTestToken(t, server.Signer()))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFetches, fetches.Load())
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signJwsTestToken mints a token for usr_1 signed with key and algorithm.
+func signJwsTestToken(t *testing.T, key crypto.Signer, algorithm jose.SignatureAlgorithm, keyID string) string {
+	t.Helper()
+	signer, err := scalekittest.NewSigner("", scalekittest.WithSigningKey(key, algorithm), scalekittest.WithKeyID(keyID))
+	require.NoError(t, err)
+	token, err := signer.MintAccessToken(scalekit.AccessTokenClaims{Sub: "usr_1"})
+	require.NoError(t, err)
+	return token
+}
+
+func TestValidateTokenSigningAlgorithms(t *testing.T) {
+	ctx := context.Background()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	keySet := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: rsaKey.Public(), KeyID: "rsa", Use: "sig"},
+		{Key: ecKey.Public(), KeyID: "ec", Algorithm: string(jose.ES256), Use: "sig"},
+		{Key: edKey.Public(), KeyID: "ed", Use: "sig"},
+	}}
+	jwksFn := func(context.Context) (*jose.JSONWebKeySet, error) { return keySet, nil }
+
+	tests := []struct {
+		name    string
+		token   string
+		options *scalekit.ValidateTokenOptions
+		wantErr error
+	}{
+		{name: "RS256 derived from RSA key", token: signJwsTestToken(t, rsaKey, jose.RS256, "rsa")},
+		{name: "ES256 from key alg", token: signJwsTestToken(t, ecKey, jose.ES256, "ec")},
+		{name: "EdDSA derived from OKP key", token: signJwsTestToken(t, edKey, jose.EdDSA, "ed")},
+		{
+			name:    "PS256 not advertised",
+			token:   signJwsTestToken(t, rsaKey, jose.PS256, "rsa"),
+			wantErr: scalekit.ErrUnsupportedSigningAlgorithm,
+		},
+		{
+			name:    "PS256 allowed explicitly",
+			token:   signJwsTestToken(t, rsaKey, jose.PS256, "rsa"),
+			options: &scalekit.ValidateTokenOptions{SigningAlgorithms: []jose.SignatureAlgorithm{jose.PS256}},
+		},
+		{
+			name:    "RS256 restricted away",
+			token:   signJwsTestToken(t, rsaKey, jose.RS256, "rsa"),
+			options: &scalekit.ValidateTokenOptions{SigningAlgorithms: []jose.SignatureAlgorithm{jose.ES256}},
+			wantErr: scalekit.ErrUnsupportedSigningAlgorithm,
+		},
+		{
+			name:    "unknown kid",
+			token:   signJwsTestToken(t, ecKey, jose.ES256, "missing"),
+			wantErr: scalekit.ErrSigningKeyNotFound,
+		},
+		{
+			name:    "kid of a key with another algorithm",
+			token:   signJwsTestToken(t, rsaKey, jose.RS256, "ec"),
+			wantErr: scalekit.ErrSigningKeyNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := scalekit.ValidateTokenWithOptions[scalekit.AccessTokenClaims](ctx, tt.token, jwksFn, tt.options)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "unexpected error: %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "usr_1", claims.Sub)
+		})
+	}
+
+	// HMAC tokens are rejected before any key is looked up.
+	hmacSigner, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: []byte("0123456789abcdef0123456789abcdef")}, nil)
+	require.NoError(t, err)
+	signed, err := hmacSigner.Sign([]byte(`{"sub":"usr_1"}`))
+	require.NoError(t, err)
+	hmacToken, err := signed.CompactSerialize()
+	require.NoError(t, err)
+	_, err = scalekit.ValidateToken[scalekit.AccessTokenClaims](ctx, hmacToken, jwksFn)
+	assert.Error(t, err)
+}
+
+func TestWithSigningAlgorithms(t *testing.T) {
+	ctx := context.Background()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	server := scalekittest.NewServer(t)
+	server.Intercept(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != "/keys" {
+			return false
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: rsaKey.Public(), KeyID: "rsa", Use: "sig"}}})
+		return true
+	})
+	signer, err := scalekittest.NewSigner(server.URL, scalekittest.WithSigningKey(rsaKey, jose.PS256), scalekittest.WithKeyID("rsa"))
+	require.NoError(t, err)
+	token, err := signer.MintAccessToken(scalekit.AccessTokenClaims{Sub: "usr_1"})
+	require.NoError(t, err)
+
+	_, err = server.Client().GetAccessTokenClaims(ctx, token)
+	assert.True(t, errors.Is(err, scalekit.ErrUnsupportedSigningAlgorithm), "unexpected error: %v", err)
+
+	client := server.Client(scalekit.WithSigningAlgorithms(jose.RS256, jose.PS256, jose.HS256))
+	claims, err := client.GetAccessTokenClaims(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, "usr_1", claims.Sub)
+}
@@ -0,0 +1,149 @@
+package test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCookieSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// startLogin starts a login with flow and returns the authorization URL's
+// query and the cookies set for the browser.
+func startLogin(t *testing.T, flow *scalekit.LoginFlow, options scalekit.LoginOptions) (url.Values, []*http.Cookie) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	authUrl, err := flow.AuthorizationUrl(rec, httptest.NewRequest(http.MethodGet, "/login", nil), options)
+	require.NoError(t, err)
+	return authUrl.Query(), rec.Result().Cookies()
+}
+
+// loginCallback calls flow.Callback for a request to the callback URL with
+// query and cookies.
+func loginCallback(flow *scalekit.LoginFlow, query url.Values, cookies []*http.Cookie) (*scalekit.LoginResult, error) {
+	req := httptest.NewRequest(http.MethodGet, "/callback?"+query.Encode(), nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	return flow.Callback(httptest.NewRecorder(), req)
+}
+
+func TestLoginFlow(t *testing.T) {
+	server := scalekittest.NewServer(t)
+	flow, err := scalekit.NewLoginFlow(server.Client(), scalekit.LoginFlowOptions{
+		RedirectUri: "https://app.example.com/callback",
+		Secret:      testCookieSecret,
+	})
+	require.NoError(t, err)
+	user := scalekit.IdTokenClaims{Id: "usr_1", Email: "jane@example.com"}
+
+	authQuery, cookies := startLogin(t, flow, scalekit.LoginOptions{
+		AuthorizationUrlOptions: scalekit.AuthorizationUrlOptions{OrganizationId: "org_1"},
+		ReturnTo:                "/dashboard",
+	})
+	assert.Equal(t, "org_1", authQuery.Get("organization_id"))
+	assert.Equal(t, "S256", authQuery.Get("code_challenge_method"))
+	assert.NotEmpty(t, authQuery.Get("code_challenge"))
+	require.Len(t, cookies, 1)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.True(t, cookies[0].Secure)
+	assert.NotContains(t, cookies[0].Value, authQuery.Get("nonce"))
+
+	code := server.IssueAuthorizationCode(user, scalekittest.WithClaims(map[string]any{"nonce": authQuery.Get("nonce")}))
+	result, err := loginCallback(flow, url.Values{"code": {code}, "state": {authQuery.Get("state")}}, cookies)
+	require.NoError(t, err)
+	assert.Equal(t, "usr_1", result.User.Id)
+	assert.Equal(t, "/dashboard", result.ReturnTo)
+
+	t.Run("nonce of another login", func(t *testing.T) {
+		authQuery, cookies := startLogin(t, flow, scalekit.LoginOptions{})
+		code := server.IssueAuthorizationCode(user, scalekittest.WithClaims(map[string]any{"nonce": "other"}))
+		_, err := loginCallback(flow, url.Values{"code": {code}, "state": {authQuery.Get("state")}}, cookies)
+		assert.True(t, errors.Is(err, scalekit.ErrNonceMismatch), "unexpected error: %v", err)
+	})
+
+	t.Run("state of another browser", func(t *testing.T) {
+		startLogin(t, flow, scalekit.LoginOptions{})
+		attackerQuery, _ := startLogin(t, flow, scalekit.LoginOptions{})
+		_, err := loginCallback(flow, url.Values{"code": {"skcode_x"}, "state": {attackerQuery.Get("state")}}, nil)
+		assert.True(t, errors.Is(err, scalekit.ErrInvalidLoginState), "unexpected error: %v", err)
+	})
+
+	t.Run("tampered cookie", func(t *testing.T) {
+		authQuery, cookies := startLogin(t, flow, scalekit.LoginOptions{})
+		cookies[0].Value = "A" + cookies[0].Value[1:]
+		_, err := loginCallback(flow, url.Values{"code": {"skcode_x"}, "state": {authQuery.Get("state")}}, cookies)
+		assert.True(t, errors.Is(err, scalekit.ErrInvalidLoginState), "unexpected error: %v", err)
+	})
+
+	t.Run("cookie of another login", func(t *testing.T) {
+		_, cookies := startLogin(t, flow, scalekit.LoginOptions{})
+		otherQuery, otherCookies := startLogin(t, flow, scalekit.LoginOptions{})
+		otherCookies[0].Value = cookies[0].Value
+		_, err := loginCallback(flow, url.Values{"code": {"skcode_x"}, "state": {otherQuery.Get("state")}}, otherCookies)
+		assert.True(t, errors.Is(err, scalekit.ErrInvalidLoginState), "unexpected error: %v", err)
+	})
+
+	t.Run("authorization error", func(t *testing.T) {
+		authQuery, cookies := startLogin(t, flow, scalekit.LoginOptions{})
+		_, err := loginCallback(flow, url.Values{
+			"error":             {"access_denied"},
+			"error_description": {"user cancelled"},
+			"state":             {authQuery.Get("state")},
+		}, cookies)
+		var authErr *scalekit.AuthorizationError
+		require.True(t, errors.As(err, &authErr), "unexpected error: %v", err)
+		assert.Equal(t, "access_denied", authErr.Code)
+		assert.Equal(t, "user cancelled", authErr.Description)
+	})
+
+	t.Run("authorization error with invalid state", func(t *testing.T) {
+		_, err := loginCallback(flow, url.Values{
+			"error":             {"access_denied"},
+			"error_description": {"forged"},
+			"state":             {"unknown"},
+		}, nil)
+		assert.True(t, errors.Is(err, scalekit.ErrInvalidLoginState), "unexpected error: %v", err)
+	})
+
+	t.Run("open redirect", func(t *testing.T) {
+		_, err := flow.AuthorizationUrl(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/login", nil),
+			scalekit.LoginOptions{ReturnTo: "//evil.example.com/"})
+		assert.True(t, errors.Is(err, scalekit.ErrInvalidReturnTo), "unexpected error: %v", err)
+	})
+}
+
+func TestLoginFlowOptions(t *testing.T) {
+	server := scalekittest.NewServer(t)
+	_, err := scalekit.NewLoginFlow(server.Client(), scalekit.LoginFlowOptions{RedirectUri: "https://app.example.com/callback", Secret: []byte("short")})
+	assert.True(t, errors.Is(err, scalekit.ErrCookieSecretTooShort), "unexpected error: %v", err)
+
+	flow, err := scalekit.NewLoginFlow(server.Client(), scalekit.LoginFlowOptions{
+		RedirectUri: "https://app.example.com/callback",
+		Secret:      testCookieSecret,
+		MaxAge:      time.Nanosecond,
+	})
+	require.NoError(t, err)
+	authQuery, cookies := startLogin(t, flow, scalekit.LoginOptions{})
+	_, err = loginCallback(flow, url.Values{"code": {"skcode_x"}, "state": {authQuery.Get("state")}}, cookies)
+	assert.True(t, errors.Is(err, scalekit.ErrLoginStateExpired), "unexpected error: %v", err)
+
+	for target, local := range map[string]bool{
+		"/dashboard?tab=1":     true,
+		"/":                    true,
+		"//evil.example.com":   false,
+		"/\\evil.example.com":  false,
+		"https://evil.example": false,
+		"dashboard":            false,
+	} {
+		assert.Equal(t, local, scalekit.IsLocalRedirect(target), target)
+	}
+}
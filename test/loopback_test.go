@@ -0,0 +1,75 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loopbackBrowser returns an OpenBrowser function that returns to the
+// redirect URI with the query built by answer from the authorization URL.
+func loopbackBrowser(t *testing.T, answer func(authQuery url.Values) url.Values) func(string) error {
+	return func(authorizationUrl string) error {
+		authUrl, err := url.Parse(authorizationUrl)
+		require.NoError(t, err)
+		authQuery := authUrl.Query()
+		redirectUri := authQuery.Get("redirect_uri")
+		assert.True(t, strings.HasPrefix(redirectUri, "http://127.0.0.1:"), redirectUri)
+		assert.Equal(t, "S256", authQuery.Get("code_challenge_method"))
+
+		go func() {
+			// A request without the login's state is ignored.
+			resp, err := http.Get(redirectUri + "?code=forged&state=forged")
+			if assert.NoError(t, err) {
+				resp.Body.Close()
+				assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			}
+			resp, err = http.Get(redirectUri + "?" + answer(authQuery).Encode())
+			if assert.NoError(t, err) {
+				resp.Body.Close()
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+			}
+		}()
+		return nil
+	}
+}
+
+func TestLoginWithLoopback(t *testing.T) {
+	ctx := context.Background()
+	server := scalekittest.NewServer(t)
+	client := server.Client()
+
+	resp, err := scalekit.LoginWithLoopback(ctx, client, scalekit.LoopbackLoginOptions{
+		OpenBrowser: loopbackBrowser(t, func(authQuery url.Values) url.Values {
+			code := server.IssueAuthorizationCode(scalekit.IdTokenClaims{Id: "usr_1"},
+				scalekittest.WithClaims(map[string]any{"nonce": authQuery.Get("nonce")}))
+			return url.Values{"code": {code}, "state": {authQuery.Get("state")}}
+		}),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "usr_1", resp.User.Id)
+
+	_, err = scalekit.LoginWithLoopback(ctx, client, scalekit.LoopbackLoginOptions{
+		OpenBrowser: loopbackBrowser(t, func(authQuery url.Values) url.Values {
+			return url.Values{"error": {"access_denied"}, "state": {authQuery.Get("state")}}
+		}),
+	})
+	var authErr *scalekit.AuthorizationError
+	require.True(t, errors.As(err, &authErr), "unexpected error: %v", err)
+	assert.Equal(t, "access_denied", authErr.Code)
+
+	_, err = scalekit.LoginWithLoopback(ctx, client, scalekit.LoopbackLoginOptions{
+		OpenBrowser: func(string) error { return nil },
+		Timeout:     20 * time.Millisecond,
+	})
+	assert.ErrorIs(t, err, scalekit.ErrLoginTimeout)
+}
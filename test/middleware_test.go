@@ -0,0 +1,173 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const middlewareTestAudience = "https://api.example.com"
+
+func TestAuthMiddleware(t *testing.T) {
+	fake := scalekittest.NewServer(t)
+	signer, client := fake.Signer(), fake.Client()
+	mint := func(opts ...scalekittest.TokenOption) string {
+		token, err := signer.MintAccessToken(scalekit.AccessTokenClaims{Sub: "usr_1"}, opts...)
+		require.NoError(t, err)
+		return token
+	}
+
+	handler := scalekit.NewAuthMiddleware(client, scalekit.AuthMiddlewareOptions{
+		ValidateTokenOptions: scalekit.ValidateTokenOptions{
+			Audience: []string{middlewareTestAudience},
+			Scopes:   []string{"invoices:read"},
+		},
+		Realm: "invoices",
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := scalekit.AccessTokenClaimsFromContext(r.Context())
+		require.True(t, ok)
+		_, _ = w.Write([]byte(claims.Sub))
+	}))
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+		challenge     string
+	}{
+		{
+			name:          "valid token",
+			authorization: "Bearer " + mint(scalekittest.WithAudience(middlewareTestAudience), scalekittest.WithScopes("invoices:read")),
+			status:        http.StatusOK,
+		},
+		{
+			name:      "missing token",
+			status:    http.StatusUnauthorized,
+			challenge: `Bearer realm="invoices"`,
+		},
+		{
+			name:          "wrong scheme",
+			authorization: "Basic dXNlcjpwYXNz",
+			status:        http.StatusBadRequest,
+			challenge:     `Bearer realm="invoices", error="invalid_request", error_description="authorization header must use the Bearer scheme"`,
+		},
+		{
+			name:          "expired token",
+			authorization: "Bearer " + mint(scalekittest.WithAudience(middlewareTestAudience), scalekittest.WithExpiry(-time.Minute)),
+			status:        http.StatusUnauthorized,
+			challenge:     `Bearer realm="invoices", error="invalid_token", error_description="token has expired"`,
+		},
+		{
+			name:          "wrong audience",
+			authorization: "Bearer " + mint(scalekittest.WithAudience("https://other.example.com"), scalekittest.WithScopes("invoices:read")),
+			status:        http.StatusUnauthorized,
+			challenge:     `Bearer realm="invoices", error="invalid_token", error_description="none of the expected audiences found in token aud claim"`,
+		},
+		{
+			name:          "missing scope",
+			authorization: "Bearer " + mint(scalekittest.WithAudience(middlewareTestAudience), scalekittest.WithScopes("invoices:write")),
+			status:        http.StatusForbidden,
+			challenge:     `Bearer realm="invoices", error="insufficient_scope", error_description="missing expected scope 'invoices:read' in token scope claim", scope="invoices:read"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/invoices", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, tt.challenge, rec.Header().Get("WWW-Authenticate"))
+			if tt.status == http.StatusOK {
+				assert.Equal(t, "usr_1", rec.Body.String())
+			}
+		})
+	}
+}
+
+// basicScalekit hides the optional interfaces of the client it wraps, like a
+// test double implementing only Scalekit.
+type basicScalekit struct {
+	scalekit.Scalekit
+}
+
+func TestAuthMiddlewareWithoutOptionalInterfaces(t *testing.T) {
+	fake := scalekittest.NewServer(t)
+	client := basicScalekit{fake.Client()}
+	_, ok := scalekit.Scalekit(client).(scalekit.AccessTokenClaimsValidator)
+	require.False(t, ok)
+	handler := scalekit.NewAuthMiddleware(client, scalekit.AuthMiddlewareOptions{
+		ValidateTokenOptions: scalekit.ValidateTokenOptions{Audience: []string{middlewareTestAudience}},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := scalekit.AccessTokenClaimsFromContext(r.Context())
+		_, _ = w.Write([]byte(claims.Sub))
+	}))
+
+	for audience, status := range map[string]int{middlewareTestAudience: http.StatusOK, "https://other.example.com": http.StatusUnauthorized} {
+		token, err := fake.Signer().MintAccessToken(scalekit.AccessTokenClaims{Sub: "usr_1"}, scalekittest.WithAudience(audience))
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, status, rec.Code, audience)
+		if status == http.StatusOK {
+			assert.Equal(t, "usr_1", rec.Body.String())
+		}
+	}
+}
+
+func TestAuthMiddlewareExtractors(t *testing.T) {
+	fake := scalekittest.NewServer(t)
+	signer, client := fake.Signer(), fake.Client()
+	token, err := signer.MintAccessToken(scalekit.AccessTokenClaims{Sub: "usr_1"})
+	require.NoError(t, err)
+
+	handler := scalekit.NewAuthMiddleware(client, scalekit.AuthMiddlewareOptions{
+		Extractors: []scalekit.TokenExtractor{
+			scalekit.TokenFromAuthorizationHeader,
+			scalekit.TokenFromCookie("access_token"),
+			scalekit.TokenFromQuery("access_token"),
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/?access_token="+token, nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestGetAccessTokenClaimsWithOptionsErrors(t *testing.T) {
+	ctx := context.Background()
+	fake := scalekittest.NewServer(t)
+	signer, client := fake.Signer(), fake.Client().(scalekit.AccessTokenClaimsValidator)
+	token, err := signer.MintAccessToken(scalekit.AccessTokenClaims{Sub: "usr_1"}, scalekittest.WithAudience(middlewareTestAudience))
+	require.NoError(t, err)
+
+	claims, err := client.GetAccessTokenClaimsWithOptions(ctx, token, &scalekit.ValidateTokenOptions{Audience: []string{middlewareTestAudience}})
+	require.NoError(t, err)
+	assert.Equal(t, "usr_1", claims.Sub)
+
+	_, err = client.GetAccessTokenClaimsWithOptions(ctx, token, &scalekit.ValidateTokenOptions{Audience: []string{"https://other.example.com"}})
+	assert.True(t, errors.Is(err, scalekit.ErrAudienceMismatch), "unexpected error: %v", err)
+
+	_, err = client.GetAccessTokenClaimsWithOptions(ctx, token, &scalekit.ValidateTokenOptions{Scopes: []string{"invoices:read"}})
+	assert.True(t, errors.Is(err, scalekit.ErrInsufficientScope), "unexpected error: %v", err)
+	assert.EqualError(t, err, "token missing scope claim")
+}
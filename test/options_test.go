@@ -0,0 +1,106 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/organizations/organizationsconnect"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTransport records the path of every request it forwards.
+type recordingTransport struct {
+	base  http.RoundTripper
+	mu    sync.Mutex
+	paths []string
+}
+
+func (r *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	r.paths = append(r.paths, req.URL.Path)
+	r.mu.Unlock()
+	return r.base.RoundTrip(req)
+}
+
+func TestWithTransportIsUsedForAllRequests(t *testing.T) {
+	server := scalekittest.NewServer(t)
+	transport := &recordingTransport{base: server.HTTPClient().Transport}
+	client := scalekit.NewScalekitClient(server.URL, server.ClientID, server.ClientSecret, scalekit.WithTransport(transport))
+
+	_, err := client.Organization().ListOrganization(context.Background(), nil)
+	require.NoError(t, err)
+	_, err = client.GetClientAccessToken(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"/oauth/token",
+		organizationsconnect.OrganizationServiceListOrganizationProcedure,
+		"/oauth/token",
+	}, transport.paths)
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	server := scalekittest.NewServer(t)
+
+	// The default client does not trust the test server's certificate.
+	defaultClient := scalekit.NewScalekitClient(server.URL, server.ClientID, server.ClientSecret)
+	_, err := defaultClient.Organization().ListOrganization(context.Background(), nil)
+	require.Error(t, err)
+
+	client := scalekit.NewScalekitClient(server.URL, server.ClientID, server.ClientSecret, scalekit.WithHTTPClient(server.HTTPClient()))
+	_, err = client.Organization().ListOrganization(context.Background(), nil)
+	require.NoError(t, err)
+	_, err = client.GetClientAccessToken(context.Background())
+	require.NoError(t, err)
+}
+
+func TestWithUserAgentSuffixAndAPIVersion(t *testing.T) {
+	server := scalekittest.NewServer(t)
+	var headers sync.Map
+	server.Intercept(func(_ http.ResponseWriter, r *http.Request) bool {
+		headers.Store(r.URL.Path, r.Header.Clone())
+		return false
+	})
+	client := server.Client(
+		scalekit.WithUserAgentSuffix("acme-billing/1.2"),
+		scalekit.WithAPIVersion("20990101"),
+	)
+
+	_, err := client.Organization().ListOrganization(context.Background(), nil)
+	require.NoError(t, err)
+
+	for _, path := range []string{"/oauth/token", organizationsconnect.OrganizationServiceListOrganizationProcedure} {
+		value, ok := headers.Load(path)
+		require.True(t, ok, path)
+		header := value.(http.Header)
+		assert.True(t, strings.HasPrefix(header.Get("User-Agent"), "Scalekit-Go/"), path)
+		assert.True(t, strings.HasSuffix(header.Get("User-Agent"), " acme-billing/1.2"), path)
+		assert.Equal(t, "20990101", header.Get("X-Api-Version"), path)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	server := scalekittest.NewServer(t)
+	server.Intercept(func(_ http.ResponseWriter, r *http.Request) bool {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+		return false
+	})
+
+	client := server.Client(scalekit.WithTimeout(50 * time.Millisecond))
+	start := time.Now()
+	_, err := client.RefreshAccessToken(context.Background(), "refresh_token")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected error: %v", err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
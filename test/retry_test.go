@@ -0,0 +1,215 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/organizations/organizationsconnect"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fastRetryPolicy retries quickly so tests do not wait on real backoff delays.
+func fastRetryPolicy() scalekit.RetryPolicy {
+	policy := scalekit.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = 5 * time.Millisecond
+	return policy
+}
+
+// failingResponse describes how the test server answers a failed attempt.
+type failingResponse struct {
+	status     int
+	retryAfter string
+}
+
+// failNext makes server answer path with the given failures, in order, before
+// handling it normally. It returns the number of requests made to path.
+func failNext(server *scalekittest.Server, path string, failures ...failingResponse) *atomic.Int32 {
+	var calls atomic.Int32
+	server.Intercept(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != path {
+			return false
+		}
+		n := int(calls.Add(1))
+		if n > len(failures) {
+			return false
+		}
+		if failures[n-1].retryAfter != "" {
+			w.Header().Set("Retry-After", failures[n-1].retryAfter)
+		}
+		w.WriteHeader(failures[n-1].status)
+		return true
+	})
+	return &calls
+}
+
+func TestRetryPolicyRPC(t *testing.T) {
+	listPath := organizationsconnect.OrganizationServiceListOrganizationProcedure
+	createPath := organizationsconnect.OrganizationServiceCreateOrganizationProcedure
+
+	tests := []struct {
+		name      string
+		path      string
+		failures  []failingResponse
+		ctx       func() context.Context
+		wantErr   bool
+		wantCalls int32
+	}{
+		{
+			name:      "idempotent call is retried on 503",
+			path:      listPath,
+			failures:  []failingResponse{{status: http.StatusServiceUnavailable}, {status: http.StatusBadGateway}},
+			wantCalls: 3,
+		},
+		{
+			name:      "idempotent call gives up after max attempts",
+			path:      listPath,
+			failures:  []failingResponse{{status: 503}, {status: 503}, {status: 503}, {status: 503}},
+			wantErr:   true,
+			wantCalls: 3,
+		},
+		{
+			name:      "non-idempotent create is not retried on 503",
+			path:      createPath,
+			failures:  []failingResponse{{status: http.StatusServiceUnavailable}},
+			wantErr:   true,
+			wantCalls: 1,
+		},
+		{
+			name:      "non-idempotent create is retried when rate limited with Retry-After",
+			path:      createPath,
+			failures:  []failingResponse{{status: http.StatusTooManyRequests, retryAfter: "0"}},
+			wantCalls: 2,
+		},
+		{
+			name:     "per-call policy overrides client policy",
+			path:     listPath,
+			failures: []failingResponse{{status: http.StatusServiceUnavailable}},
+			ctx: func() context.Context {
+				return scalekit.ContextWithRetryPolicy(context.Background(), scalekit.NoRetryPolicy())
+			},
+			wantErr:   true,
+			wantCalls: 1,
+		},
+		{
+			name:     "partially filled policy keeps the default attempts",
+			path:     listPath,
+			failures: []failingResponse{{status: http.StatusServiceUnavailable}, {status: http.StatusServiceUnavailable}},
+			ctx: func() context.Context {
+				return scalekit.ContextWithRetryPolicy(context.Background(), scalekit.RetryPolicy{
+					InitialBackoff: time.Millisecond,
+					MaxBackoff:     5 * time.Millisecond,
+				})
+			},
+			wantCalls: 3,
+		},
+		{
+			name:      "client errors are not retried",
+			path:      listPath,
+			failures:  []failingResponse{{status: http.StatusBadRequest}},
+			wantErr:   true,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := scalekittest.NewServer(t)
+			calls := failNext(server, tt.path, tt.failures...)
+			client := server.Client(scalekit.WithRetryPolicy(fastRetryPolicy()))
+			ctx := context.Background()
+			if tt.ctx != nil {
+				ctx = tt.ctx()
+			}
+
+			var err error
+			if tt.path == createPath {
+				_, err = client.Organization().CreateOrganization(ctx, TestOrgName, scalekit.CreateOrganizationOptions{})
+			} else {
+				_, err = client.Organization().ListOrganization(ctx, nil)
+			}
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantCalls, calls.Load())
+		})
+	}
+}
+
+func TestRetryPolicyTokenEndpoint(t *testing.T) {
+	tests := []struct {
+		name      string
+		call      func(client scalekit.Scalekit, refreshToken string) error
+		failures  []failingResponse
+		wantErr   bool
+		wantCalls int32
+	}{
+		{
+			name: "client credentials grant is retried on 503",
+			call: func(client scalekit.Scalekit, _ string) error {
+				_, err := client.GetClientAccessToken(context.Background())
+				return err
+			},
+			failures:  []failingResponse{{status: http.StatusServiceUnavailable}},
+			wantCalls: 2,
+		},
+		{
+			name: "refresh token grant is not retried on 503",
+			call: func(client scalekit.Scalekit, refreshToken string) error {
+				_, err := client.RefreshAccessToken(context.Background(), refreshToken)
+				return err
+			},
+			failures:  []failingResponse{{status: http.StatusServiceUnavailable}},
+			wantErr:   true,
+			wantCalls: 1,
+		},
+		{
+			name: "refresh token grant is retried on 429",
+			call: func(client scalekit.Scalekit, refreshToken string) error {
+				_, err := client.RefreshAccessToken(context.Background(), refreshToken)
+				return err
+			},
+			failures:  []failingResponse{{status: http.StatusTooManyRequests}},
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := scalekittest.NewServer(t)
+			client := server.Client(scalekit.WithRetryPolicy(fastRetryPolicy()))
+			resp, err := client.AuthenticateWithCode(context.Background(),
+				server.IssueAuthorizationCode(scalekit.IdTokenClaims{Id: "usr_1"}), "https://app.example.com/callback", scalekit.AuthenticationOptions{})
+			require.NoError(t, err)
+			calls := failNext(server, "/oauth/token", tt.failures...)
+
+			err = tt.call(client, resp.RefreshToken)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantCalls, calls.Load())
+		})
+	}
+}
+
+func TestRetryPolicyHonorsRetryAfter(t *testing.T) {
+	server := scalekittest.NewServer(t)
+	calls := failNext(server, "/oauth/token", failingResponse{status: http.StatusTooManyRequests, retryAfter: "1"})
+	client := server.Client(scalekit.WithRetryPolicy(fastRetryPolicy()))
+
+	start := time.Now()
+	_, err := client.GetClientAccessToken(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+}
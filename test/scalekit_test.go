@@ -78,6 +78,8 @@ func TestAuthenticateWithCode(t *testing.T) {
 							"given_name":     "Mock",
 							"family_name":    "User",
 							"email_verified": true,
+							"iss":            "http://" + r.Host,
+							"aud":            "client_id",
 							"iat":            iat,
 							"exp":            exp,
 							"oid":            "org_mock456",
@@ -234,6 +236,8 @@ func TestAuthenticateWithCode_ClientSecretBehaviorByClientType(t *testing.T) {
 						"given_name":     "Mock",
 						"family_name":    "User",
 						"email_verified": true,
+						"iss":            "http://" + r.Host,
+						"aud":            "client_id",
 						"iat":            iat,
 						"exp":            exp,
 					}
@@ -313,6 +317,10 @@ func TestAuthenticateWithCode_ClientSecretBehaviorByClientType(t *testing.T) {
 	}
 }
 
+// testTokenIssuer is the iss claim of the fixture tokens, which were issued by a
+// development environment rather than the mock servers the tests start.
+const testTokenIssuer = "http://airdev.localhost:8888"
+
 func TestGetAccessToken(t *testing.T) {
 	type testCase struct {
 		name     string
@@ -354,7 +362,7 @@ func TestGetAccessToken(t *testing.T) {
 			server := httptest.NewServer(http.HandlerFunc(tt.mockFn))
 			defer server.Close()
 
-			client := scalekit.NewScalekitClient(server.URL, "client_id", "client_secret")
+			client := scalekit.NewScalekitClient(server.URL, "client_id", "client_secret", scalekit.WithIssuer(testTokenIssuer))
 			token, err := client.GetAccessTokenClaims(context.Background(), tt.token)
 			tt.assertFn(t, token, err)
 		})
@@ -726,7 +734,7 @@ func TestGetIdpInitiatedLoginClaims(t *testing.T) {
 			server := httptest.NewServer(http.HandlerFunc(tt.mockFn))
 			defer server.Close()
 
-			client := scalekit.NewScalekitClient(server.URL, "client_id", "client_secret")
+			client := scalekit.NewScalekitClient(server.URL, "client_id", "client_secret", scalekit.WithIssuer(testTokenIssuer))
 			claims, err := client.GetIdpInitiatedLoginClaims(context.Background(), tt.token)
 			tt.assertFn(t, claims, err)
 		})
@@ -809,7 +817,7 @@ func TestValidateAccessToken(t *testing.T) {
 			server := httptest.NewServer(http.HandlerFunc(tt.mockFn))
 			defer server.Close()
 
-			client := scalekit.NewScalekitClient(server.URL, "client_id", "client_secret")
+			client := scalekit.NewScalekitClient(server.URL, "client_id", "client_secret", scalekit.WithIssuer(testTokenIssuer))
 			isValid, err := client.ValidateAccessToken(context.Background(), tt.token)
 			tt.assertFn(t, isValid, err)
 		})
@@ -856,6 +864,7 @@ func TestValidateTokenWithOptions(t *testing.T) {
 			"given_name":     "Mock",
 			"family_name":    "User",
 			"email_verified": true,
+			"iss":            testTokenIssuer,
 			"iat":            now.Unix(),
 			"exp":            now.Add(time.Hour).Unix(),
 		}, "mock-id-token-kid")
@@ -865,7 +874,7 @@ func TestValidateTokenWithOptions(t *testing.T) {
 		now := time.Now()
 		return signedToken(map[string]interface{}{
 			"sub":   "usr_mock123",
-			"iss":   "http://test.com",
+			"iss":   testTokenIssuer,
 			"aud":   []string{"prd_skc_17002334227857508"},
 			"iat":   now.Unix(),
 			"exp":   now.Add(time.Hour).Unix(),
@@ -877,7 +886,7 @@ func TestValidateTokenWithOptions(t *testing.T) {
 		now := time.Now()
 		return signedToken(map[string]interface{}{
 			"sub": "usr_mock123",
-			"iss": "http://test.com",
+			"iss": testTokenIssuer,
 			"aud": []string{"prd_skc_17002334227857508"},
 			"iat": now.Unix(),
 			"exp": now.Add(time.Hour).Unix(),
@@ -888,7 +897,7 @@ func TestValidateTokenWithOptions(t *testing.T) {
 		now := time.Now()
 		return signedToken(map[string]interface{}{
 			"sub":   "usr_mock123",
-			"iss":   "http://test.com",
+			"iss":   testTokenIssuer,
 			"aud":   []string{"prd_skc_17002334227857508"},
 			"iat":   now.Unix(),
 			"exp":   now.Add(time.Hour).Unix(),
@@ -1118,7 +1127,7 @@ func TestValidateTokenWithOptions(t *testing.T) {
 			server := httptest.NewServer(http.HandlerFunc(tt.mockFn))
 			defer server.Close()
 
-			client := scalekit.NewScalekitClient(server.URL, "client_id", "client_secret")
+			client := scalekit.NewScalekitClient(server.URL, "client_id", "client_secret", scalekit.WithIssuer(testTokenIssuer))
 			isValid, err := client.ValidateTokenWithOptions(context.Background(), tt.token, tt.options)
 			tt.assertFn(t, isValid, err)
 		})
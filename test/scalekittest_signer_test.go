@@ -0,0 +1,101 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignerMintedTokensValidate(t *testing.T) {
+	ctx := context.Background()
+	fake := scalekittest.NewServer(t)
+	signer, client := fake.Signer(), fake.Client()
+
+	idToken, err := signer.MintIDToken(scalekit.IdTokenClaims{
+		Id:            "usr_1",
+		Email:         "jane@example.com",
+		EmailVerified: true,
+		Claims:        scalekit.Claims{"oid": "org_1"},
+	})
+	require.NoError(t, err)
+	user, err := scalekit.ValidateToken[scalekit.IdTokenClaims](ctx, idToken, signer.KeySet)
+	require.NoError(t, err)
+	assert.Equal(t, "usr_1", user.Id)
+	assert.Equal(t, "jane@example.com", user.Email)
+	assert.True(t, user.EmailVerified)
+	assert.Equal(t, "org_1", user.Claims["oid"])
+	assert.Equal(t, signer.Issuer, user.Claims["iss"])
+
+	accessToken, err := signer.MintAccessToken(scalekit.AccessTokenClaims{Sub: "usr_1"},
+		scalekittest.WithAudience("https://api.example.com"),
+		scalekittest.WithScopes("read", "write"),
+		scalekittest.WithClaims(map[string]any{"roles": []string{"admin"}}))
+	require.NoError(t, err)
+	claims, err := client.GetAccessTokenClaims(ctx, accessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "usr_1", claims.Sub)
+	assert.Equal(t, scalekit.Audience{"https://api.example.com"}, claims.Audience)
+	assert.Equal(t, "read write", claims.Claims["scope"])
+	assert.Equal(t, []any{"admin"}, claims.Claims["roles"])
+
+	valid, err := client.ValidateTokenWithOptions(ctx, accessToken, &scalekit.ValidateTokenOptions{
+		Audience: []string{"https://api.example.com"},
+		Scopes:   []string{"write"},
+	})
+	require.NoError(t, err)
+	assert.True(t, valid)
+	valid, err = client.ValidateTokenWithOptions(ctx, accessToken, &scalekit.ValidateTokenOptions{Scopes: []string{"admin"}})
+	assert.Error(t, err)
+	assert.False(t, valid)
+
+	expired, err := signer.MintAccessToken(scalekit.AccessTokenClaims{Sub: "usr_1"}, scalekittest.WithExpiry(-time.Minute))
+	require.NoError(t, err)
+	valid, err = client.ValidateAccessToken(ctx, expired)
+	assert.Error(t, err)
+	assert.False(t, valid)
+
+	relayState := "state"
+	loginToken, err := signer.MintIdpInitiatedLoginToken(scalekit.IdpInitiatedLoginClaims{
+		ConnectionID:   "conn_1",
+		OrganizationID: "org_1",
+		RelayState:     &relayState,
+	})
+	require.NoError(t, err)
+	login, err := client.GetIdpInitiatedLoginClaims(ctx, loginToken)
+	require.NoError(t, err)
+	assert.Equal(t, "conn_1", login.ConnectionID)
+	assert.Equal(t, "org_1", login.OrganizationID)
+	require.NotNil(t, login.RelayState)
+	assert.Equal(t, relayState, *login.RelayState)
+}
+
+func TestFakeServerAuthenticateWithCode(t *testing.T) {
+	ctx := context.Background()
+	server := scalekittest.NewServer(t)
+	client := server.Client()
+
+	code := server.IssueAuthorizationCode(scalekit.IdTokenClaims{Id: "usr_1", Email: "jane@example.com"})
+	resp, err := client.AuthenticateWithCode(ctx, code, "https://app.example.com/callback", scalekit.AuthenticationOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "usr_1", resp.User.Id)
+	assert.Equal(t, "jane@example.com", resp.User.Email)
+	assert.NotEmpty(t, resp.RefreshToken)
+	claims, err := client.GetAccessTokenClaims(ctx, resp.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "usr_1", claims.Sub)
+
+	// Codes are single use.
+	_, err = client.AuthenticateWithCode(ctx, code, "https://app.example.com/callback", scalekit.AuthenticationOptions{})
+	assert.Error(t, err)
+
+	refreshed, err := client.RefreshAccessToken(ctx, resp.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, resp.RefreshToken, refreshed.RefreshToken)
+	_, err = client.RefreshAccessToken(ctx, resp.RefreshToken)
+	assert.Error(t, err)
+}
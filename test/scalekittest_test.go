@@ -0,0 +1,215 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	commonsv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/commons"
+	connectionsv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/connections"
+	directoriesv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/directories"
+	organizationsv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/organizations"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/organizations/organizationsconnect"
+	rolesv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/roles"
+	sessionsv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/sessions"
+	usersv1 "github.com/scalekit-inc/scalekit-sdk-go/v2/pkg/grpc/scalekit/v1/users"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeServerOrganizations(t *testing.T) {
+	ctx := context.Background()
+	client := scalekittest.NewServer(t).Client()
+
+	created, err := client.Organization().CreateOrganization(ctx, TestOrgName, scalekit.CreateOrganizationOptions{ExternalId: "ext_1"})
+	require.NoError(t, err)
+	orgID := created.Organization.Id
+	assert.NotEmpty(t, orgID)
+
+	_, err = client.Organization().CreateOrganization(ctx, TestOrgName, scalekit.CreateOrganizationOptions{ExternalId: "ext_1"})
+	assert.True(t, scalekit.IsAlreadyExists(err), "unexpected error: %v", err)
+
+	byExternalID, err := client.Organization().GetOrganizationByExternalId(ctx, "ext_1")
+	require.NoError(t, err)
+	assert.Equal(t, orgID, byExternalID.Organization.Id)
+
+	name := "Renamed"
+	updated, err := client.Organization().UpdateOrganization(ctx, orgID, &scalekit.UpdateOrganization{DisplayName: &name})
+	require.NoError(t, err)
+	assert.Equal(t, name, updated.Organization.DisplayName)
+
+	for i := 0; i < 2; i++ {
+		_, err = client.Organization().CreateOrganization(ctx, TestOrgName, scalekit.CreateOrganizationOptions{})
+		require.NoError(t, err)
+	}
+	page, err := client.Organization().ListOrganization(ctx, &scalekit.ListOrganizationOptions{PageSize: 2})
+	require.NoError(t, err)
+	assert.Len(t, page.Organizations, 2)
+	assert.Equal(t, uint32(3), page.TotalSize)
+	page, err = client.Organization().ListOrganization(ctx, &scalekit.ListOrganizationOptions{PageSize: 2, PageToken: page.NextPageToken})
+	require.NoError(t, err)
+	assert.Len(t, page.Organizations, 1)
+	assert.Empty(t, page.NextPageToken)
+
+	link, err := client.Organization().GeneratePortalLink(ctx, orgID)
+	require.NoError(t, err)
+	assert.NotEmpty(t, link.Location)
+
+	require.NoError(t, client.Organization().DeleteOrganization(ctx, orgID))
+	_, err = client.Organization().GetOrganization(ctx, orgID)
+	assert.True(t, scalekit.IsNotFound(err), "unexpected error: %v", err)
+}
+
+func TestFakeServerUsersRolesAndPermissions(t *testing.T) {
+	ctx := context.Background()
+	client := scalekittest.NewServer(t).Client()
+
+	org, err := client.Organization().CreateOrganization(ctx, TestOrgName, scalekit.CreateOrganizationOptions{})
+	require.NoError(t, err)
+	orgID := org.Organization.Id
+
+	for _, name := range []string{"invoices:read", "invoices:write"} {
+		_, err = client.Permission().CreatePermission(ctx, &rolesv1.CreatePermission{Name: name})
+		require.NoError(t, err)
+	}
+	_, err = client.Role().CreateRole(ctx, &rolesv1.CreateRole{Name: "viewer", DisplayName: "Viewer", Permissions: []string{"invoices:read"}})
+	require.NoError(t, err)
+	extends := "viewer"
+	_, err = client.Role().CreateRole(ctx, &rolesv1.CreateRole{Name: "editor", DisplayName: "Editor", Extends: &extends, Permissions: []string{"invoices:write"}})
+	require.NoError(t, err)
+
+	created, err := client.User().CreateUserAndMembership(ctx, orgID, &usersv1.CreateUser{
+		Email:      "jane@example.com",
+		Membership: &usersv1.CreateMembership{Roles: []*commonsv1.Role{{Name: "editor"}}},
+	}, false)
+	require.NoError(t, err)
+	userID := created.User.Id
+	require.Len(t, created.User.Memberships, 1)
+	assert.Equal(t, "Editor", created.User.Memberships[0].Roles[0].DisplayName)
+
+	_, err = client.User().CreateUserAndMembership(ctx, orgID, &usersv1.CreateUser{Email: "jane@example.com"}, false)
+	assert.True(t, scalekit.IsAlreadyExists(err), "unexpected error: %v", err)
+
+	permissions, err := client.User().ListUserPermissions(ctx, orgID, userID)
+	require.NoError(t, err)
+	var names []string
+	for _, p := range permissions.Permissions {
+		names = append(names, p.Name)
+	}
+	assert.ElementsMatch(t, []string{"invoices:read", "invoices:write"}, names)
+
+	users, err := client.User().ListOrganizationUsers(ctx, orgID, nil)
+	require.NoError(t, err)
+	require.Len(t, users.Users, 1)
+	assert.Equal(t, userID, users.Users[0].Id)
+
+	require.NoError(t, client.User().DeleteMembership(ctx, orgID, userID, false))
+	users, err = client.User().ListOrganizationUsers(ctx, orgID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, users.Users)
+}
+
+func TestFakeServerDomainsConnectionsAndDirectories(t *testing.T) {
+	ctx := context.Background()
+	server := scalekittest.NewServer(t)
+	client := server.Client()
+
+	org, err := client.Organization().CreateOrganization(ctx, TestOrgName, scalekit.CreateOrganizationOptions{})
+	require.NoError(t, err)
+	orgID := org.Organization.Id
+
+	_, err = client.Domain().CreateDomain(ctx, orgID, "acme.com")
+	require.NoError(t, err)
+	connection, err := client.Connection().CreateConnection(ctx, orgID, &connectionsv1.CreateConnection{
+		Provider: connectionsv1.ConnectionProvider_OKTA,
+		Type:     connectionsv1.ConnectionType_OIDC,
+	})
+	require.NoError(t, err)
+	_, err = client.Connection().EnableConnection(ctx, orgID, connection.Connection.Id)
+	require.NoError(t, err)
+
+	byDomain, err := client.Connection().ListConnectionsByDomain(ctx, "acme.com")
+	require.NoError(t, err)
+	require.Len(t, byDomain.Connections, 1)
+	assert.True(t, byDomain.Connections[0].Enabled)
+	assert.Equal(t, []string{"acme.com"}, byDomain.Connections[0].Domains)
+
+	directory, err := client.Directory().CreateDirectory(ctx, orgID, &directoriesv1.CreateDirectory{
+		DirectoryType:     directoriesv1.DirectoryType_SCIM,
+		DirectoryProvider: directoriesv1.DirectoryProvider_OKTA,
+	})
+	require.NoError(t, err)
+	directoryID := directory.Directory.Id
+	group := server.AddDirectoryGroup(directoryID, &directoriesv1.DirectoryGroup{DisplayName: "Engineering"})
+	server.AddDirectoryUser(directoryID, &directoriesv1.DirectoryUser{Email: "eng@acme.com", Groups: []*directoriesv1.DirectoryGroup{group}})
+	server.AddDirectoryUser(directoryID, &directoriesv1.DirectoryUser{Email: "sales@acme.com"})
+
+	primary, err := client.Directory().GetPrimaryDirectoryByOrganizationId(ctx, orgID)
+	require.NoError(t, err)
+	assert.Equal(t, directoryID, primary.Directory.Id)
+	inGroup, err := client.Directory().ListDirectoryUsers(ctx, orgID, directoryID, &scalekit.ListDirectoryUsersOptions{DirectoryGroupId: &group.Id})
+	require.NoError(t, err)
+	require.Len(t, inGroup.Users, 1)
+	assert.Equal(t, "eng@acme.com", inGroup.Users[0].Email)
+}
+
+func TestFakeServerTokensSessionsAndClients(t *testing.T) {
+	ctx := context.Background()
+	server := scalekittest.NewServer(t)
+	client := server.Client()
+
+	org, err := client.Organization().CreateOrganization(ctx, TestOrgName, scalekit.CreateOrganizationOptions{})
+	require.NoError(t, err)
+	orgID := org.Organization.Id
+
+	token, err := client.Token().CreateToken(ctx, orgID, scalekit.CreateTokenOptions{Description: "ci"})
+	require.NoError(t, err)
+	validated, err := client.Token().ValidateToken(ctx, token.Token)
+	require.NoError(t, err)
+	assert.Equal(t, orgID, validated.TokenInfo.OrganizationId)
+	require.NoError(t, client.Token().InvalidateToken(ctx, token.Token))
+	_, err = client.Token().ValidateToken(ctx, token.Token)
+	assert.True(t, errors.Is(err, scalekit.ErrTokenValidationFailed), "unexpected error: %v", err)
+
+	server.AddSession(&sessionsv1.SessionDetails{UserId: "usr_1"})
+	server.AddSession(&sessionsv1.SessionDetails{UserId: "usr_1"})
+	revoked, err := client.Session().RevokeAllUserSessions(ctx, "usr_1")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), revoked.TotalRevoked)
+	sessions, err := client.Session().GetUserSessions(ctx, "usr_1", 10, "", &scalekit.UserSessionFilter{Status: []string{"active"}})
+	require.NoError(t, err)
+	assert.Empty(t, sessions.Sessions)
+
+	m2m, err := client.M2M().CreateOrganizationClient(ctx, orgID, scalekit.CreateOrganizationClientOptions{Name: "billing"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, m2m.PlainSecret)
+	clients, err := client.M2M().ListOrganizationClients(ctx, orgID, scalekit.ListOrganizationClientsOptions{})
+	require.NoError(t, err)
+	require.Len(t, clients.Clients, 1)
+	assert.Equal(t, "billing", clients.Clients[0].Name)
+}
+
+func TestFakeServerAuthentication(t *testing.T) {
+	ctx := context.Background()
+	server := scalekittest.NewServer(t)
+
+	// Calls without a token issued by the server are rejected.
+	raw := organizationsconnect.NewOrganizationServiceClient(server.HTTPClient(), server.URL, connect.WithGRPC())
+	_, err := raw.ListOrganization(ctx, connect.NewRequest(&organizationsv1.ListOrganizationsRequest{}))
+	assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+
+	// Wrong client credentials fail at the token endpoint.
+	wrong := scalekit.NewScalekitClient(server.URL, server.ClientID, "wrong", scalekit.WithHTTPClient(server.HTTPClient()))
+	_, err = wrong.Organization().ListOrganization(ctx, nil)
+	var sdkErr *scalekit.Error
+	require.True(t, errors.As(err, &sdkErr), "unexpected error: %v", err)
+	assert.Equal(t, http.StatusUnauthorized, sdkErr.StatusCode)
+
+	// RPCs the fake does not model report CodeUnimplemented.
+	_, err = server.Client().Organization().GetOrganizationSessionPolicy(ctx, "org_1")
+	assert.Equal(t, connect.CodeUnimplemented, connect.CodeOf(err))
+}
@@ -0,0 +1,178 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// saveSession saves session with store and returns the cookies it set.
+func saveSession(t *testing.T, store scalekit.SessionStore, session *scalekit.Session, cookies ...*http.Cookie) []*http.Cookie {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	require.NoError(t, store.Save(rec, req, session))
+	return rec.Result().Cookies()
+}
+
+// loadSession loads the session of a request carrying cookies.
+func loadSession(t *testing.T, store scalekit.SessionStore, cookies []*http.Cookie) *scalekit.Session {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range cookies {
+		if cookie.MaxAge >= 0 {
+			req.AddCookie(cookie)
+		}
+	}
+	session, err := store.Load(req)
+	require.NoError(t, err)
+	return session
+}
+
+func TestCookieSessionStore(t *testing.T) {
+	store, err := scalekit.NewCookieSessionStore(testCookieSecret, scalekit.CookieOptions{})
+	require.NoError(t, err)
+
+	large := &scalekit.Session{SessionId: "ses_1", AccessToken: strings.Repeat("a", 4000)}
+	cookies := saveSession(t, store, large)
+	require.Len(t, cookies, 2)
+	assert.Equal(t, scalekit.DefaultSessionCookieName, cookies[0].Name)
+	assert.Equal(t, scalekit.DefaultSessionCookieName+"_1", cookies[1].Name)
+	loaded := loadSession(t, store, cookies)
+	require.NotNil(t, loaded)
+	assert.Equal(t, large.AccessToken, loaded.AccessToken)
+
+	// A smaller session deletes the chunk it no longer needs.
+	smaller := saveSession(t, store, &scalekit.Session{SessionId: "ses_1", AccessToken: "short"}, cookies...)
+	require.Len(t, smaller, 2)
+	assert.Equal(t, scalekit.DefaultSessionCookieName+"_1", smaller[1].Name)
+	assert.Negative(t, smaller[1].MaxAge)
+	assert.Equal(t, "short", loadSession(t, store, smaller).AccessToken)
+
+	// The user's custom claims survive the round trip.
+	withClaims := saveSession(t, store, &scalekit.Session{SessionId: "ses_1", User: scalekit.User{
+		Id:     "usr_1",
+		Claims: scalekit.Claims{"sub": "usr_1", "tenant": "acme"},
+	}})
+	loaded = loadSession(t, store, withClaims)
+	require.NotNil(t, loaded)
+	assert.Equal(t, "usr_1", loaded.User.Id)
+	assert.Equal(t, "acme", loaded.User.Claims["tenant"])
+
+	// A session only opens under the cookie name it was sealed for.
+	renamed, err := scalekit.NewCookieSessionStore(testCookieSecret, scalekit.CookieOptions{Name: "other_session"})
+	require.NoError(t, err)
+	assert.Nil(t, loadSession(t, renamed, []*http.Cookie{{Name: "other_session", Value: withClaims[0].Value}}))
+
+	cookies[0].Value = "A" + cookies[0].Value[1:]
+	assert.Nil(t, loadSession(t, store, cookies))
+
+	err = store.Save(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil),
+		&scalekit.Session{AccessToken: strings.Repeat("a", 20000)})
+	assert.ErrorIs(t, err, scalekit.ErrSessionTooLarge)
+}
+
+func TestServerSessionStore(t *testing.T) {
+	store := scalekit.NewServerSessionStore(scalekit.NewMemorySessionBackend(), scalekit.CookieOptions{})
+
+	cookies := saveSession(t, store, &scalekit.Session{SessionId: "ses_1", AccessToken: "at_1"})
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "at_1", loadSession(t, store, cookies).AccessToken)
+
+	// Refreshed tokens of the same Scalekit session keep the session ID.
+	refreshed := saveSession(t, store, &scalekit.Session{SessionId: "ses_1", AccessToken: "at_2"}, cookies...)
+	assert.Equal(t, cookies[0].Value, refreshed[0].Value)
+	assert.Equal(t, "at_2", loadSession(t, store, cookies).AccessToken)
+
+	// A new login gets a new session ID and the old one is forgotten.
+	login := saveSession(t, store, &scalekit.Session{SessionId: "ses_2", AccessToken: "at_3"}, cookies...)
+	assert.NotEqual(t, cookies[0].Value, login[0].Value)
+	assert.Nil(t, loadSession(t, store, cookies))
+	assert.Equal(t, "at_3", loadSession(t, store, login).AccessToken)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(login[0])
+	require.NoError(t, store.Clear(httptest.NewRecorder(), req))
+	assert.Nil(t, loadSession(t, store, login))
+}
+
+func TestSessionMiddleware(t *testing.T) {
+	ctx := context.Background()
+	fake := scalekittest.NewServer(t)
+	client := fake.Client()
+	store := scalekit.NewServerSessionStore(scalekit.NewMemorySessionBackend(), scalekit.CookieOptions{})
+	handler := scalekit.NewSessionMiddleware(client, store, scalekit.SessionMiddlewareOptions{Required: true})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session, _ := scalekit.SessionFromContext(r.Context())
+			_, _ = w.Write([]byte(session.RefreshToken))
+		}))
+	serve := func(cookies []*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	resp, err := client.AuthenticateWithCode(ctx, fake.IssueAuthorizationCode(scalekit.IdTokenClaims{Id: "usr_1"}),
+		"https://app.example.com/callback", scalekit.AuthenticationOptions{})
+	require.NoError(t, err)
+	session := &scalekit.Session{
+		SessionId:    "ses_1",
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresAt:    time.Now().Add(10 * time.Second),
+	}
+	cookies := saveSession(t, store, session)
+
+	// Requests sharing the expiring session refresh it once; a second
+	// refresh would be rejected because refresh tokens are single use.
+	var wg sync.WaitGroup
+	bodies := make([]string, 8)
+	for i := range bodies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := serve(cookies)
+			if rec.Code == http.StatusOK {
+				bodies[i] = rec.Body.String()
+			}
+		}()
+	}
+	wg.Wait()
+	for _, body := range bodies {
+		assert.NotEmpty(t, body)
+		assert.NotEqual(t, resp.RefreshToken, body)
+		assert.Equal(t, bodies[0], body)
+	}
+	stored := loadSession(t, store, cookies)
+	require.NotNil(t, stored)
+	assert.Equal(t, bodies[0], stored.RefreshToken)
+	assert.True(t, stored.ExpiresAt.After(time.Now().Add(time.Minute)))
+
+	// A session whose refresh token is rejected is cleared.
+	revoked := saveSession(t, store, &scalekit.Session{SessionId: "ses_2", AccessToken: "at", RefreshToken: "skrt_revoked", ExpiresAt: time.Now().Add(-time.Second)})
+	assert.Equal(t, http.StatusUnauthorized, serve(revoked).Code)
+	assert.Nil(t, loadSession(t, store, revoked))
+
+	// A session whose access token expired and cannot be refreshed is cleared.
+	expired := saveSession(t, store, &scalekit.Session{SessionId: "ses_3", AccessToken: "at", ExpiresAt: time.Now().Add(-time.Second)})
+	assert.Equal(t, http.StatusUnauthorized, serve(expired).Code)
+	assert.Nil(t, loadSession(t, store, expired))
+
+	assert.Equal(t, http.StatusUnauthorized, serve(nil).Code)
+}
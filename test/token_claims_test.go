@@ -0,0 +1,130 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTokenRegisteredClaims(t *testing.T) {
+	ctx := context.Background()
+	fake := scalekittest.NewServer(t)
+	signer, client := fake.Signer(), fake.Client()
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		opts    []scalekittest.TokenOption
+		options *scalekit.ValidateTokenOptions
+		wantErr error
+	}{
+		{
+			name: "valid token",
+		},
+		{
+			name:    "other issuer",
+			opts:    []scalekittest.TokenOption{scalekittest.WithIssuer("https://other.example.com")},
+			wantErr: scalekit.ErrInvalidIssuer,
+		},
+		{
+			name:    "issuer with trailing slash",
+			opts:    []scalekittest.TokenOption{scalekittest.WithIssuer(signer.Issuer + "/")},
+			wantErr: nil,
+		},
+		{
+			name:    "explicit issuer",
+			opts:    []scalekittest.TokenOption{scalekittest.WithIssuer("https://auth.example.com")},
+			options: &scalekit.ValidateTokenOptions{Issuer: "https://auth.example.com"},
+		},
+		{
+			name:    "expired",
+			opts:    []scalekittest.TokenOption{scalekittest.WithExpiresAt(now.Add(-5 * time.Second))},
+			wantErr: scalekit.ErrTokenExpired,
+		},
+		{
+			name:    "expired within leeway",
+			opts:    []scalekittest.TokenOption{scalekittest.WithExpiresAt(now.Add(-5 * time.Second))},
+			options: &scalekit.ValidateTokenOptions{Leeway: 30 * time.Second},
+		},
+		{
+			name:    "not valid yet",
+			opts:    []scalekittest.TokenOption{scalekittest.WithClaims(map[string]any{"nbf": now.Add(time.Minute).Unix()})},
+			wantErr: scalekit.ErrTokenNotYetValid,
+		},
+		{
+			name:    "not valid yet within leeway",
+			opts:    []scalekittest.TokenOption{scalekittest.WithClaims(map[string]any{"nbf": now.Add(5 * time.Second).Unix()})},
+			options: &scalekit.ValidateTokenOptions{Leeway: 30 * time.Second},
+		},
+		{
+			name:    "issued in the future",
+			opts:    []scalekittest.TokenOption{scalekittest.WithIssuedAt(now.Add(time.Minute))},
+			wantErr: scalekit.ErrTokenIssuedInFuture,
+		},
+		{
+			name:    "issued in the future within custom leeway",
+			opts:    []scalekittest.TokenOption{scalekittest.WithIssuedAt(now.Add(time.Minute))},
+			options: &scalekit.ValidateTokenOptions{Leeway: 2 * time.Minute},
+		},
+		{
+			name:    "older than max age",
+			opts:    []scalekittest.TokenOption{scalekittest.WithIssuedAt(now.Add(-time.Hour))},
+			options: &scalekit.ValidateTokenOptions{MaxAge: 10 * time.Minute},
+			wantErr: scalekit.ErrTokenTooOld,
+		},
+		{
+			name:    "within max age",
+			opts:    []scalekittest.TokenOption{scalekittest.WithIssuedAt(now.Add(-5 * time.Minute))},
+			options: &scalekit.ValidateTokenOptions{MaxAge: 10 * time.Minute},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := signer.Mint(map[string]any{"sub": "usr_1"}, tt.opts...)
+			require.NoError(t, err)
+			_, err = client.(scalekit.AccessTokenClaimsValidator).GetAccessTokenClaimsWithOptions(ctx, token, tt.options)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, tt.wantErr), "unexpected error: %v", err)
+		})
+	}
+}
+
+func TestValidateTokenIssuerOptions(t *testing.T) {
+	ctx := context.Background()
+	fake := scalekittest.NewServer(t)
+	signer := fake.Signer()
+	token, err := signer.Mint(map[string]any{"sub": "usr_1"}, scalekittest.WithIssuer("https://auth.example.com"))
+	require.NoError(t, err)
+
+	// The package-level function checks the issuer only when asked to.
+	_, err = scalekit.ValidateToken[scalekit.AccessTokenClaims](ctx, token, signer.KeySet)
+	assert.NoError(t, err)
+	_, err = scalekit.ValidateTokenWithOptions[scalekit.AccessTokenClaims](ctx, token, signer.KeySet, &scalekit.ValidateTokenOptions{Issuer: signer.Issuer})
+	assert.True(t, errors.Is(err, scalekit.ErrInvalidIssuer), "unexpected error: %v", err)
+
+	// Clients expect their environment URL unless configured otherwise.
+	custom := fake.Client(scalekit.WithIssuer("https://auth.example.com"))
+	_, err = custom.GetAccessTokenClaims(ctx, token)
+	assert.NoError(t, err)
+	unchecked := fake.Client(scalekit.WithIssuer(""))
+	_, err = unchecked.GetAccessTokenClaims(ctx, token)
+	assert.NoError(t, err)
+
+	// Clients tolerate clock skew only when configured to.
+	expired, err := signer.Mint(map[string]any{"sub": "usr_1"}, scalekittest.WithExpiresAt(time.Now().Add(-5*time.Second)))
+	require.NoError(t, err)
+	_, err = fake.Client().GetAccessTokenClaims(ctx, expired)
+	assert.True(t, errors.Is(err, scalekit.ErrTokenExpired), "unexpected error: %v", err)
+	lenient := fake.Client(scalekit.WithTokenLeeway(30 * time.Second))
+	_, err = lenient.GetAccessTokenClaims(ctx, expired)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,107 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExchangeToken(t *testing.T) {
+	ctx := context.Background()
+	server := scalekittest.NewServer(t)
+	signer := server.Signer()
+	server.Intercept(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != "/oauth/token" || r.PostFormValue("grant_type") != string(scalekit.GrantTypeTokenExchange) {
+			return false
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("subject_token") == "revoked" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return true
+		}
+		assert.Equal(t, server.ClientID, r.PostForm.Get("client_id"))
+		assert.Equal(t, server.ClientSecret, r.PostForm.Get("client_secret"))
+		assert.Equal(t, "user_token", r.PostForm.Get("subject_token"))
+		assert.Equal(t, scalekit.TokenTypeAccessToken, r.PostForm.Get("subject_token_type"))
+		assert.Equal(t, "agent_token", r.PostForm.Get("actor_token"))
+		assert.Equal(t, scalekit.TokenTypeJwt, r.PostForm.Get("actor_token_type"))
+		assert.Equal(t, []string{"calendar", "mail"}, r.PostForm["audience"])
+		assert.Equal(t, []string{"https://api.example.com"}, r.PostForm["resource"])
+		assert.Equal(t, "calendar:read mail:send", r.PostForm.Get("scope"))
+
+		token, err := signer.MintAccessToken(scalekit.AccessTokenClaims{
+			Sub: "usr_1",
+			Act: &scalekit.Actor{
+				Sub:      "agent_1",
+				ClientId: server.ClientID,
+				Act:      &scalekit.Actor{Sub: "agent_0"},
+			},
+		}, scalekittest.WithAudience("calendar", "mail"))
+		require.NoError(t, err)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token":      token,
+			"issued_token_type": scalekit.TokenTypeAccessToken,
+			"token_type":        "Bearer",
+			"expires_in":        300,
+			"scope":             "calendar:read",
+		})
+		return true
+	})
+	client := server.Client()
+
+	t.Run("exchanges subject and actor tokens", func(t *testing.T) {
+		resp, err := client.(scalekit.TokenExchanger).ExchangeToken(ctx, scalekit.TokenExchangeOptions{
+			SubjectToken:   "user_token",
+			ActorToken:     "agent_token",
+			ActorTokenType: scalekit.TokenTypeJwt,
+			Audience:       []string{"calendar", "mail"},
+			Resource:       []string{"https://api.example.com"},
+			Scopes:         []string{"calendar:read", "mail:send"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, scalekit.TokenTypeAccessToken, resp.IssuedTokenType)
+		assert.Equal(t, "Bearer", resp.TokenType)
+		assert.Equal(t, 300, resp.ExpiresIn)
+		assert.Equal(t, []string{"calendar:read"}, resp.Scopes)
+
+		claims, err := client.(scalekit.AccessTokenClaimsValidator).GetAccessTokenClaimsWithOptions(ctx, resp.AccessToken, &scalekit.ValidateTokenOptions{Audience: []string{"mail"}})
+		require.NoError(t, err)
+		assert.Equal(t, "usr_1", claims.Sub)
+		require.NotNil(t, claims.Act)
+		assert.Equal(t, "agent_1", claims.Act.Sub)
+		assert.Equal(t, server.ClientID, claims.Act.ClientId)
+		assert.Equal(t, []scalekit.Actor{
+			{Sub: "agent_1", ClientId: server.ClientID, Act: &scalekit.Actor{Sub: "agent_0"}},
+			{Sub: "agent_0"},
+		}, claims.Delegation())
+	})
+
+	t.Run("requires a subject token", func(t *testing.T) {
+		_, err := client.(scalekit.TokenExchanger).ExchangeToken(ctx, scalekit.TokenExchangeOptions{})
+		assert.ErrorIs(t, err, scalekit.ErrSubjectTokenRequired)
+	})
+
+	t.Run("surfaces OAuth errors", func(t *testing.T) {
+		_, err := client.(scalekit.TokenExchanger).ExchangeToken(ctx, scalekit.TokenExchangeOptions{SubjectToken: "revoked"})
+		var sdkErr *scalekit.Error
+		require.True(t, errors.As(err, &sdkErr))
+		assert.Equal(t, "invalid_grant", sdkErr.Reason)
+	})
+
+	t.Run("tokens without act have no delegation", func(t *testing.T) {
+		token, err := signer.MintAccessToken(scalekit.AccessTokenClaims{Sub: "usr_1"})
+		require.NoError(t, err)
+		claims, err := client.GetAccessTokenClaims(ctx, token)
+		require.NoError(t, err)
+		assert.Nil(t, claims.Act)
+		assert.Nil(t, claims.Delegation())
+	})
+}
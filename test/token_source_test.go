@@ -0,0 +1,180 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newM2MTestClient returns a client of a fake Scalekit server, the
+// credentials of an organization client created on it, and a counter of the
+// tokens issued for those credentials.
+func newM2MTestClient(t *testing.T, server *scalekittest.Server) (scalekit.ClientTokenSourcer, scalekit.GenerateClientTokenOptions, *atomic.Int32) {
+	t.Helper()
+	client := server.Client()
+	org, err := client.Organization().CreateOrganization(context.Background(), TestOrgName, scalekit.CreateOrganizationOptions{})
+	require.NoError(t, err)
+	resp, err := client.M2M().CreateOrganizationClient(context.Background(), org.Organization.Id, scalekit.CreateOrganizationClientOptions{Name: "Billing"})
+	require.NoError(t, err)
+
+	var issued atomic.Int32
+	server.Intercept(func(_ http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/oauth/token" && r.PostFormValue("client_id") == resp.Client.ClientId {
+			issued.Add(1)
+		}
+		return false
+	})
+	return client.(scalekit.ClientTokenSourcer), scalekit.GenerateClientTokenOptions{ClientID: resp.Client.ClientId, ClientSecret: resp.PlainSecret}, &issued
+}
+
+func TestClientTokenSource(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("caches tokens per source", func(t *testing.T) {
+		client, credentials, issued := newM2MTestClient(t, scalekittest.NewServer(t))
+		options := credentials
+		options.Scopes = []string{"read", "write"}
+		source, err := client.ClientTokenSource(options)
+		require.NoError(t, err)
+
+		tokens := make([]string, 8)
+		var wg sync.WaitGroup
+		for i := range tokens {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				token, err := source.Token(ctx)
+				assert.NoError(t, err)
+				tokens[i] = token
+			}()
+		}
+		wg.Wait()
+		assert.EqualValues(t, 1, issued.Load())
+		for _, token := range tokens {
+			assert.Equal(t, tokens[0], token)
+		}
+
+		other, err := client.ClientTokenSource(credentials)
+		require.NoError(t, err)
+		token, err := other.Token(ctx)
+		require.NoError(t, err)
+		assert.NotEqual(t, tokens[0], token)
+		assert.EqualValues(t, 2, issued.Load())
+	})
+
+	t.Run("refreshes tokens about to expire", func(t *testing.T) {
+		server := scalekittest.NewServer(t)
+		client, credentials, issued := newM2MTestClient(t, server)
+		server.SetAccessTokenLifetime(30 * time.Second)
+		source, err := client.ClientTokenSource(credentials)
+		require.NoError(t, err)
+		for range 3 {
+			_, err := source.Token(ctx)
+			require.NoError(t, err)
+		}
+		assert.EqualValues(t, 3, issued.Load())
+	})
+
+	t.Run("refreshes stale tokens once", func(t *testing.T) {
+		client, credentials, issued := newM2MTestClient(t, scalekittest.NewServer(t))
+		source, err := client.ClientTokenSource(credentials)
+		require.NoError(t, err)
+		stale, err := source.Token(ctx)
+		require.NoError(t, err)
+		fresh, err := source.Refresh(ctx, stale)
+		require.NoError(t, err)
+		assert.NotEqual(t, stale, fresh)
+		token, err := source.Refresh(ctx, stale)
+		require.NoError(t, err)
+		assert.Equal(t, fresh, token)
+		assert.EqualValues(t, 2, issued.Load())
+	})
+
+	t.Run("requires credentials", func(t *testing.T) {
+		client := scalekittest.NewServer(t).Client().(scalekit.ClientTokenSourcer)
+		_, err := client.ClientTokenSource(scalekit.GenerateClientTokenOptions{ClientSecret: "m2m_secret"})
+		assert.ErrorIs(t, err, scalekit.ErrClientIdRequired)
+		_, err = client.ClientTokenSource(scalekit.GenerateClientTokenOptions{ClientID: "m2m_client"})
+		assert.ErrorIs(t, err, scalekit.ErrClientSecretRequired)
+	})
+}
+
+// newResourceServer serves an API that rejects the first bearer token it sees
+// and echoes the token and request body of the other requests.
+func newResourceServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	var rejected sync.Once
+	var rejectedToken string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		rejected.Do(func() { rejectedToken = token })
+		if token == rejectedToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		_, _ = fmt.Fprintf(w, "%s %s", token, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTokenTransport(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("retries once with a fresh token on 401", func(t *testing.T) {
+		client, credentials, issued := newM2MTestClient(t, scalekittest.NewServer(t))
+		source, err := client.ClientTokenSource(credentials)
+		require.NoError(t, err)
+		httpClient := &http.Client{Transport: scalekit.NewTokenTransport(source, nil)}
+		api := newResourceServer(t)
+
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, api.URL, strings.NewReader("payload"))
+		require.NoError(t, err)
+		response, err := httpClient.Do(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		token, err := source.Token(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, token+" payload", string(body))
+		assert.Empty(t, request.Header.Get("Authorization"))
+
+		response, err = httpClient.Get(api.URL)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		body, err = io.ReadAll(response.Body)
+		require.NoError(t, err)
+		assert.Equal(t, token+" ", string(body))
+		assert.EqualValues(t, 2, issued.Load())
+	})
+
+	t.Run("returns the 401 of requests that cannot be replayed", func(t *testing.T) {
+		client, credentials, _ := newM2MTestClient(t, scalekittest.NewServer(t))
+		source, err := client.ClientTokenSource(credentials)
+		require.NoError(t, err)
+		httpClient := &http.Client{Transport: scalekit.NewTokenTransport(source, nil)}
+		api := newResourceServer(t)
+
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, api.URL, io.NopCloser(strings.NewReader("payload")))
+		require.NoError(t, err)
+		response, err := httpClient.Do(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	})
+}
@@ -0,0 +1,139 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/scalekittest"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/webhooks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookVerifier(t *testing.T) {
+	const oldSecret = testWebhookSecret
+	const newSecret = "whsec_bmV3c2VjcmV0"
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	payload := []byte(`{"id": "evt_1", "type": "organization.created", "data": {}}`)
+
+	verifier, err := scalekit.NewWebhookVerifier([]string{newSecret, oldSecret}, scalekit.WebhookVerifierOptions{
+		Tolerance: time.Minute,
+		Now:       func() time.Time { return now },
+	})
+	require.NoError(t, err)
+	var _ webhooks.Verifier = verifier
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		check   scalekit.WebhookCheck
+		err     error
+	}{
+		{name: "new secret", headers: signWebhookWith(newSecret, "msg_1", now, payload)},
+		{name: "old secret", headers: signWebhookWith(oldSecret, "msg_1", now.Add(-time.Minute), payload)},
+		{
+			name:    "other secret",
+			headers: signWebhookWith("whsec_b3RoZXJzZWNyZXQ=", "msg_1", now, payload),
+			check:   scalekit.WebhookCheckSignature,
+			err:     scalekit.ErrInvalidSignature,
+		},
+		{
+			name:    "missing headers",
+			headers: map[string]string{"Webhook-Id": "msg_1", "Webhook-Timestamp": "1"},
+			check:   scalekit.WebhookCheckHeaders,
+			err:     scalekit.ErrMissingRequiredHeaders,
+		},
+		{
+			name:    "too old",
+			headers: signWebhookWith(newSecret, "msg_1", now.Add(-2*time.Minute), payload),
+			check:   scalekit.WebhookCheckTimestamp,
+			err:     scalekit.ErrMessageTimestampTooOld,
+		},
+		{
+			name:    "too new",
+			headers: signWebhookWith(newSecret, "msg_1", now.Add(2*time.Minute), payload),
+			check:   scalekit.WebhookCheckTimestamp,
+			err:     scalekit.ErrMessageTimestampTooNew,
+		},
+		{
+			name: "malformed timestamp",
+			headers: map[string]string{
+				"webhook-id":        "msg_1",
+				"webhook-timestamp": "yesterday",
+				"webhook-signature": "v1,c2lnbmF0dXJl",
+			},
+			check: scalekit.WebhookCheckTimestamp,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifier.Verify(tt.headers, payload)
+			if tt.check == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var verificationErr *scalekit.WebhookVerificationError
+			require.True(t, errors.As(err, &verificationErr), "error %v is not a WebhookVerificationError", err)
+			assert.Equal(t, tt.check, verificationErr.Check)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+
+	t.Run("rejects malformed secrets", func(t *testing.T) {
+		_, err := scalekit.NewWebhookVerifier([]string{newSecret, "whsec_not base64"}, scalekit.WebhookVerifierOptions{})
+		assert.ErrorIs(t, err, scalekit.ErrInvalidSecret)
+		_, err = scalekit.NewWebhookVerifier(nil, scalekit.WebhookVerifierOptions{})
+		assert.ErrorIs(t, err, scalekit.ErrInvalidSecret)
+	})
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	client := scalekit.NewScalekitClient("https://example.scalekit.com", "client_id", "client_secret")
+	payload := []byte(`{"id": "evt_1", "type": "organization.created", "data": {}}`)
+	timestamp := time.Now().Truncate(time.Second)
+
+	headers, err := scalekit.SignWebhookPayload(testWebhookSecret, "msg_1", timestamp, payload)
+	require.NoError(t, err)
+	assert.Equal(t, "msg_1", headers["webhook-id"])
+	assert.Equal(t, fmt.Sprint(timestamp.Unix()), headers["webhook-timestamp"])
+	assert.True(t, strings.HasPrefix(headers["webhook-signature"], "v1,"))
+	valid, err := client.VerifyWebhookPayload(testWebhookSecret, headers, payload)
+	require.NoError(t, err)
+	assert.True(t, valid)
+	valid, err = client.VerifyInterceptorPayload(testWebhookSecret, headers, payload)
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	_, err = scalekit.SignWebhookPayload("whsec", "msg_1", timestamp, payload)
+	assert.ErrorIs(t, err, scalekit.ErrInvalidSecret)
+	_, err = scalekit.SignWebhookPayload(testWebhookSecret, "", timestamp, payload)
+	assert.ErrorIs(t, err, scalekit.ErrMissingRequiredHeaders)
+
+	t.Run("builds requests for handlers", func(t *testing.T) {
+		var calls int
+		handler := webhooks.NewHandler(func(context.Context, *webhooks.Event) error {
+			calls++
+			return nil
+		}, webhooks.HandlerOptions{Verifier: webhooks.SecretVerifier(client, testWebhookSecret)})
+
+		for range 2 {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, scalekittest.NewWebhookRequest("/webhooks", testWebhookSecret, "msg_2", payload))
+			assert.Equal(t, http.StatusNoContent, recorder.Code)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, scalekittest.NewWebhookRequest("/webhooks", testWebhookSecret, "", payload))
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		assert.Equal(t, 2, calls)
+		assert.Panics(t, func() { scalekittest.NewWebhookRequest("/webhooks", "whsec", "", payload) })
+	})
+}
@@ -0,0 +1,267 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/webhooks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testWebhookSecret = "whsec_dGVzdHNlY3JldA=="
+
+// signWebhook returns the headers of a delivery of payload signed now with
+// testWebhookSecret.
+func signWebhook(id string, payload []byte) map[string]string {
+	return signWebhookWith(testWebhookSecret, id, time.Now(), payload)
+}
+
+// signWebhookWith returns the headers of a delivery of payload signed with
+// secret at timestamp.
+func signWebhookWith(secret, id string, timestamp time.Time, payload []byte) map[string]string {
+	headers, err := scalekit.SignWebhookPayload(secret, id, timestamp, payload)
+	if err != nil {
+		panic(err)
+	}
+	return headers
+}
+
+const directoryUserCreatedPayload = `{
+	"id": "evt_1",
+	"type": "organization.directory.user_created",
+	"object": "DirectoryUser",
+	"environment_id": "env_1",
+	"organization_id": "org_1",
+	"occurred_at": "2026-10-17T09:30:00Z",
+	"spec_version": "1",
+	"data": {
+		"id": "diruser_1",
+		"organization_id": "org_1",
+		"directory_id": "dir_1",
+		"email": "jane@example.com",
+		"given_name": "Jane",
+		"active": true,
+		"groups": [{"id": "dirgroup_1", "display_name": "Engineering"}],
+		"roles": [{"role_name": "admin"}]
+	}
+}`
+
+func TestWebhookRouter(t *testing.T) {
+	ctx := context.Background()
+	client := scalekit.NewScalekitClient("https://example.scalekit.com", "client_id", "client_secret")
+	router := webhooks.NewRouter(webhooks.SecretVerifier(client, testWebhookSecret))
+
+	var users []*webhooks.DirectoryUser
+	var unknown []string
+	router.On(webhooks.DirectoryUserCreated, webhooks.Typed(func(_ context.Context, event *webhooks.Event, user *webhooks.DirectoryUser) error {
+		assert.Equal(t, "env_1", event.EnvironmentId)
+		assert.Equal(t, "org_1", event.OrganizationId)
+		assert.Equal(t, time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC), event.OccurredAt)
+		users = append(users, user)
+		return nil
+	}))
+	failure := errors.New("handler failed")
+	router.On(webhooks.OrganizationDeleted, func(context.Context, *webhooks.Event) error { return failure })
+
+	t.Run("dispatches typed events", func(t *testing.T) {
+		payload := []byte(directoryUserCreatedPayload)
+		require.NoError(t, router.Route(ctx, signWebhook("msg_1", payload), payload))
+		require.Len(t, users, 1)
+		assert.Equal(t, webhooks.DirectoryUser{
+			Id:             "diruser_1",
+			OrganizationId: "org_1",
+			DirectoryId:    "dir_1",
+			Email:          "jane@example.com",
+			GivenName:      "Jane",
+			Active:         true,
+			Groups:         []webhooks.DirectoryUserGroup{{Id: "dirgroup_1", DisplayName: "Engineering"}},
+			Roles:          []webhooks.DirectoryUserRole{{RoleName: "admin"}},
+		}, *users[0])
+	})
+
+	t.Run("ignores unknown events without a fallback", func(t *testing.T) {
+		payload := []byte(`{"id": "evt_2", "type": "organization.renamed", "data": {}}`)
+		require.NoError(t, router.Route(ctx, signWebhook("msg_2", payload), payload))
+	})
+
+	t.Run("sends unknown events to the fallback", func(t *testing.T) {
+		router.OnUnknown(func(_ context.Context, event *webhooks.Event) error {
+			unknown = append(unknown, event.Type)
+			return nil
+		})
+		payload := []byte(`{"id": "evt_3", "type": "organization.renamed", "data": {}}`)
+		require.NoError(t, router.Route(ctx, signWebhook("msg_3", payload), payload))
+		assert.Equal(t, []string{"organization.renamed"}, unknown)
+	})
+
+	t.Run("returns handler errors", func(t *testing.T) {
+		payload := []byte(`{"id": "evt_4", "type": "organization.deleted", "data": {"id": "org_1"}}`)
+		assert.ErrorIs(t, router.Route(ctx, signWebhook("msg_4", payload), payload), failure)
+	})
+
+	t.Run("rejects unverified deliveries", func(t *testing.T) {
+		payload := []byte(directoryUserCreatedPayload)
+		headers := signWebhook("msg_5", []byte(`{}`))
+		assert.ErrorIs(t, router.Route(ctx, headers, payload), scalekit.ErrInvalidSignature)
+		assert.Len(t, users, 1)
+	})
+
+	t.Run("rejects payloads that are not events", func(t *testing.T) {
+		for _, payload := range []string{`not json`, `{"type": "organization.created"}`} {
+			err := router.Route(ctx, signWebhook("msg_6", []byte(payload)), []byte(payload))
+			assert.ErrorIs(t, err, webhooks.ErrInvalidEvent, payload)
+		}
+		payload := []byte(`{"id": "evt_7", "type": "organization.directory.user_created", "data": {"active": "yes"}}`)
+		assert.ErrorIs(t, router.Route(ctx, signWebhook("msg_7", payload), payload), webhooks.ErrInvalidEvent)
+	})
+}
+
+func TestWebhookHandler(t *testing.T) {
+	client := scalekit.NewScalekitClient("https://example.scalekit.com", "client_id", "client_secret")
+	verifier := webhooks.SecretVerifier(client, testWebhookSecret)
+
+	post := func(handler http.Handler, method string, headers map[string]string, payload string) int {
+		request := httptest.NewRequest(method, "/webhooks", strings.NewReader(payload))
+		for name, value := range headers {
+			request.Header.Set(name, value)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	t.Run("processes each delivery once", func(t *testing.T) {
+		var calls atomic.Int32
+		fail := atomic.Bool{}
+		fail.Store(true)
+		handler := webhooks.NewHandler(func(ctx context.Context, event *webhooks.Event) error {
+			_, hasDeadline := ctx.Deadline()
+			assert.True(t, hasDeadline)
+			assert.Equal(t, "evt_1", event.Id)
+			calls.Add(1)
+			if fail.Load() {
+				return errors.New("database unavailable")
+			}
+			return nil
+		}, webhooks.HandlerOptions{Verifier: verifier})
+
+		headers := signWebhook("msg_1", []byte(directoryUserCreatedPayload))
+		assert.Equal(t, http.StatusInternalServerError, post(handler, http.MethodPost, headers, directoryUserCreatedPayload))
+		fail.Store(false)
+		assert.Equal(t, http.StatusNoContent, post(handler, http.MethodPost, headers, directoryUserCreatedPayload))
+		assert.Equal(t, http.StatusNoContent, post(handler, http.MethodPost, headers, directoryUserCreatedPayload))
+		assert.EqualValues(t, 2, calls.Load())
+	})
+
+	t.Run("rejects invalid deliveries", func(t *testing.T) {
+		var rejected []error
+		handler := webhooks.NewHandler(func(context.Context, *webhooks.Event) error {
+			t.Error("callback called for an invalid delivery")
+			return nil
+		}, webhooks.HandlerOptions{
+			Verifier:     verifier,
+			MaxBodyBytes: 1024,
+			OnError:      func(_ *http.Request, _ *webhooks.Event, err error) { rejected = append(rejected, err) },
+		})
+		valid := signWebhook("msg_2", []byte(directoryUserCreatedPayload))
+		large := strings.Repeat(" ", 2048)
+		tests := []struct {
+			name    string
+			method  string
+			headers map[string]string
+			payload string
+			status  int
+		}{
+			{"wrong method", http.MethodGet, valid, "", http.StatusMethodNotAllowed},
+			{"missing headers", http.MethodPost, map[string]string{"webhook-id": "msg_2"}, directoryUserCreatedPayload, http.StatusBadRequest},
+			{"bad signature", http.MethodPost, valid, `{"id": "evt_9", "type": "organization.created"}`, http.StatusUnauthorized},
+			{"not an event", http.MethodPost, signWebhook("msg_3", []byte(`[]`)), `[]`, http.StatusBadRequest},
+			{"too large", http.MethodPost, signWebhook("msg_4", []byte(large)), large, http.StatusRequestEntityTooLarge},
+		}
+		for _, tt := range tests {
+			assert.Equal(t, tt.status, post(handler, tt.method, tt.headers, tt.payload), tt.name)
+		}
+		assert.Len(t, rejected, 4)
+		assert.ErrorIs(t, rejected[0], scalekit.ErrMissingRequiredHeaders)
+		assert.ErrorIs(t, rejected[1], scalekit.ErrInvalidSignature)
+		assert.ErrorIs(t, rejected[2], webhooks.ErrInvalidEvent)
+	})
+
+	t.Run("acknowledges async deliveries before the callback", func(t *testing.T) {
+		release := make(chan struct{})
+		done := make(chan *webhooks.Event, 1)
+		router := webhooks.NewRouter(verifier)
+		router.OnUnknown(func(ctx context.Context, event *webhooks.Event) error {
+			<-release
+			assert.NoError(t, ctx.Err())
+			done <- event
+			return nil
+		})
+		handler := webhooks.NewHandler(router.Dispatch, webhooks.HandlerOptions{Verifier: verifier, Async: true})
+
+		headers := signWebhook("msg_5", []byte(directoryUserCreatedPayload))
+		assert.Equal(t, http.StatusAccepted, post(handler, http.MethodPost, headers, directoryUserCreatedPayload))
+		// A redelivery while the callback runs is turned away for a retry.
+		assert.Equal(t, http.StatusConflict, post(handler, http.MethodPost, headers, directoryUserCreatedPayload))
+		close(release)
+		select {
+		case event := <-done:
+			assert.Equal(t, webhooks.DirectoryUserCreated, event.Type)
+		case <-time.After(5 * time.Second):
+			t.Fatal("callback did not run")
+		}
+		assert.Eventually(t, func() bool {
+			return post(handler, http.MethodPost, headers, directoryUserCreatedPayload) == http.StatusNoContent
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("turns away deliveries being processed", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		handler := webhooks.NewHandler(func(context.Context, *webhooks.Event) error {
+			close(started)
+			<-release
+			return nil
+		}, webhooks.HandlerOptions{Verifier: verifier})
+
+		headers := signWebhook("msg_6", []byte(directoryUserCreatedPayload))
+		first := make(chan int, 1)
+		go func() { first <- post(handler, http.MethodPost, headers, directoryUserCreatedPayload) }()
+		<-started
+		assert.Equal(t, http.StatusConflict, post(handler, http.MethodPost, headers, directoryUserCreatedPayload))
+		close(release)
+		assert.Equal(t, http.StatusNoContent, <-first)
+		assert.Equal(t, http.StatusNoContent, post(handler, http.MethodPost, headers, directoryUserCreatedPayload))
+	})
+
+	t.Run("requires a verifier", func(t *testing.T) {
+		assert.PanicsWithValue(t, "webhooks: HandlerOptions.Verifier is required", func() {
+			webhooks.NewHandler(func(context.Context, *webhooks.Event) error { return nil }, webhooks.HandlerOptions{})
+		})
+	})
+}
+
+func TestMemorySeenStore(t *testing.T) {
+	ctx := context.Background()
+	store := webhooks.NewMemorySeenStore(2)
+	seen := func(id string) bool {
+		seen, err := store.Seen(ctx, id)
+		require.NoError(t, err)
+		return seen
+	}
+	assert.False(t, seen("a"))
+	require.NoError(t, store.MarkSeen(ctx, "a"))
+	require.NoError(t, store.MarkSeen(ctx, "b"))
+	assert.True(t, seen("a"))
+	require.NoError(t, store.MarkSeen(ctx, "c"))
+	assert.True(t, seen("a"), "recently seen IDs are kept")
+	assert.False(t, seen("b"), "least recently seen IDs are evicted")
+}
@@ -0,0 +1,149 @@
+package scalekit
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// Token type identifiers of OAuth 2.0 Token Exchange (RFC 8693 section 3).
+const (
+	TokenTypeAccessToken  = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeRefreshToken = "urn:ietf:params:oauth:token-type:refresh_token"
+	TokenTypeIdToken      = "urn:ietf:params:oauth:token-type:id_token"
+	TokenTypeJwt          = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// TokenExchangeOptions defines the inputs of ExchangeToken.
+type TokenExchangeOptions struct {
+	// SubjectToken represents the party on whose behalf the new token is
+	// requested, typically the user's access token. Required.
+	SubjectToken string
+
+	// SubjectTokenType defaults to TokenTypeAccessToken.
+	SubjectTokenType string
+
+	// ActorToken represents the acting party, such as an agent, when it is
+	// not the client itself. The issued token names it in its act claim.
+	ActorToken string
+
+	// ActorTokenType defaults to TokenTypeAccessToken when ActorToken is set.
+	ActorTokenType string
+
+	// Audience lists the logical names of the services the token is meant for.
+	Audience []string
+
+	// Resource lists the URIs of the services the token is meant for.
+	Resource []string
+
+	// Scopes requested for the issued token.
+	Scopes []string
+
+	// RequestedTokenType is the type of token to issue. When empty, the
+	// server chooses, usually an access token.
+	RequestedTokenType string
+}
+
+// TokenExchangeResponse is the token issued by ExchangeToken.
+type TokenExchangeResponse struct {
+	// AccessToken holds the issued token, whatever its IssuedTokenType.
+	AccessToken string
+
+	// IssuedTokenType is the type of AccessToken, such as TokenTypeAccessToken.
+	IssuedTokenType string
+
+	// TokenType is how to use the token, usually "Bearer".
+	TokenType    string
+	ExpiresIn    int
+	RefreshToken string
+
+	// Scopes granted, when they differ from the requested ones.
+	Scopes []string
+}
+
+// TokenExchanger exchanges a subject token, and optionally an actor token, for
+// a new token. The client returned by NewScalekitClient implements it.
+type TokenExchanger interface {
+	ExchangeToken(ctx context.Context, options TokenExchangeOptions) (*TokenExchangeResponse, error)
+}
+
+// ExchangeToken exchanges a token for another one with OAuth 2.0 Token
+// Exchange (RFC 8693), for example to let an agent call an API on behalf of a
+// user with a token naming both. The client authenticates with its client ID
+// and secret.
+func (s *scalekitClient) ExchangeToken(ctx context.Context, options TokenExchangeOptions) (*TokenExchangeResponse, error) {
+	if options.SubjectToken == "" {
+		return nil, ErrSubjectTokenRequired
+	}
+	subjectTokenType := options.SubjectTokenType
+	if subjectTokenType == "" {
+		subjectTokenType = TokenTypeAccessToken
+	}
+
+	qs := url.Values{}
+	qs.Set("grant_type", GrantTypeTokenExchange)
+	qs.Set("client_id", s.coreClient.clientId)
+	if s.coreClient.clientSecret != "" {
+		qs.Set("client_secret", s.coreClient.clientSecret)
+	}
+	qs.Set("subject_token", options.SubjectToken)
+	qs.Set("subject_token_type", subjectTokenType)
+	if options.ActorToken != "" {
+		actorTokenType := options.ActorTokenType
+		if actorTokenType == "" {
+			actorTokenType = TokenTypeAccessToken
+		}
+		qs.Set("actor_token", options.ActorToken)
+		qs.Set("actor_token_type", actorTokenType)
+	}
+	for _, audience := range options.Audience {
+		qs.Add("audience", audience)
+	}
+	for _, resource := range options.Resource {
+		qs.Add("resource", resource)
+	}
+	if len(options.Scopes) > 0 {
+		qs.Set("scope", strings.Join(options.Scopes, " "))
+	}
+	if options.RequestedTokenType != "" {
+		qs.Set("requested_token_type", options.RequestedTokenType)
+	}
+
+	authResp, err := s.coreClient.authenticate(ctx, qs)
+	if err != nil {
+		return nil, err
+	}
+	resp := &TokenExchangeResponse{
+		AccessToken:     authResp.AccessToken,
+		IssuedTokenType: authResp.IssuedTokenType,
+		TokenType:       authResp.TokenType,
+		ExpiresIn:       authResp.ExpiresIn,
+		RefreshToken:    authResp.RefreshToken,
+	}
+	if authResp.Scope != "" {
+		resp.Scopes = strings.Fields(authResp.Scope)
+	}
+	return resp, nil
+}
+
+// Actor is the act claim of a token issued by token exchange (RFC 8693
+// section 4.1): the party that acts on behalf of the token's subject.
+type Actor struct {
+	Sub      string `json:"sub"`
+	Iss      string `json:"iss,omitempty"`
+	ClientId string `json:"client_id,omitempty"`
+
+	// Act is the actor this actor itself acted for, when the token was
+	// exchanged more than once. The outermost actor is the current one.
+	Act *Actor `json:"act,omitempty"`
+}
+
+// Delegation returns the actors of claims from the current actor to the first
+// one, or nil when the token was not issued to act on behalf of its subject.
+func (a *AccessTokenClaims) Delegation() []Actor {
+	var actors []Actor
+	for actor := a.Act; actor != nil; actor = actor.Act {
+		actors = append(actors, *actor)
+	}
+	return actors
+}
@@ -0,0 +1,173 @@
+package scalekit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// TokenSource supplies access tokens for outgoing requests.
+type TokenSource interface {
+	// Token returns a cached token, fetching a new one when there is none or
+	// it is about to expire.
+	Token(ctx context.Context) (string, error)
+
+	// Refresh returns a token other than stale, such as one a server just
+	// rejected. When stale is still the cached token, a new one is fetched.
+	Refresh(ctx context.Context, stale string) (string, error)
+}
+
+type clientTokenSource struct {
+	core    *coreClient
+	options GenerateClientTokenOptions
+	group   singleflight.Group
+	token   atomic.Pointer[clientToken]
+}
+
+// ClientTokenSourcer returns caching sources of client-credentials tokens. The
+// client returned by NewScalekitClient implements it.
+type ClientTokenSourcer interface {
+	ClientTokenSource(options GenerateClientTokenOptions) (TokenSource, error)
+}
+
+// ClientTokenSource returns a TokenSource of client-credentials tokens for the
+// client ID, secret and scopes of options, for example the credentials of an
+// M2M client. Tokens are cached and fetched again the refresh skew (see
+// WithTokenRefreshSkew) before they expire, and concurrent callers share a
+// single token request. Each call returns a new source with its own cache, so
+// keep the returned source, for example in a NewTokenTransport, rather than
+// calling ClientTokenSource per request.
+func (s *scalekitClient) ClientTokenSource(options GenerateClientTokenOptions) (TokenSource, error) {
+	if options.ClientID == "" {
+		return nil, ErrClientIdRequired
+	}
+	if options.ClientSecret == "" {
+		return nil, ErrClientSecretRequired
+	}
+	options.Scopes = slices.Clone(options.Scopes)
+	return &clientTokenSource{core: s.coreClient, options: options}, nil
+}
+
+func (t *clientTokenSource) Token(ctx context.Context) (string, error) {
+	if token := t.token.Load(); token != nil &&
+		(token.expiresAt.IsZero() || time.Until(token.expiresAt) > t.core.tokenRefreshSkew) {
+		return token.value, nil
+	}
+	return t.fetch(ctx, "")
+}
+
+func (t *clientTokenSource) Refresh(ctx context.Context, stale string) (string, error) {
+	if token := t.token.Load(); token != nil && token.value != stale {
+		return t.Token(ctx)
+	}
+	return t.fetch(ctx, stale)
+}
+
+// fetch requests a new token unless another caller did so since the cached
+// token was found missing, expiring or equal to stale.
+func (t *clientTokenSource) fetch(ctx context.Context, stale string) (string, error) {
+	value, err, _ := t.group.Do("token", func() (any, error) {
+		if token := t.token.Load(); token != nil && token.value != stale &&
+			(token.expiresAt.IsZero() || time.Until(token.expiresAt) > t.core.tokenRefreshSkew) {
+			return token.value, nil
+		}
+		qs := url.Values{}
+		qs.Set("grant_type", GrantTypeClientCredentials)
+		qs.Set("client_id", t.options.ClientID)
+		qs.Set("client_secret", t.options.ClientSecret)
+		if len(t.options.Scopes) > 0 {
+			qs.Set("scope", strings.Join(t.options.Scopes, " "))
+		}
+		// Use WithoutCancel so one caller's context cancellation does not fail all waiters.
+		res, err := t.core.authenticate(context.WithoutCancel(ctx), qs)
+		if err != nil {
+			return nil, err
+		}
+		token := &clientToken{value: res.AccessToken}
+		if res.ExpiresIn > 0 {
+			token.expiresAt = time.Now().Add(time.Duration(res.ExpiresIn) * time.Second)
+		}
+		t.token.Store(token)
+		return token.value, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return value.(string), nil
+}
+
+type tokenTransport struct {
+	source TokenSource
+	base   http.RoundTripper
+}
+
+// NewTokenTransport returns an http.RoundTripper that sends requests through
+// base, http.DefaultTransport when nil, with a bearer token from source in
+// their Authorization header. A request answered with 401 Unauthorized is
+// sent once more with a refreshed token, provided its body can be replayed.
+//
+//	source, err := client.ClientTokenSource(scalekit.GenerateClientTokenOptions{
+//		ClientID:     clientId,
+//		ClientSecret: clientSecret,
+//	})
+//	httpClient := &http.Client{Transport: scalekit.NewTokenTransport(source, nil)}
+func NewTokenTransport(source TokenSource, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &tokenTransport{source: source, base: base}
+}
+
+func (t *tokenTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	token, err := t.source.Token(r.Context())
+	if err != nil {
+		closeRequestBody(r)
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(withBearerToken(r, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if r.Body != nil && r.Body != http.NoBody && r.GetBody == nil {
+		return resp, nil
+	}
+
+	// When no other token can be had, the 401 response is returned as is.
+	token, err = t.source.Refresh(r.Context(), token)
+	if err != nil {
+		return resp, nil
+	}
+	retry := withBearerToken(r, token)
+	if r.GetBody != nil {
+		if retry.Body, err = r.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	// The first response is drained so its connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBodyBytes))
+	_ = resp.Body.Close()
+	return t.base.RoundTrip(retry)
+}
+
+// withBearerToken returns a clone of r carrying token, as RoundTrippers must
+// not modify the request they are given.
+func withBearerToken(r *http.Request, token string) *http.Request {
+	clone := r.Clone(r.Context())
+	clone.Header.Set("Authorization", "Bearer "+token)
+	return clone
+}
+
+// closeRequestBody closes the body of a request that will not be sent, as
+// RoundTrippers must.
+func closeRequestBody(r *http.Request) {
+	if r.Body != nil {
+		_ = r.Body.Close()
+	}
+}
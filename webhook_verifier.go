@@ -0,0 +1,218 @@
+package scalekit
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultWebhookTolerance is how far the webhook-timestamp of a delivery may
+// be from the current time when WebhookVerifierOptions.Tolerance is zero.
+const DefaultWebhookTolerance = 5 * time.Minute
+
+// WebhookCheck names a check of webhook and interceptor payload verification.
+type WebhookCheck string
+
+const (
+	// WebhookCheckHeaders fails when the webhook-id, webhook-timestamp or
+	// webhook-signature header is missing.
+	WebhookCheckHeaders WebhookCheck = "headers"
+	// WebhookCheckTimestamp fails when webhook-timestamp is malformed or
+	// outside the tolerance.
+	WebhookCheckTimestamp WebhookCheck = "timestamp"
+	// WebhookCheckSignature fails when no signature matches any secret.
+	WebhookCheckSignature WebhookCheck = "signature"
+)
+
+// WebhookVerificationError reports the check a payload failed. It wraps
+// ErrMissingRequiredHeaders, ErrMessageTimestampTooOld,
+// ErrMessageTimestampTooNew, ErrInvalidSignature or the timestamp parse error.
+type WebhookVerificationError struct {
+	Check WebhookCheck
+	Err   error
+}
+
+func (e *WebhookVerificationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *WebhookVerificationError) Unwrap() error {
+	return e.Err
+}
+
+// invalidSecretError is a malformed secret. It matches ErrInvalidSecret while
+// keeping the message of the underlying decoding error.
+type invalidSecretError struct {
+	err error
+}
+
+func (e *invalidSecretError) Error() string {
+	return e.err.Error()
+}
+
+func (e *invalidSecretError) Unwrap() []error {
+	return []error{ErrInvalidSecret, e.err}
+}
+
+// WebhookVerifierOptions configures NewWebhookVerifier.
+type WebhookVerifierOptions struct {
+	// Tolerance is how far the webhook-timestamp of a delivery may be from
+	// Now, in either direction. Defaults to DefaultWebhookTolerance.
+	Tolerance time.Duration
+
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// WebhookVerifier verifies the signatures of webhook and interceptor payloads
+// against one or more secrets. It is safe for concurrent use.
+type WebhookVerifier struct {
+	keys      [][]byte
+	tolerance time.Duration
+	now       func() time.Time
+}
+
+// NewWebhookVerifier returns a verifier accepting payloads signed with any of
+// secrets, such as the old and new secret while a webhook secret is rotated.
+// Secrets have the "whsec_<base64>" format shown in the dashboard; a malformed
+// secret fails with an error matching ErrInvalidSecret.
+func NewWebhookVerifier(secrets []string, options WebhookVerifierOptions) (*WebhookVerifier, error) {
+	if len(secrets) == 0 {
+		return nil, ErrInvalidSecret
+	}
+	verifier := &WebhookVerifier{tolerance: options.Tolerance, now: options.Now}
+	if verifier.tolerance <= 0 {
+		verifier.tolerance = DefaultWebhookTolerance
+	}
+	if verifier.now == nil {
+		verifier.now = time.Now
+	}
+	for _, secret := range secrets {
+		key, err := webhookSecretKey(secret)
+		if err != nil {
+			return nil, err
+		}
+		verifier.keys = append(verifier.keys, key)
+	}
+	return verifier, nil
+}
+
+// webhookSecretKey decodes the signing key of a "whsec_<base64>" secret.
+func webhookSecretKey(secret string) ([]byte, error) {
+	secretParts := strings.Split(secret, "_")
+	if len(secretParts) < 2 {
+		return nil, ErrInvalidSecret
+	}
+	key, err := base64.StdEncoding.DecodeString(secretParts[1])
+	if err != nil {
+		return nil, &invalidSecretError{err: err}
+	}
+	return key, nil
+}
+
+// Verify checks the webhook-id, webhook-timestamp and webhook-signature
+// headers of a payload. Header names are case-insensitive. It returns a
+// *WebhookVerificationError naming the failed check.
+func (v *WebhookVerifier) Verify(headers map[string]string, payload []byte) error {
+	signed, err := parseWebhookHeaders(headers)
+	if err != nil {
+		return err
+	}
+	return v.verify(signed, payload)
+}
+
+// webhookHeaders are the signature headers of a payload.
+type webhookHeaders struct {
+	id, timestamp, signature string
+}
+
+func parseWebhookHeaders(headers map[string]string) (*webhookHeaders, error) {
+	normalizedHeaders := make(map[string]string, len(headers))
+	for k, v := range headers {
+		normalizedHeaders[strings.ToLower(k)] = v
+	}
+	signed := &webhookHeaders{
+		id:        normalizedHeaders["webhook-id"],
+		timestamp: normalizedHeaders["webhook-timestamp"],
+		signature: normalizedHeaders["webhook-signature"],
+	}
+	if signed.id == "" || signed.timestamp == "" || signed.signature == "" {
+		return nil, &WebhookVerificationError{Check: WebhookCheckHeaders, Err: ErrMissingRequiredHeaders}
+	}
+	return signed, nil
+}
+
+func (v *WebhookVerifier) verify(signed *webhookHeaders, payload []byte) error {
+	timestamp, err := v.verifyTimestamp(signed.timestamp)
+	if err != nil {
+		return &WebhookVerificationError{Check: WebhookCheckTimestamp, Err: err}
+	}
+
+	data := fmt.Sprintf("%s.%d.%s", signed.id, timestamp.Unix(), payload)
+	for _, key := range v.keys {
+		computedSignature := computeSignature(key, data)
+		for _, versionedSignature := range strings.Split(signed.signature, " ") {
+			version, signature, ok := strings.Cut(versionedSignature, ",")
+			if !ok || version != webhookSignatureVersion {
+				continue
+			}
+			if hmac.Equal([]byte(signature), []byte(computedSignature)) {
+				return nil
+			}
+		}
+	}
+	return &WebhookVerificationError{Check: WebhookCheckSignature, Err: ErrInvalidSignature}
+}
+
+func (v *WebhookVerifier) verifyTimestamp(timestampStr string) (time.Time, error) {
+	unixTimestamp, err := strconv.ParseInt(timestampStr, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	now := v.now()
+	timestamp := time.Unix(unixTimestamp, 0)
+	if now.Sub(timestamp) > v.tolerance {
+		return time.Time{}, ErrMessageTimestampTooOld
+	}
+	if timestamp.Unix() > now.Add(v.tolerance).Unix() {
+		return time.Time{}, ErrMessageTimestampTooNew
+	}
+	return timestamp, nil
+}
+
+// verifyPayloadSignature verifies a payload signed with secret. Missing headers
+// are reported before a malformed secret, as VerifyPayloadSignature always has.
+func verifyPayloadSignature(secret string, headers map[string]string, payload []byte) error {
+	signed, err := parseWebhookHeaders(headers)
+	if err != nil {
+		return err
+	}
+	verifier, err := NewWebhookVerifier([]string{secret}, WebhookVerifierOptions{})
+	if err != nil {
+		return err
+	}
+	return verifier.verify(signed, payload)
+}
+
+// SignWebhookPayload signs payload with secret the way Scalekit signs webhook
+// and interceptor deliveries, and returns the webhook-id, webhook-timestamp
+// and webhook-signature headers to send it with. Use it to test webhook
+// consumers end to end, or to replay a stored event to another endpoint.
+func SignWebhookPayload(secret, id string, timestamp time.Time, payload []byte) (map[string]string, error) {
+	if id == "" {
+		return nil, ErrMissingRequiredHeaders
+	}
+	key, err := webhookSecretKey(secret)
+	if err != nil {
+		return nil, err
+	}
+	data := fmt.Sprintf("%s.%d.%s", id, timestamp.Unix(), payload)
+	return map[string]string{
+		"webhook-id":        id,
+		"webhook-timestamp": strconv.FormatInt(timestamp.Unix(), 10),
+		"webhook-signature": webhookSignatureVersion + "," + computeSignature(key, data),
+	}, nil
+}
@@ -0,0 +1,231 @@
+// Package webhooks decodes and routes the webhook events Scalekit sends when
+// directories, organizations, connections, users and memberships change.
+//
+// A Router verifies each delivery with the webhook secret of the endpoint and
+// calls the handler registered for its event type:
+//
+//	router := webhooks.NewRouter(webhooks.SecretVerifier(client, secret))
+//	router.On(webhooks.DirectoryUserCreated, webhooks.Typed(func(ctx context.Context, event *webhooks.Event, user *webhooks.DirectoryUser) error {
+//		return provision(ctx, event.OrganizationId, user)
+//	}))
+//	err := router.Route(ctx, headers, payload)
+//
+// NewHandler serves deliveries over HTTP, with body limits and replay
+// protection:
+//
+//	mux.Handle("POST /webhooks/scalekit", webhooks.NewHandler(router.Dispatch, webhooks.HandlerOptions{
+//		Verifier: webhooks.SecretVerifier(client, secret),
+//	}))
+//
+// Experimental: the event types and payload structs of this package, such as
+// DirectoryUserCreated and DirectoryUser, have not been checked against
+// Scalekit's published event catalog or recorded deliveries yet. Names and
+// fields may change in a minor release once they are; Event, Router and
+// NewHandler do not depend on them.
+package webhooks
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Event types of the events decoded into DirectoryUser.
+//
+// Experimental: see the package documentation.
+const (
+	DirectoryUserCreated = "organization.directory.user_created"
+	DirectoryUserUpdated = "organization.directory.user_updated"
+	DirectoryUserDeleted = "organization.directory.user_deleted"
+)
+
+// Event types of the events decoded into DirectoryGroup.
+//
+// Experimental: see the package documentation.
+const (
+	DirectoryGroupCreated = "organization.directory.group_created"
+	DirectoryGroupUpdated = "organization.directory.group_updated"
+	DirectoryGroupDeleted = "organization.directory.group_deleted"
+)
+
+// Event types of the events decoded into Organization.
+//
+// Experimental: see the package documentation.
+const (
+	OrganizationCreated = "organization.created"
+	OrganizationUpdated = "organization.updated"
+	OrganizationDeleted = "organization.deleted"
+)
+
+// Event types of the events decoded into Connection.
+//
+// Experimental: see the package documentation.
+const (
+	ConnectionCreated  = "organization.sso_created"
+	ConnectionEnabled  = "organization.sso_enabled"
+	ConnectionDisabled = "organization.sso_disabled"
+	ConnectionDeleted  = "organization.sso_deleted"
+)
+
+// Event types of the events decoded into User.
+//
+// Experimental: see the package documentation.
+const (
+	UserCreated = "user.created"
+	UserUpdated = "user.updated"
+	UserDeleted = "user.deleted"
+	UserSignup  = "user.signup"
+	UserLogin   = "user.login"
+	UserLogout  = "user.logout"
+)
+
+// Event types of the events decoded into Membership.
+//
+// Experimental: see the package documentation.
+const (
+	MembershipCreated = "user.organization_membership_created"
+	MembershipUpdated = "user.organization_membership_updated"
+	MembershipDeleted = "user.organization_membership_deleted"
+)
+
+// ErrInvalidEvent is returned when a verified payload is not a webhook event.
+var ErrInvalidEvent = errors.New("invalid webhook event")
+
+// Event is the envelope of every webhook event. Data holds the payload of the
+// event type, which Decode or Typed unmarshal into the matching struct.
+type Event struct {
+	Id             string    `json:"id"`
+	Type           string    `json:"type"`
+	Object         string    `json:"object,omitempty"`
+	EnvironmentId  string    `json:"environment_id"`
+	OrganizationId string    `json:"organization_id,omitempty"`
+	OccurredAt     time.Time `json:"occurred_at"`
+	SpecVersion    string    `json:"spec_version,omitempty"`
+
+	Data json.RawMessage `json:"data"`
+}
+
+// ParseEvent decodes a webhook payload into an Event. It does not verify the
+// payload; use a Router, or verify it first.
+func ParseEvent(payload []byte) (*Event, error) {
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, errors.Join(ErrInvalidEvent, err)
+	}
+	if event.Id == "" || event.Type == "" {
+		return nil, errors.Join(ErrInvalidEvent, errors.New("event id and type are required"))
+	}
+	return &event, nil
+}
+
+// Decode unmarshals the event's Data into v.
+func (e *Event) Decode(v any) error {
+	if len(e.Data) == 0 {
+		return errors.Join(ErrInvalidEvent, errors.New("event has no data"))
+	}
+	if err := json.Unmarshal(e.Data, v); err != nil {
+		return errors.Join(ErrInvalidEvent, err)
+	}
+	return nil
+}
+
+// DirectoryUser is the payload of directory user events.
+//
+// Experimental: see the package documentation.
+type DirectoryUser struct {
+	Id                string               `json:"id"`
+	OrganizationId    string               `json:"organization_id"`
+	DirectoryId       string               `json:"directory_id"`
+	ExternalId        string               `json:"external_id,omitempty"`
+	Email             string               `json:"email"`
+	PreferredUsername string               `json:"preferred_username,omitempty"`
+	GivenName         string               `json:"given_name,omitempty"`
+	FamilyName        string               `json:"family_name,omitempty"`
+	Active            bool                 `json:"active"`
+	Groups            []DirectoryUserGroup `json:"groups,omitempty"`
+	Roles             []DirectoryUserRole  `json:"roles,omitempty"`
+	Attributes        map[string]any       `json:"attributes,omitempty"`
+}
+
+// DirectoryUserGroup is a group a DirectoryUser belongs to.
+type DirectoryUserGroup struct {
+	Id          string `json:"id"`
+	DisplayName string `json:"display_name,omitempty"`
+}
+
+// DirectoryUserRole is a role assigned to a DirectoryUser through its groups.
+type DirectoryUserRole struct {
+	RoleName string `json:"role_name"`
+}
+
+// DirectoryGroup is the payload of directory group events.
+//
+// Experimental: see the package documentation.
+type DirectoryGroup struct {
+	Id             string         `json:"id"`
+	OrganizationId string         `json:"organization_id"`
+	DirectoryId    string         `json:"directory_id"`
+	ExternalId     string         `json:"external_id,omitempty"`
+	DisplayName    string         `json:"display_name"`
+	Attributes     map[string]any `json:"attributes,omitempty"`
+}
+
+// Organization is the payload of organization events.
+//
+// Experimental: see the package documentation.
+type Organization struct {
+	Id          string            `json:"id"`
+	ExternalId  string            `json:"external_id,omitempty"`
+	DisplayName string            `json:"display_name"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// Connection is the payload of SSO connection events.
+//
+// Experimental: see the package documentation.
+type Connection struct {
+	Id             string `json:"id"`
+	OrganizationId string `json:"organization_id"`
+	ConnectionType string `json:"connection_type"`
+	Provider       string `json:"provider"`
+	Status         string `json:"status,omitempty"`
+	Enabled        bool   `json:"enabled"`
+}
+
+// User is the payload of user events.
+//
+// Experimental: see the package documentation.
+type User struct {
+	Id         string            `json:"id"`
+	Email      string            `json:"email"`
+	ExternalId string            `json:"external_id,omitempty"`
+	Profile    *UserProfile      `json:"user_profile,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+// UserProfile is the profile of a User.
+type UserProfile struct {
+	GivenName     string `json:"given_name,omitempty"`
+	FamilyName    string `json:"family_name,omitempty"`
+	Name          string `json:"name,omitempty"`
+	EmailVerified bool   `json:"email_verified"`
+	PhoneNumber   string `json:"phone_number,omitempty"`
+	Locale        string `json:"locale,omitempty"`
+}
+
+// Membership is the payload of organization membership events.
+//
+// Experimental: see the package documentation.
+type Membership struct {
+	UserId           string            `json:"user_id"`
+	OrganizationId   string            `json:"organization_id"`
+	MembershipStatus string            `json:"membership_status,omitempty"`
+	Roles            []MembershipRole  `json:"roles,omitempty"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+// MembershipRole is a role of a Membership.
+type MembershipRole struct {
+	Id   string `json:"id,omitempty"`
+	Name string `json:"name"`
+}
@@ -0,0 +1,159 @@
+package webhooks
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/internal/signedhttp"
+)
+
+// DefaultMaxBodyBytes is the largest webhook payload NewHandler reads when
+// HandlerOptions.MaxBodyBytes is zero.
+const DefaultMaxBodyBytes = signedhttp.DefaultMaxBodyBytes
+
+// DefaultHandlerTimeout bounds the event callback of NewHandler when
+// HandlerOptions.Timeout is zero. Scalekit retries deliveries that are not
+// acknowledged within 15 seconds.
+const DefaultHandlerTimeout = 10 * time.Second
+
+// HandlerOptions configures NewHandler.
+type HandlerOptions struct {
+	// Verifier authenticates deliveries. Required; NewHandler panics without it.
+	Verifier Verifier
+
+	// MaxBodyBytes caps the payload size. Larger deliveries are rejected with
+	// 413. Defaults to DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+
+	// Seen detects redeliveries by their webhook-id header. IDs are recorded
+	// once their callback succeeds. Defaults to an in-memory store of the last
+	// DefaultSeenCapacity IDs.
+	Seen SeenStore
+
+	// Async acknowledges verified events with 202 before the callback runs,
+	// for callbacks that may outlast Scalekit's delivery timeout. Errors of
+	// async callbacks are only reported to OnError, with a copy of the
+	// request without its body, and the deliveries are not retried.
+	Async bool
+
+	// Timeout bounds the context of the callback. Defaults to
+	// DefaultHandlerTimeout.
+	Timeout time.Duration
+
+	// OnError is called with deliveries that are rejected or whose callback
+	// fails, for logging. event is nil when the delivery was not decoded.
+	OnError func(r *http.Request, event *Event, err error)
+}
+
+type handler struct {
+	onEvent EventHandler
+	options HandlerOptions
+
+	// inFlight holds the webhook IDs whose callback is running.
+	inFlight sync.Map
+}
+
+// NewHandler returns an http.Handler receiving webhook deliveries. It reads
+// the body up to MaxBodyBytes, verifies it with the webhook headers of the
+// request, skips deliveries whose webhook-id was already processed, and
+// passes the event to onEvent, such as a Router's Dispatch method. It
+// responds with:
+//
+//   - 204 when the event was processed, or was a redelivery;
+//   - 202 when the event was accepted for an Async callback;
+//   - 400 when headers are missing or the payload is not an event;
+//   - 401 when the signature or timestamp does not verify;
+//   - 405 for methods other than POST, 413 for payloads over MaxBodyBytes;
+//   - 409 when a delivery with the same webhook-id is still being processed
+//     by this handler, so that Scalekit retries it later;
+//   - 500 when onEvent fails, so that Scalekit delivers the event again.
+//
+// NewHandler panics when options.Verifier is nil.
+func NewHandler(onEvent EventHandler, options HandlerOptions) http.Handler {
+	if options.Verifier == nil {
+		panic("webhooks: HandlerOptions.Verifier is required")
+	}
+	if options.MaxBodyBytes <= 0 {
+		options.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if options.Seen == nil {
+		options.Seen = NewMemorySeenStore(0)
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultHandlerTimeout
+	}
+	if options.OnError == nil {
+		options.OnError = func(*http.Request, *Event, error) {}
+	}
+	return &handler{onEvent: onEvent, options: options}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	payload, headers, ok := signedhttp.ReadVerified(w, r, h.options.Verifier, h.options.MaxBodyBytes,
+		func(err error) { h.options.OnError(r, nil, err) })
+	if !ok {
+		return
+	}
+	event, err := ParseEvent(payload)
+	if err != nil {
+		h.options.OnError(r, nil, err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	id := headers["webhook-id"]
+	if _, running := h.inFlight.LoadOrStore(id, struct{}{}); running {
+		http.Error(w, http.StatusText(http.StatusConflict), http.StatusConflict)
+		return
+	}
+	seen, err := h.options.Seen.Seen(r.Context(), id)
+	if err != nil {
+		h.inFlight.Delete(id)
+		h.options.OnError(r, event, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if seen {
+		h.inFlight.Delete(id)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if h.options.Async {
+		// The goroutine outlives the request, so it gets a copy that does not
+		// end with the response.
+		async := r.Clone(context.WithoutCancel(r.Context()))
+		async.Body = http.NoBody
+		w.WriteHeader(http.StatusAccepted)
+		go func() {
+			defer h.inFlight.Delete(id)
+			if err := h.process(async, id, event); err != nil {
+				h.options.OnError(async, event, err)
+			}
+		}()
+		return
+	}
+	defer h.inFlight.Delete(id)
+	if err := h.process(r, id, event); err != nil {
+		h.options.OnError(r, event, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// process passes event to the callback and records id once it succeeds.
+func (h *handler) process(r *http.Request, id string, event *Event) error {
+	if err := h.handle(r.Context(), event); err != nil {
+		return err
+	}
+	return h.options.Seen.MarkSeen(context.WithoutCancel(r.Context()), id)
+}
+
+func (h *handler) handle(ctx context.Context, event *Event) error {
+	ctx, cancel := context.WithTimeout(ctx, h.options.Timeout)
+	defer cancel()
+	return h.onEvent(ctx, event)
+}
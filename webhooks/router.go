@@ -0,0 +1,99 @@
+package webhooks
+
+import (
+	"context"
+	"sync"
+
+	"github.com/scalekit-inc/scalekit-sdk-go/v2"
+	"github.com/scalekit-inc/scalekit-sdk-go/v2/internal/signedhttp"
+)
+
+// Verifier authenticates webhook deliveries. Verify returns nil when the
+// signature headers match the payload. *scalekit.WebhookVerifier implements
+// it, and accepts several secrets while one is rotated.
+type Verifier = signedhttp.Verifier
+
+// SecretVerifier returns a Verifier that checks deliveries with
+// client.VerifyWebhookPayload and the endpoint's signing secret.
+func SecretVerifier(client scalekit.Scalekit, secret string) Verifier {
+	return signedhttp.VerifierFunc(func(headers map[string]string, payload []byte) error {
+		_, err := client.VerifyWebhookPayload(secret, headers, payload)
+		return err
+	})
+}
+
+// EventHandler handles one webhook event. A returned error is reported to the
+// caller of Route or Dispatch.
+type EventHandler func(ctx context.Context, event *Event) error
+
+// Typed adapts a handler taking the decoded payload of an event, such as a
+// *DirectoryUser, to an EventHandler. Events whose data does not decode into T
+// fail with ErrInvalidEvent.
+func Typed[T any](handler func(ctx context.Context, event *Event, data *T) error) EventHandler {
+	return func(ctx context.Context, event *Event) error {
+		var data T
+		if err := event.Decode(&data); err != nil {
+			return err
+		}
+		return handler(ctx, event, &data)
+	}
+}
+
+// Router dispatches verified webhook events to the handlers registered for
+// their type. It is safe for concurrent use.
+type Router struct {
+	verifier Verifier
+
+	mu       sync.RWMutex
+	handlers map[string]EventHandler
+	unknown  EventHandler
+}
+
+// NewRouter returns a Router that authenticates deliveries with verifier.
+func NewRouter(verifier Verifier) *Router {
+	return &Router{verifier: verifier, handlers: make(map[string]EventHandler)}
+}
+
+// On registers handler for events of eventType, replacing any previous one.
+func (r *Router) On(eventType string, handler EventHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[eventType] = handler
+}
+
+// OnUnknown registers handler for events of a type without a handler. Without
+// one, such events are acknowledged and ignored.
+func (r *Router) OnUnknown(handler EventHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unknown = handler
+}
+
+// Route verifies a delivery, decodes its event and dispatches it. It returns
+// the verifier's error for deliveries that fail verification, ErrInvalidEvent
+// for payloads that are not events, and otherwise the handler's error.
+func (r *Router) Route(ctx context.Context, headers map[string]string, payload []byte) error {
+	if err := r.verifier.Verify(headers, payload); err != nil {
+		return err
+	}
+	event, err := ParseEvent(payload)
+	if err != nil {
+		return err
+	}
+	return r.Dispatch(ctx, event)
+}
+
+// Dispatch calls the handler registered for the type of an already verified
+// event, or the unknown-event handler.
+func (r *Router) Dispatch(ctx context.Context, event *Event) error {
+	r.mu.RLock()
+	handler, ok := r.handlers[event.Type]
+	if !ok {
+		handler = r.unknown
+	}
+	r.mu.RUnlock()
+	if handler == nil {
+		return nil
+	}
+	return handler(ctx, event)
+}
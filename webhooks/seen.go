@@ -0,0 +1,67 @@
+package webhooks
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// DefaultSeenCapacity is the number of webhook IDs the store returned by
+// NewMemorySeenStore remembers when created with a capacity of zero.
+const DefaultSeenCapacity = 10000
+
+// SeenStore remembers the IDs of processed webhook deliveries so that
+// redeliveries are not processed twice. Implement it on a shared store, such
+// as Redis, when several instances receive webhooks.
+type SeenStore interface {
+	// Seen reports whether id was recorded by MarkSeen.
+	Seen(ctx context.Context, id string) (bool, error)
+
+	// MarkSeen records id once its delivery was processed successfully.
+	MarkSeen(ctx context.Context, id string) error
+}
+
+type memorySeenStore struct {
+	capacity int
+
+	mu    sync.Mutex
+	order *list.List
+	ids   map[string]*list.Element
+}
+
+// NewMemorySeenStore returns a SeenStore that keeps the last capacity IDs in
+// memory, evicting the least recently seen. A capacity of zero or less
+// defaults to DefaultSeenCapacity. IDs are not shared between instances and
+// are lost on restart.
+func NewMemorySeenStore(capacity int) SeenStore {
+	if capacity <= 0 {
+		capacity = DefaultSeenCapacity
+	}
+	return &memorySeenStore{capacity: capacity, order: list.New(), ids: make(map[string]*list.Element)}
+}
+
+func (s *memorySeenStore) Seen(_ context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	element, ok := s.ids[id]
+	if ok {
+		s.order.MoveToFront(element)
+	}
+	return ok, nil
+}
+
+func (s *memorySeenStore) MarkSeen(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, ok := s.ids[id]; ok {
+		s.order.MoveToFront(element)
+		return nil
+	}
+	s.ids[id] = s.order.PushFront(id)
+	if s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.ids, oldest.Value.(string))
+	}
+	return nil
+}